@@ -0,0 +1,153 @@
+package aia
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PropertiesPath is where the project properties are kept in the archive
+const PropertiesPath = "youngandroidproject/project.properties"
+
+// Project is the content of an App Inventor .aia archive
+type Project struct {
+	// Properties is the project.properties file as it was read
+	Properties []byte
+	Screens    []*Screen
+	// Files holds the other entries by their path, the assets among them
+	Files map[string][]byte
+}
+
+// Screen is a screen of the project, its design (.scm) and its blocks (.bky) as App Inventor stores them
+type Screen struct {
+	Name   string
+	Schema string
+	Blocks string
+}
+
+// Open reads an .aia file
+func Open(fileName string) (*Project, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return Read(content)
+}
+
+// Read reads the content of an .aia archive
+func Read(content []byte) (*Project, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	project := &Project{Files: map[string][]byte{}}
+	screens := map[string]*Screen{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := file.Name
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, errors.New("the entry " + name + " isn't inside the project")
+		}
+		extension := path.Ext(name)
+		switch {
+		case name == PropertiesPath:
+			project.Properties = data
+		case strings.HasPrefix(name, "src/") && (extension == ".scm" || extension == ".bky"):
+			screenName := strings.TrimSuffix(path.Base(name), extension)
+			screen, ok := screens[screenName]
+			if !ok {
+				screen = &Screen{Name: screenName}
+				screens[screenName] = screen
+				project.Screens = append(project.Screens, screen)
+			}
+			if extension == ".scm" {
+				screen.Schema = string(data)
+			} else {
+				screen.Blocks = string(data)
+			}
+		default:
+			project.Files[name] = data
+		}
+	}
+	if project.Properties == nil {
+		return nil, errors.New("not an App Inventor project, " + PropertiesPath + " is missing")
+	}
+	sort.Slice(project.Screens, func(i, j int) bool { return project.Screens[i].Name < project.Screens[j].Name })
+	return project, nil
+}
+
+func readFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Property returns the value of a project property, such as main or name
+func (p *Project) Property(key string) string {
+	for _, line := range strings.Split(string(p.Properties), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if found && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// SourceDir is the directory of the screens in the archive, given by the package of the main screen,
+// e.g. src/appinventor/ai_user/HelloWorld for appinventor.ai_user.HelloWorld.Screen1
+func (p *Project) SourceDir() (string, error) {
+	main := p.Property("main")
+	dot := strings.LastIndex(main, ".")
+	if dot < 0 {
+		return "", errors.New("the main property of the project does not name a screen")
+	}
+	return "src/" + strings.ReplaceAll(main[:dot], ".", "/"), nil
+}
+
+// Write writes the project as an .aia archive, the entries are sorted so that the output is stable
+func (p *Project) Write(writer io.Writer) error {
+	sourceDir, err := p.SourceDir()
+	if err != nil {
+		return err
+	}
+	entries := map[string][]byte{PropertiesPath: p.Properties}
+	for name, data := range p.Files {
+		entries[name] = data
+	}
+	for _, screen := range p.Screens {
+		entries[sourceDir+"/"+screen.Name+".scm"] = []byte(screen.Schema)
+		entries[sourceDir+"/"+screen.Name+".bky"] = []byte(screen.Blocks)
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(writer)
+	for _, name := range names {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(entries[name]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
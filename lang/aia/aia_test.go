@@ -0,0 +1,77 @@
+package aia
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+const properties = "main=appinventor.ai_user.Hello.Screen1\nname=Hello\n"
+
+func archiveOf(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, data := range entries {
+		entry, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestReadRejectsEntriesOutsideTheProject(t *testing.T) {
+	for _, name := range []string{
+		"../../../../tmp/zs/pwned.txt",
+		"assets/../../pwned.txt",
+		"/etc/pwned",
+	} {
+		t.Run(name, func(t *testing.T) {
+			content := archiveOf(t, map[string]string{PropertiesPath: properties, name: "pwned"})
+			if _, err := Read(content); err == nil {
+				t.Errorf("expected %s to be rejected", name)
+			}
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	content := archiveOf(t, map[string]string{
+		PropertiesPath: properties,
+		"src/appinventor/ai_user/Hello/Screen1.scm": "#|\n$JSON\n{}\n|#",
+		"src/appinventor/ai_user/Hello/Screen1.bky": "<xml></xml>",
+		"assets/kitty.png":                          "png",
+	})
+	project, err := Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Screens) != 1 || project.Screens[0].Name != "Screen1" || project.Screens[0].Blocks != "<xml></xml>" {
+		t.Fatalf("unexpected screens %+v", project.Screens)
+	}
+	if string(project.Files["assets/kitty.png"]) != "png" {
+		t.Errorf("the asset wasn't kept")
+	}
+	if project.Property("name") != "Hello" {
+		t.Errorf("expected the name Hello but got %q", project.Property("name"))
+	}
+
+	var written bytes.Buffer
+	if err := project.Write(&written); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Read(written.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Screens[0].Schema != project.Screens[0].Schema || string(again.Properties) != properties {
+		t.Errorf("the project changed when written and read again")
+	}
+}
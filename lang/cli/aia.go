@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"Falcon/aia"
+	"Falcon/code/ast"
+	"Falcon/code/parsers/blocklytomist"
+	"Falcon/design"
+	"Falcon/project"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var aiaCommand = &Command{
+	Name:    "aia",
+	Usage:   "aia <unpack project.aia dir | pack dir project.aia>",
+	Summary: "Converts App Inventor projects to Falcon source files and back",
+	Run:     runAia,
+}
+
+// the versions written at the end of the blocks of a screen that has none yet
+const (
+	yaVersion       = "208"
+	languageVersion = "33"
+)
+
+// versionsPath keeps the versions of the blocks of the screens unpacked, so that they are packed
+// back as they were read, e.g. Screen1.ya-version=208
+const versionsPath = "blocks.properties"
+
+func runAia(args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("expected an aia sub command")
+	}
+	positional, err := parseFlags(flag.NewFlagSet("aia "+args[0], flag.ContinueOnError), args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageErrorf("expected 2 arguments but got %", strconv.Itoa(len(positional)))
+	}
+	switch args[0] {
+	case "unpack":
+		return unpackProject(positional[0], positional[1])
+	case "pack":
+		return packProject(positional[0], positional[1])
+	}
+	return usageErrorf("unknown aia sub command '%'", args[0])
+}
+
+// unpackProject writes every screen of the project as ScreenN.mist and ScreenN.aiml, the project
+// properties and the assets are copied as they are
+func unpackProject(archive string, dir string) error {
+	project, err := aia.Open(archive)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{aia.PropertiesPath: project.Properties}
+	for name, data := range project.Files {
+		files[name] = data
+	}
+	var versions strings.Builder
+	for _, screen := range project.Screens {
+		var root ast.XmlRoot
+		if xml.Unmarshal([]byte(screen.Blocks), &root) == nil && root.YaCodeBlocks != nil {
+			versions.WriteString(screen.Name + ".ya-version=" + root.YaCodeBlocks.YaVersion + "\n")
+			versions.WriteString(screen.Name + ".language-version=" + root.YaCodeBlocks.LanguageVersion + "\n")
+		}
+	}
+	if versions.Len() > 0 {
+		files[versionsPath] = []byte(versions.String())
+	}
+	for _, screen := range project.Screens {
+		err := safeRun(func() error {
+			designXml, err := design.NewSchemaParser(screen.Schema).ConvertSchemaToXml()
+			if err != nil {
+				return err
+			}
+			files[screen.Name+".aiml"] = []byte(designXml)
+			var sourceCode string
+			if strings.TrimSpace(screen.Blocks) != "" {
+				sourceCode = exprsToSource(blocklytomist.NewParser(screen.Blocks).GenerateAST())
+			}
+			files[screen.Name+".mist"] = []byte(sourceCode)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", screen.Name, err)
+		}
+	}
+	for name, data := range files {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("the entry %s would be written outside of %s", name, dir)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packProject builds the project from an unpacked directory, the screens are read as a project.
+// The files of the sub directories are kept as they are, the assets and the project properties among them.
+func packProject(dir string, archive string) error {
+	aiaProject := &aia.Project{Files: map[string][]byte{}}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(dir, path)
+		if relative != "." && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !strings.ContainsRune(relative, filepath.Separator) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relative)
+		if name == aia.PropertiesPath {
+			aiaProject.Properties = data
+		} else {
+			aiaProject.Files[name] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if aiaProject.Properties == nil {
+		return fmt.Errorf("%s is missing", filepath.Join(dir, filepath.FromSlash(aia.PropertiesPath)))
+	}
+	loaded, diagnostics, err := project.Load(dir, nil)
+	if err != nil {
+		return err
+	}
+	if err := reportDiagnostics(diagnostics, false); err != nil {
+		return err
+	}
+	versions, err := readVersions(dir)
+	if err != nil {
+		return err
+	}
+	for _, screen := range loaded.Screens {
+		packed, err := packScreen(loaded, screen, versions)
+		if err != nil {
+			return err
+		}
+		aiaProject.Screens = append(aiaProject.Screens, packed)
+	}
+	var content bytes.Buffer
+	if err := aiaProject.Write(&content); err != nil {
+		return err
+	}
+	return os.WriteFile(archive, content.Bytes(), 0644)
+}
+
+// readVersions reads the versions of the blocks kept when the project was unpacked, by the screen
+// name and the version name, e.g. Screen1.ya-version
+func readVersions(dir string) (map[string]string, error) {
+	versions := map[string]string{}
+	content, err := os.ReadFile(filepath.Join(dir, versionsPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return versions, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if name, value, found := strings.Cut(strings.TrimSpace(line), "="); found {
+			versions[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return versions, nil
+}
+
+// packScreen converts the design and the blocks of a screen, the library included, to the files
+// App Inventor reads
+func packScreen(loaded *project.Project, screen *project.Screen, versions map[string]string) (*aia.Screen, error) {
+	designContent, err := os.ReadFile(screen.DesignFile)
+	if err != nil {
+		return nil, err
+	}
+	schema := string(designContent)
+	if filepath.Ext(screen.DesignFile) == ".aiml" {
+		schema, err = design.NewXmlParser(schema).ConvertXmlToSchema()
+		if err != nil {
+			return nil, err
+		}
+	}
+	root := ast.XmlRoot{
+		XMLNS:        "http://www.w3.org/1999/xhtml",
+		Blocks:       rootBlocks(loaded.Blocks(screen), false, nil),
+		YaCodeBlocks: &ast.YaCodeBlocks{YaVersion: yaVersion, LanguageVersion: languageVersion},
+	}
+	if version, ok := versions[screen.Name+".ya-version"]; ok {
+		root.YaCodeBlocks.YaVersion = version
+	}
+	if version, ok := versions[screen.Name+".language-version"]; ok {
+		root.YaCodeBlocks.LanguageVersion = version
+	}
+	blocks, err := xml.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(strings.TrimSpace(schema), "#|") {
+		schema = design.WrapSchema(schema)
+	}
+	return &aia.Screen{Name: screen.Name, Schema: schema, Blocks: string(blocks)}, nil
+}
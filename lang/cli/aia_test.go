@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"Falcon/aia"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// packSource packs a project of the files given by their path in the project directory, its
+// Screen1 has the stock design
+func packSource(t *testing.T, files map[string]string, archive string) {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "source")
+	if err := os.MkdirAll(filepath.Join(source, "youngandroidproject"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		writeFile(t, source, name, content)
+	}
+	mustRun(t, "", "aia", "pack", source, archive)
+}
+
+func helloPurr(t *testing.T) map[string]string {
+	t.Helper()
+	return map[string]string{
+		"Screen1.aiml": mustRun(t, readFile(t, stockScreen), "design", "to-aiml"),
+		"Screen1.mist": "when Button1.Click() {\n  Sound1.Play()\n  Sound1.Vibrate(500)\n}\n\n" +
+			"when Screen1.Initialize() {\n  Button1.Text = \"Pet the Kitty\"\n  Player1.Loop = false\n}\n",
+		"youngandroidproject/project.properties": "main=appinventor.ai_user.HelloPurr.Screen1\nname=HelloPurr\n",
+	}
+}
+
+func TestAiaRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files := helloPurr(t)
+	packed := filepath.Join(dir, "HelloPurr.aia")
+	packSource(t, files, packed)
+	project, err := aia.Open(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Screens) != 1 || project.Screens[0].Schema != readFile(t, stockScreen) {
+		t.Fatalf("expected the stock design in the project but got %+v", project.Screens)
+	}
+
+	unpacked := filepath.Join(dir, "unpacked")
+	mustRun(t, "", "aia", "unpack", packed, unpacked)
+	for name, content := range files {
+		if back := readFile(t, filepath.Join(unpacked, name)); back != content {
+			t.Errorf("%s changed when packed and unpacked:\n%s\nthen:\n%s", name, content, back)
+		}
+	}
+	mustRun(t, "", "check", unpacked)
+
+	again := filepath.Join(dir, "again.aia")
+	mustRun(t, "", "aia", "pack", unpacked, again)
+	if readFile(t, again) != readFile(t, packed) {
+		t.Errorf("the project changed when unpacked and packed again")
+	}
+}
+
+func TestAiaKeepsTheBlocksVersions(t *testing.T) {
+	dir := t.TempDir()
+	packed := filepath.Join(dir, "HelloPurr.aia")
+	packSource(t, helloPurr(t), packed)
+	// a project saved by another App Inventor build
+	project, err := aia.Open(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer := `<yacodeblocks ya-version="230" language-version="37">`
+	blocks := project.Screens[0].Blocks
+	project.Screens[0].Blocks = strings.Replace(blocks, `<yacodeblocks ya-version="208" language-version="33">`, newer, 1)
+	if project.Screens[0].Blocks == blocks {
+		t.Fatalf("expected the default versions in the blocks but got:\n%s", blocks)
+	}
+	var content bytes.Buffer
+	if err := project.Write(&content); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "Newer.aia", content.String())
+
+	unpacked := filepath.Join(dir, "unpacked")
+	mustRun(t, "", "aia", "unpack", filepath.Join(dir, "Newer.aia"), unpacked)
+	again := filepath.Join(dir, "again.aia")
+	mustRun(t, "", "aia", "pack", unpacked, again)
+	repacked, err := aia.Open(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(repacked.Screens[0].Blocks, newer) {
+		t.Errorf("expected the versions of the project kept but got:\n%s", repacked.Screens[0].Blocks)
+	}
+}
+
+func TestAiaPackReportsUndefinedComponents(t *testing.T) {
+	source := t.TempDir()
+	writeFile(t, source, "Screen1.aiml", mustRun(t, readFile(t, stockScreen), "design", "to-aiml"))
+	writeFile(t, source, "Screen1.mist", "when AccelerometerSensor1.Shaking() {\n}\n")
+	if _, errOut, code := run(t, "", "aia", "pack", source, filepath.Join(t.TempDir(), "Broken.aia")); code == ExitOk {
+		t.Errorf("expected the undefined component to fail the pack but got:\n%s", errOut)
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"Falcon/code/check"
+	"Falcon/project"
+	"errors"
+	"flag"
+	"os"
+)
+
+var checkCommand = &Command{
+	Name:    "check",
+	Usage:   "check [-json] [-strict] [-components extension.json] [directory]",
+	Summary: "Checks every screen of a project along with the shared " + project.LibraryFile,
+	Run:     runCheck,
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "print diagnostics as JSON")
+	strict := fs.Bool("strict", false, "type mismatches and runtime conversions are errors")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usageErrorf("expected a single project directory")
+	}
+	dir := "."
+	if len(positional) == 1 {
+		dir = positional[0]
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return usageErrorf("expected a project directory but got the file %", dir)
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	loaded, diagnostics, err := project.Load(dir, components)
+	if err != nil {
+		return err
+	}
+	if len(loaded.Screens) == 0 {
+		return errors.New("no screen designs found in " + dir)
+	}
+	if firstError(diagnostics) == nil {
+		// the library is checked with every screen, its diagnostics are only reported once
+		reported := map[string]bool{}
+		for _, screen := range loaded.Screens {
+			for _, diagnostic := range check.NewChecker(*strict).Locate(loaded.Located(screen)).Check(loaded.Blocks(screen)) {
+				key := diagnostic.Position() + diagnostic.Message
+				if !reported[key] {
+					reported[key] = true
+					diagnostics = append(diagnostics, diagnostic)
+				}
+			}
+		}
+	}
+	return reportDiagnostics(diagnostics, *asJson)
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"Falcon/code/context"
+	"Falcon/code/sugar"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ExitOk    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+type Command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(args []string) error
+}
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		compileCommand,
+		decompileCommand,
+		yailCommand,
+		designCommand,
+		aiaCommand,
+		checkCommand,
+		tokensCommand,
+		astCommand,
+		fmtCommand,
+		mergeCommand,
+		scaffoldCommand,
+		lspCommand,
+		runCommand,
+		testCommand,
+	}
+}
+
+// usageError is returned by a command when it was invoked with bad arguments
+type usageError struct {
+	message string
+}
+
+func (u *usageError) Error() string {
+	return u.message
+}
+
+func usageErrorf(message string, args ...string) error {
+	return &usageError{message: sugar.Format(message, args...)}
+}
+
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// Run executes the falcon command line with the given arguments (excluding the program name)
+// and returns the process exit code.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		if len(args) > 1 {
+			if command := findCommand(args[1]); command != nil {
+				printCommandUsage(stdout, command)
+				return ExitOk
+			}
+		}
+		printUsage(stdout)
+		return ExitOk
+	}
+	command := findCommand(args[0])
+	if command == nil {
+		fmt.Fprintf(stderr, "falcon: unknown command '%s'\n\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+	err := safeRun(func() error { return command.Run(args[1:]) })
+	if err == nil {
+		return ExitOk
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandUsage(stdout, command)
+		return ExitOk
+	}
+	var uErr *usageError
+	if errors.As(err, &uErr) {
+		fmt.Fprintf(stderr, "falcon %s: %s\n", command.Name, uErr.message)
+		printCommandUsage(stderr, command)
+		return ExitUsage
+	}
+	fmt.Fprintf(stderr, "falcon %s: %s\n", command.Name, err.Error())
+	return ExitError
+}
+
+func findCommand(name string) *Command {
+	for _, command := range commands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Falcon is a text language for App Inventor.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  falcon <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", command.Name, command.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Use \"falcon help <command>\" for more information about a command.")
+}
+
+func printCommandUsage(w io.Writer, command *Command) {
+	fmt.Fprintf(w, "Usage: falcon %s\n", command.Usage)
+	fmt.Fprintf(w, "\n%s\n", command.Summary)
+}
+
+// safeRun executes fn and converts any panic raised by the compiler pipeline into an error, a
+// diagnostic is reported like the others
+func safeRun(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if msg, ok := r.(string); ok {
+				err = errors.New(strings.TrimSpace(msg))
+			} else if diagnostic, ok := r.(*context.Diagnostic); ok {
+				err = reportDiagnostics([]*context.Diagnostic{diagnostic}, false)
+			} else if rErr, ok := r.(error); ok {
+				err = rErr
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	return fn()
+}
+
+// parseFlags parses the flags of a sub command, allowing them to be interspersed with
+// positional arguments, e.g. `falcon compile file.mist -o blocks.xml`
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageErrorf(err.Error())
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return positional, nil
+}
+
+// readInput reads the content of the given path, "-" or an empty path reads from stdin
+func readInput(path string) (string, error) {
+	if path == "" || path == "-" {
+		content, err := io.ReadAll(stdin)
+		return string(content), err
+	}
+	content, err := os.ReadFile(path)
+	return string(content), err
+}
+
+// writeOutput writes the content to the given path ending with a new line, "-" or an empty path
+// writes to stdout
+func writeOutput(path string, content string) error {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return writeContent(path, content)
+}
+
+// writeContent writes the content as it is, such as a converted design that must be the same byte
+// for byte, "-" or an empty path writes to stdout
+func writeContent(path string, content string) error {
+	if path == "" || path == "-" {
+		_, err := io.WriteString(stdout, content)
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// inputName returns the name used to refer the input source in error messages
+func inputName(path string) string {
+	if path == "" || path == "-" {
+		return "<stdin>"
+	}
+	return filepath.Base(path)
+}
+
+// singleInput returns the only positional argument, or an empty string if it's stdin
+func singleInput(positional []string) (string, error) {
+	switch len(positional) {
+	case 0:
+		return "", nil
+	case 1:
+		return positional[0], nil
+	default:
+		return "", usageErrorf("expected a single input file but got %", strconv.Itoa(len(positional)))
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs the command line over the input, it returns what is written to stdout and to stderr
+func run(t *testing.T, input string, args ...string) (string, string, int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	stdin, stdout, stderr = strings.NewReader(input), &out, &errOut
+	t.Cleanup(func() {
+		stdin, stdout, stderr = os.Stdin, os.Stdout, os.Stderr
+	})
+	code := Run(args)
+	return out.String(), errOut.String(), code
+}
+
+// mustRun runs the command line and fails the test unless it succeeds
+func mustRun(t *testing.T, input string, args ...string) string {
+	t.Helper()
+	out, errOut, code := run(t, input, args...)
+	if code != ExitOk {
+		t.Fatalf("falcon %s exited with %d:\n%s", strings.Join(args, " "), code, errOut)
+	}
+	return out
+}
+
+// writeFile writes a file of the test directory and returns its path
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"no command", nil, ExitOk, "Usage:\n  falcon <command> [arguments]", ""},
+		{"help", []string{"help"}, ExitOk, "Commands:", ""},
+		{"help of a command", []string{"help", "compile"}, ExitOk, "Usage: falcon compile", ""},
+		{"help flag of a command", []string{"compile", "-h"}, ExitOk, "Usage: falcon compile", ""},
+		{"unknown command", []string{"build"}, ExitUsage, "", "falcon: unknown command 'build'"},
+		{"unknown flag", []string{"compile", "-zzz"}, ExitUsage, "",
+			"falcon compile: flag provided but not defined: -zzz\nUsage: falcon compile"},
+		{"two inputs", []string{"compile", "a.mist", "b.mist"}, ExitUsage, "",
+			"falcon compile: expected a single input file but got 2"},
+		{"missing file", []string{"compile", "missing.mist"}, ExitError, "", "falcon compile: open missing.mist"},
+		{"check of a file", []string{"check", "cli.go"}, ExitUsage, "",
+			"falcon check: expected a project directory but got the file cli.go"},
+		{"syntax error", []string{"compile"}, ExitError, "",
+			"<stdin>:1:9: error: Unexpected end of file, was expecting type CloseCurve [syntax]\nfalcon compile: failed with 1 error(s)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, errOut, code := run(t, "println(1", test.args...)
+			if code != test.code {
+				t.Errorf("expected the exit code %d but got %d", test.code, code)
+			}
+			if !strings.Contains(out, test.stdout) || (test.stdout == "" && out != "") {
+				t.Errorf("expected %q on stdout but got:\n%s", test.stdout, out)
+			}
+			if !strings.Contains(errOut, test.stderr) || (test.stderr == "" && errOut != "") {
+				t.Errorf("expected %q on stderr but got:\n%s", test.stderr, errOut)
+			}
+		})
+	}
+}
+
+func TestInputAndOutput(t *testing.T) {
+	source := "println(1)\n"
+	fromStdin := mustRun(t, source, "compile")
+	if !strings.Contains(fromStdin, `<block type="controls_eval_but_ignore"`) || !strings.HasSuffix(fromStdin, "</xml>\n") {
+		t.Fatalf("expected the blocks on stdout but got:\n%s", fromStdin)
+	}
+	if dash := mustRun(t, source, "compile", "-", "-o", "-"); dash != fromStdin {
+		t.Errorf("expected - to stand for stdin and stdout but got:\n%s", dash)
+	}
+
+	dir := t.TempDir()
+	input := writeFile(t, dir, "main.mist", source)
+	output := filepath.Join(dir, "blocks.xml")
+	// the flags may come after the input
+	if out := mustRun(t, "", "compile", input, "-o", output); out != "" {
+		t.Errorf("expected nothing on stdout but got:\n%s", out)
+	}
+	if written := readFile(t, output); written != fromStdin {
+		t.Errorf("expected the file to hold the blocks of stdin but got:\n%s", written)
+	}
+	if decompiled := mustRun(t, "", "decompile", output); decompiled != source {
+		t.Errorf("expected the source back but got:\n%s", decompiled)
+	}
+}
+
+func TestBlocksRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"annotation after a global", "global x = 0\n\n@collapsed\nfunc f() {\n  println(1)\n}\n"},
+		{"annotations in a body", "func f() {\n  @disabled\n  println(1)\n  @collapsed @external\n  println(2)\n}\n"},
+		{"helper access", "global a = Align@Center\n\n@disabled\nglobal b = 1\n"},
+		{"annotations of plugged blocks", "global total = @external (1 + 2) * 3\n\n" +
+			"global names = @collapsed @disabled ([\"a\", @inline (\"b\" _ this.total)])\n\n" +
+			"func f() =\n  @collapsed ({\n    println(1)\n    2\n  })\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := mustRun(t, test.source, "compile")
+			decompiled := mustRun(t, blocks, "decompile", "-ids")
+			again := mustRun(t, mustRun(t, decompiled, "compile"), "decompile", "-ids")
+			if again != decompiled {
+				t.Errorf("the decompiled source changed when compiled again:\n%s\nthen:\n%s", decompiled, again)
+			}
+			if withoutIds := mustRun(t, blocks, "decompile"); withoutIds != test.source {
+				t.Errorf("expected the source back:\n%s\nbut got:\n%s", test.source, withoutIds)
+			}
+		})
+	}
+}
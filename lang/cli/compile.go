@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/check"
+	"Falcon/code/context"
+	"Falcon/code/layout"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/code/sourcemap"
+	"Falcon/components/registry"
+	"Falcon/design"
+	"Falcon/project"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+var compileCommand = &Command{
+	Name:    "compile",
+	Usage:   "compile [-o blocks.xml] [-json] [-strict] [-keep-types] [-components extension.json] [-design Screen1.aiml] [-previous blocks.xml] [-source-map map.json] [file.mist]",
+	Summary: "Compiles Falcon source code to Blockly XML",
+	Run:     runCompile,
+}
+
+func runCompile(args []string) error {
+	fs := flag.NewFlagSet("compile", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	asJson := fs.Bool("json", false, "print diagnostics as JSON")
+	strict := fs.Bool("strict", false, "type mismatches and runtime conversions are errors")
+	keepTypes := fs.Bool("keep-types", false, "keep the type annotations in block comments")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	designFile := fs.String("design", "", "screen design (.aiml or .scm) declaring the components, found next to the file by default")
+	previousFile := fs.String("previous", "", "blocks previously compiled or exported, whose unchanged blocks keep their positions")
+	sourceMapFile := fs.String("source-map", "", "write the source ranges of the blocks by their ids to the file")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return err
+	}
+	sourceCode, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	screen, err := loadDesign(*designFile, input)
+	if err != nil {
+		return err
+	}
+	previous, err := loadPositions(*previousFile)
+	if err != nil {
+		return err
+	}
+	options := parseOptions{components: components, screen: screen}
+	library, diagnostics, err := includeLibrary(input, &options)
+	if err != nil {
+		return err
+	}
+	expressions, langParser, sourceDiagnostics := parseSourceWith(inputName(input), sourceCode, options)
+	diagnostics = append(diagnostics, sourceDiagnostics...)
+	expressions, located := withLibrary(library, expressions, options, langParser)
+	var blocks []ast.Block
+	if firstError(diagnostics) == nil {
+		diagnostics = append(diagnostics, check.NewChecker(*strict).Locate(located).Check(expressions)...)
+		var diagnostic *context.Diagnostic
+		if blocks, diagnostic = generateBlocks(expressions, *keepTypes, previous); diagnostic != nil {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	if err := reportDiagnostics(diagnostics, *asJson); err != nil {
+		return err
+	}
+	xmlContent, err := blocksToXml(blocks)
+	if err != nil {
+		return err
+	}
+	if *sourceMapFile != "" {
+		if err := writeSourceMap(*sourceMapFile, sourcemap.FromSource(blocks, located)); err != nil {
+			return err
+		}
+	}
+	return writeOutput(*output, xmlContent)
+}
+
+// parseOptions is what the parser is told besides the source code
+type parseOptions struct {
+	included   *mistparser.LangParser // an already parsed file, whose declarations are visible
+	components *registry.Registry     // the component descriptors, the default ones when nil
+	screen     *design.Component      // the design declaring the components, if any
+}
+
+// parseSourceWith runs the lexer and the parser over the source code
+func parseSourceWith(
+	fileName string,
+	sourceCode string,
+	options parseOptions,
+) ([]ast.Expr, *mistparser.LangParser, []*context.Diagnostic) {
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: fileName}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	langParser := mistparser.NewLangParser(true, tokens)
+	if options.included != nil {
+		langParser.Include(options.included)
+	}
+	if options.components != nil {
+		langParser.Resolver.Components = options.components
+	}
+	if options.screen != nil {
+		langParser.SetDesign(options.screen)
+	}
+	expressions, parseDiagnostics := langParser.ParseAll()
+	return expressions, langParser, append(diagnostics, parseDiagnostics...)
+}
+
+// includeLibrary parses the library of the project the input is in, if there's one, and makes its
+// declarations visible to the source parsed with the options
+func includeLibrary(input string, options *parseOptions) ([]ast.Expr, []*context.Diagnostic, error) {
+	libraryFile := project.FindLibrary(input)
+	if libraryFile == "" {
+		return nil, nil, nil
+	}
+	sourceCode, err := os.ReadFile(libraryFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	expressions, langParser, diagnostics := parseSourceWith(project.LibraryFile, string(sourceCode),
+		parseOptions{components: options.components})
+	options.included = langParser
+	return expressions, diagnostics, nil
+}
+
+// withLibrary puts the expressions of the library included, if any, before the ones of the source,
+// along with where they are
+func withLibrary(
+	library []ast.Expr,
+	expressions []ast.Expr,
+	options parseOptions,
+	langParser *mistparser.LangParser,
+) ([]ast.Expr, []mistparser.LocatedExpr) {
+	if options.included == nil {
+		return expressions, langParser.Located
+	}
+	return slices.Concat(library, expressions), slices.Concat(options.included.Located, langParser.Located)
+}
+
+// loadComponents adds the descriptors of the file to the default components, nil when there's no file
+func loadComponents(path string) (*registry.Registry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	components, err := registry.Default().Extend(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return components, nil
+}
+
+func writeSourceMap(path string, sourceMap *sourcemap.SourceMap) error {
+	content, err := json.MarshalIndent(sourceMap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// loadPositions reads the positions of the blocks of a workspace, nil when there's no file
+func loadPositions(path string) (*layout.Positions, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := layout.ReadPositions(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return positions, nil
+}
+
+func blocksToXml(blocks []ast.Block) (string, error) {
+	xmlBlock := ast.XmlRoot{
+		Blocks: blocks,
+		XMLNS:  "https://developers.google.com/blockly/xml",
+	}
+	bytes, err := xml.MarshalIndent(xmlBlock, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// generateBlocks generates the root blocks like rootBlocks, the error a block is generated with is
+// returned as a diagnostic
+func generateBlocks(
+	expressions []ast.Expr,
+	keepTypes bool,
+	previous *layout.Positions,
+) (blocks []ast.Block, diagnostic *context.Diagnostic) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if diagnostic, ok = r.(*context.Diagnostic); !ok {
+				panic(r)
+			}
+		}
+	}()
+	return rootBlocks(expressions, keepTypes, previous), nil
+}
+
+// rootBlocks generates the root blocks, gives them their ids and places them on the workspace.
+// The type annotations are erased unless they're kept in comments.
+func rootBlocks(expressions []ast.Expr, keepTypes bool, previous *layout.Positions) []ast.Block {
+	blocks := make([]ast.Block, len(expressions))
+	for i, expression := range expressions {
+		if keepTypes {
+			blocks[i] = ast.TypedRootBlock(expression)
+		} else {
+			blocks[i] = ast.RootBlock(expression)
+		}
+	}
+	sourcemap.AssignIds(blocks)
+	layout.Arrange(blocks, previous)
+	return blocks
+}
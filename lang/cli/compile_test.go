@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"Falcon/code/context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileReportsGenerationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		line    int
+		column  int
+		message string
+	}{
+		{"statement as a value", "println(println(1))\n", 1, 9, "Expected a consumable but got a statement"},
+		{"test block", "func f() {\n}\n\ntest \"f\" {\n  f()\n}\n", 4, 1,
+			"Tests cannot be compiled to blocks, run them with falcon test"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, errOut, code := run(t, test.source, "compile", "-json")
+			if code != ExitError || out != "" {
+				t.Fatalf("expected the compilation to fail with no blocks but got %d:\n%s", code, out)
+			}
+			// the JSON diagnostics come before the final message
+			var diagnostics []struct {
+				Severity string
+				context.Span
+				Message string
+			}
+			if err := json.NewDecoder(strings.NewReader(errOut)).Decode(&diagnostics); err != nil {
+				t.Fatalf("expected JSON diagnostics but got %v:\n%s", err, errOut)
+			}
+			if len(diagnostics) != 1 || diagnostics[0].Severity != "error" || diagnostics[0].Line != test.line ||
+				diagnostics[0].Column != test.column || diagnostics[0].Message != test.message {
+				t.Errorf("expected %q at %d:%d but got %+v", test.message, test.line, test.column, diagnostics)
+			}
+		})
+	}
+}
+
+func TestLibraryOfTheProject(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "lib.mist", "func go() {\n  println(\"gone\")\n}\n")
+	source := writeFile(t, dir, "Screen1.mist", "func start() {\n  go()\n}\n")
+	writeFile(t, dir, "Screen1_test.mist", "test \"start\" {\n  start()\n}\n")
+
+	blocks := mustRun(t, "", "compile", source)
+	if !strings.Contains(blocks, `<field name="NAME">go</field>`) || !strings.Contains(blocks, `<field name="NAME">start</field>`) {
+		t.Errorf("expected the blocks of the library before the ones of the screen but got:\n%s", blocks)
+	}
+	if out := mustRun(t, "", "run", "-call", "start", source); out != "gone\n" {
+		t.Errorf("expected the procedure of the library to run but got %q", out)
+	}
+	if out := mustRun(t, "", "test", dir); !strings.Contains(out, "PASS  Screen1_test.mist > start") {
+		t.Errorf("expected the test to call the library but got:\n%s", out)
+	}
+	// the library alone doesn't include itself
+	mustRun(t, "", "compile", filepath.Join(dir, "lib.mist"))
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/parsers/blocklytomist"
+	"Falcon/code/sourcemap"
+	"flag"
+	"path/filepath"
+	"strings"
+)
+
+var decompileCommand = &Command{
+	Name:    "decompile",
+	Usage:   "decompile [-o file.mist] [-ids] [-source-map map.json] [blocks.xml]",
+	Summary: "Converts Blockly XML back to Falcon source code",
+	Run:     runDecompile,
+}
+
+func runDecompile(args []string) error {
+	fs := flag.NewFlagSet("decompile", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	keepIds := fs.Bool("ids", false, "keep the block ids as @id annotations")
+	sourceMapFile := fs.String("source-map", "", "write the source ranges of the blocks by their ids to the file")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return err
+	}
+	xmlContent, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	parser := blocklytomist.NewParser(xmlContent)
+	parser.KeepIds = *keepIds
+	exprs := parser.GenerateAST()
+	sourceCode := exprsToSource(exprs)
+	if *sourceMapFile != "" {
+		sourceMap := sourcemap.FromWorkspace(parser.Blocks(), sourceName(*output), sourceCode)
+		if err := writeSourceMap(*sourceMapFile, sourceMap); err != nil {
+			return err
+		}
+	}
+	return writeOutput(*output, sourceCode)
+}
+
+func exprsToSource(exprs []ast.Expr) string {
+	var builder strings.Builder
+	for i, expr := range exprs {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(ast.FormatStatement(expr))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// sourceName is the name of the decompiled file in the source map
+func sourceName(output string) string {
+	if output == "" || output == "-" {
+		return "<stdout>"
+	}
+	return filepath.Base(output)
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"Falcon/design"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var designCommand = &Command{
+	Name:    "design",
+	Usage:   "design <to-scm|to-aiml [-o output] [input] | check [-json] [-components extension.json] [Screen1.aiml] | diff [-json] [-o output] old.aiml new.aiml | patch [-o output] Screen1.aiml patch.json>",
+	Summary: "Converts screen designs between .aiml (XML) and .scm (JSON), checks, compares and patches them",
+	Run:     runDesign,
+}
+
+func runDesign(args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("expected a design sub command")
+	}
+	var convert func(name string, content string) (string, error)
+	switch args[0] {
+	case "check":
+		return runDesignCheck(args[1:])
+	case "diff":
+		return runDesignDiff(args[1:])
+	case "patch":
+		return runDesignPatch(args[1:])
+	case "to-scm":
+		convert = func(name string, content string) (string, error) {
+			// every problem of the design is reported, not only the first one
+			if err := reportDiagnostics(design.Validate(name, content, nil), false); err != nil {
+				return "", err
+			}
+			return design.NewXmlParser(content).ConvertXmlToSchema()
+		}
+	case "to-aiml":
+		convert = func(name string, content string) (string, error) {
+			return design.NewSchemaParser(content).ConvertSchemaToXml()
+		}
+	default:
+		return usageErrorf("unknown design sub command '%'", args[0])
+	}
+	fs := flag.NewFlagSet("design "+args[0], flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	positional, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return err
+	}
+	content, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	converted, err := convert(inputName(input), content)
+	if err != nil {
+		return err
+	}
+	return writeContent(*output, converted)
+}
+
+func runDesignCheck(args []string) error {
+	fs := flag.NewFlagSet("design check", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "print diagnostics as JSON")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return err
+	}
+	content, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	return reportDiagnostics(design.Validate(inputName(input), content, components), *asJson)
+}
+
+// runDesignDiff prints the changes between two versions of a design, or their patch in JSON
+func runDesignDiff(args []string) error {
+	fs := flag.NewFlagSet("design diff", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	asJson := fs.Bool("json", false, "print the patch that design patch applies")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageErrorf("expected the old and the new design")
+	}
+	var versions [2]*design.Component
+	for i, path := range positional {
+		content, err := readInput(path)
+		if err != nil {
+			return err
+		}
+		if versions[i], err = design.ParseDesign(content); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	patch := design.Diff(versions[0], versions[1])
+	if *asJson {
+		content, err := json.MarshalIndent(patch, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeOutput(*output, string(content))
+	}
+	if len(patch.Changes) == 0 && *output == "" {
+		return nil
+	}
+	lines := make([]string, len(patch.Changes))
+	for i, change := range patch.Changes {
+		lines[i] = change.String()
+	}
+	return writeOutput(*output, strings.Join(lines, "\n"))
+}
+
+// runDesignPatch applies the changes made to a copy of a design to another copy, nothing is written
+// when some don't apply
+func runDesignPatch(args []string) error {
+	fs := flag.NewFlagSet("design patch", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageErrorf("expected the design and the patch")
+	}
+	content, err := readInput(positional[0])
+	if err != nil {
+		return err
+	}
+	screen, err := design.ParseDesign(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(positional[0]), err)
+	}
+	patchContent, err := readInput(positional[1])
+	if err != nil {
+		return err
+	}
+	patch, err := design.ReadPatch([]byte(patchContent))
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(positional[1]), err)
+	}
+	if err := patch.Apply(screen); err != nil {
+		return fmt.Errorf("conflicts with %s:\n%w", filepath.Base(positional[0]), err)
+	}
+	// the design is written back in its own format
+	if design.IsSchema(content) {
+		return writeContent(*output, design.SchemaOf(screen))
+	}
+	var patched bytes.Buffer
+	if err := screen.WriteXML(&patched, 0); err != nil {
+		return err
+	}
+	return writeContent(*output, patched.String())
+}
+
+// loadDesign reads the screen design of the file, else the Screen1.aiml or Screen1.scm found next to
+// Screen1.mist. There's no design for the standard input or when none is found.
+func loadDesign(designFile string, input string) (*design.Component, error) {
+	if designFile == "" {
+		designFile = design.FindDesign(input)
+		if designFile == "" {
+			return nil, nil
+		}
+	}
+	content, err := readInput(designFile)
+	if err != nil {
+		return nil, err
+	}
+	screen, err := design.ParseDesign(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(designFile), err)
+	}
+	return screen, nil
+}
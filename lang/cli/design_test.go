@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// a stock screen App Inventor saved
+const stockScreen = "../design/testdata/Screen1.scm"
+
+// a screen with a property App Inventor saved as null
+const nullProperty = `#|
+$JSON
+{"authURL":[],"YaVersion":"208","Source":"Form","Properties":{"$Name":"Screen1","$Type":"Form","$Version":"31",` +
+	`"AppName":"app","Title":null,"Uuid":"0","$Components":[{"$Name":"Label1","$Type":"Label","$Version":"5","Text":null,"Uuid":"1"}]}}
+|#`
+
+func TestDesignRoundTrip(t *testing.T) {
+	for _, schema := range []string{readFile(t, stockScreen), nullProperty} {
+		aiml := mustRun(t, schema, "design", "to-aiml")
+		if back := mustRun(t, aiml, "design", "to-scm"); back != schema {
+			t.Errorf("the design changed when converted to .aiml and back:\n%s\nthen:\n%s", schema, back)
+		}
+	}
+
+	schema := readFile(t, stockScreen)
+	aiml := mustRun(t, schema, "design", "to-aiml")
+
+	dir := t.TempDir()
+	aimlFile, scmFile := filepath.Join(dir, "Screen1.aiml"), filepath.Join(dir, "Screen1.scm")
+	mustRun(t, "", "design", "to-aiml", "-o", aimlFile, stockScreen)
+	mustRun(t, "", "design", "to-scm", "-o", scmFile, aimlFile)
+	if back := readFile(t, scmFile); back != schema {
+		t.Errorf("the design changed when converted through files:\n%s", back)
+	}
+	if again := mustRun(t, "", "design", "to-aiml", scmFile); again != aiml {
+		t.Errorf("the .aiml design changed when converted again:\n%s\nthen:\n%s", aiml, again)
+	}
+}
+
+func TestDesignPatchKeepsTheFormat(t *testing.T) {
+	dir := t.TempDir()
+	schema := readFile(t, stockScreen)
+	aiml := mustRun(t, schema, "design", "to-aiml")
+	old := writeFile(t, dir, "old.aiml", aiml)
+	new := writeFile(t, dir, "new.aiml", aiml)
+	patch := writeFile(t, dir, "patch.json", mustRun(t, "", "design", "diff", "-json", old, new))
+	if patched := mustRun(t, "", "design", "patch", stockScreen, patch); patched != schema {
+		t.Errorf("an empty patch changed the design:\n%s", patched)
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"Falcon/code/context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// reportDiagnostics prints the diagnostics to stderr, either rendered for humans or as a JSON
+// array, and returns an error when at least one of them is an error
+func reportDiagnostics(diagnostics []*context.Diagnostic, asJson bool) error {
+	if asJson {
+		if diagnostics == nil {
+			diagnostics = []*context.Diagnostic{}
+		}
+		encoder := json.NewEncoder(stderr)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diagnostics); err != nil {
+			return err
+		}
+	} else {
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintf(stderr, "%s%s: %s [%s]\n",
+				diagnostic.Position(), diagnostic.Severity.String(), diagnostic.Message, diagnostic.Code)
+			for _, related := range diagnostic.Related {
+				fmt.Fprintf(stderr, "  %snote: %s\n", spanPosition(related.Span), related.Message)
+			}
+		}
+	}
+	errorCount := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == context.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return errors.New("failed with " + strconv.Itoa(errorCount) + " error(s)")
+	}
+	return nil
+}
+
+func spanPosition(span context.Span) string {
+	diagnostic := context.Diagnostic{Span: span}
+	return diagnostic.Position()
+}
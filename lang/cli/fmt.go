@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"Falcon/design"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var fmtCommand = &Command{
+	Name:    "fmt",
+	Usage:   "fmt [-check] [-w] [file.mist ...]",
+	Summary: "Formats Falcon source code in the canonical style",
+	Run:     runFmt,
+}
+
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := fs.Bool("check", false, "list the files that are not formatted and fail if there are any")
+	write := fs.Bool("w", false, "write the result back to the source files")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *write && len(positional) == 0 {
+		return usageErrorf("-w needs at least one file")
+	}
+	if len(positional) == 0 {
+		positional = []string{""}
+	}
+	unformatted := 0
+	for _, input := range positional {
+		sourceCode, err := readInput(input)
+		if err != nil {
+			return err
+		}
+		screen, err := loadDesign("", input)
+		if err != nil {
+			return err
+		}
+		formatted, err := formatSource(inputName(input), sourceCode, screen)
+		if err != nil {
+			return err
+		}
+		switch {
+		case *check:
+			if formatted != sourceCode {
+				unformatted++
+				fmt.Fprintln(stdout, inputName(input))
+			}
+		case *write:
+			if formatted != sourceCode {
+				if err := os.WriteFile(input, []byte(formatted), 0644); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := writeOutput("", formatted); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted > 0 {
+		return errors.New(strconv.Itoa(unformatted) + " file(s) are not formatted")
+	}
+	return nil
+}
+
+// formatSource parses the source code and prints it back in the canonical style, comments included.
+// The components declared by the design are left out of the @ headers.
+func formatSource(fileName string, sourceCode string, screen *design.Component) (string, error) {
+	expressions, langParser, diagnostics := parseSourceWith(fileName, sourceCode, parseOptions{screen: screen})
+	if err := reportDiagnostics(diagnostics, false); err != nil {
+		return "", err
+	}
+	if len(langParser.Tokens) == 0 {
+		// nothing but comments and whitespace
+		return sourceCode, nil
+	}
+	definitions := langParser.GetComponentDefinitionsCode()
+	code := exprsToSource(expressions)
+	if definitions != "" && code != "" {
+		definitions += "\n"
+	}
+	return definitions + code, nil
+}
@@ -0,0 +1,35 @@
+package cli
+
+import "testing"
+
+func TestFmtKeepsComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"trailing comment of a statement", "func f() {\n  println(1) // one\n}\n"},
+		{"trailing comment of a local", "func f() {\n  local x = 1 // c\n  println(x)\n}\n"},
+		{"trailing comments of locals", "func f() {\n  // above\n  local x = 1 // x\n  local y = 2 // y\n  println(x + y) // sum\n}\n"},
+		{"last local", "func f() {\n  println(1)\n  local z = 3 // last\n}\n"},
+		{"comment after a body", "func f() {\n  if (true) {\n    println(1)\n  } // done\n}\n"},
+		{"header of the file", "// header\n\nglobal x = 1\n"},
+		{"header above the definitions", "// header\n\n@Button { Button1 }\n\nglobal x = 1\n"},
+		{"comment apart from a statement", "global a = 1\n\n// about b\n\nglobal b = 2\n\n// c\nglobal c = 3\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted := mustRun(t, test.source, "fmt")
+			if formatted != test.source {
+				t.Errorf("expected the source as it is:\n%s\nbut got:\n%s", test.source, formatted)
+			}
+		})
+	}
+}
+
+func TestFmtCanonical(t *testing.T) {
+	source := "func f(){\n  local x=1 // c\n    println( x )\n}\n"
+	expected := "func f() {\n  local x = 1 // c\n  println(x)\n}\n"
+	if formatted := mustRun(t, source, "fmt"); formatted != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, formatted)
+	}
+}
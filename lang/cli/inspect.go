@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"flag"
+	"strings"
+)
+
+var tokensCommand = &Command{
+	Name:    "tokens",
+	Usage:   "tokens [file.mist]",
+	Summary: "Prints the tokens produced by the lexer",
+	Run:     runTokens,
+}
+
+var astCommand = &Command{
+	Name:    "ast",
+	Usage:   "ast [file.mist]",
+	Summary: "Parses the source and prints the syntax tree as Falcon code",
+	Run:     runAst,
+}
+
+func runTokens(args []string) error {
+	input, sourceCode, err := readSingleSource("tokens", args)
+	if err != nil {
+		return err
+	}
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: inputName(input)}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	var builder strings.Builder
+	for _, token := range tokens {
+		builder.WriteString(token.Debug())
+		builder.WriteString("\n")
+	}
+	if err := writeOutput("", builder.String()); err != nil {
+		return err
+	}
+	return reportDiagnostics(diagnostics, false)
+}
+
+func runAst(args []string) error {
+	input, sourceCode, err := readSingleSource("ast", args)
+	if err != nil {
+		return err
+	}
+	screen, err := loadDesign("", input)
+	if err != nil {
+		return err
+	}
+	expressions, langParser, diagnostics := parseSourceWith(inputName(input), sourceCode, parseOptions{screen: screen})
+	if err := reportDiagnostics(diagnostics, false); err != nil {
+		return err
+	}
+	return writeOutput("", langParser.GetComponentDefinitionsCode()+exprsToSource(expressions))
+}
+
+func readSingleSource(name string, args []string) (string, string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return "", "", err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return "", "", err
+	}
+	sourceCode, err := readInput(input)
+	return input, sourceCode, err
+}
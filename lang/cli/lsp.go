@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"Falcon/lsp"
+	"flag"
+)
+
+var lspCommand = &Command{
+	Name:    "lsp",
+	Usage:   "lsp [--stdio]",
+	Summary: "Runs the language server over stdin and stdout",
+	Run:     runLsp,
+}
+
+func runLsp(args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	// editors pass --stdio by convention, it's the only transport supported
+	fs.Bool("stdio", true, "communicate over stdin and stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageErrorf("unexpected argument '%'", positional[0])
+	}
+	return lsp.NewServer(stdin, stdout).Serve()
+}
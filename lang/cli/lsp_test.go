@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// frame writes the messages with the Content-Length header of the protocol
+func frame(t *testing.T, messages ...any) string {
+	t.Helper()
+	var framed strings.Builder
+	for _, message := range messages {
+		content, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		framed.WriteString("Content-Length: " + strconv.Itoa(len(content)) + "\r\n\r\n")
+		framed.Write(content)
+	}
+	return framed.String()
+}
+
+type lspMessage struct {
+	Id     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// unframe reads the messages the server wrote
+func unframe(t *testing.T, output string) []lspMessage {
+	t.Helper()
+	reader := bufio.NewReader(strings.NewReader(output))
+	var messages []lspMessage
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return messages
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			t.Fatalf("malformed header %q", header)
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			t.Fatal(err)
+		}
+		var message lspMessage
+		if err := json.Unmarshal(content, &message); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+}
+
+func request(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notification(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func positionAt(line int, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": "file:///project/Screen1.mist"},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestLspRoundTrip(t *testing.T) {
+	text := "@Button { Button1 }\n" +
+		"when Button1.Click {\n" +
+		"  println(missing)\n" +
+		"  Button1.Text = \"clicked\"\n" +
+		"}\n"
+	input := frame(t,
+		request(1, "initialize", map[string]any{"processId": nil, "capabilities": map[string]any{}}),
+		notification("initialized", map[string]any{}),
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{
+			"uri": "file:///project/Screen1.mist", "languageId": "falcon", "version": 1, "text": text,
+		}}),
+		// in missing, then right after Button1.
+		request(2, "textDocument/completion", positionAt(2, 11)),
+		request(3, "textDocument/completion", positionAt(3, 10)),
+		request(4, "shutdown", nil),
+		notification("exit", nil),
+	)
+	messages := unframe(t, mustRun(t, input, "lsp", "--stdio"))
+	if len(messages) != 5 {
+		t.Fatalf("expected 4 responses and the diagnostics but got %d messages", len(messages))
+	}
+
+	var initialized struct {
+		Capabilities struct {
+			CompletionProvider struct {
+				TriggerCharacters []string `json:"triggerCharacters"`
+			} `json:"completionProvider"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(messages[0].Result, &initialized); err != nil || messages[0].Id == nil || *messages[0].Id != 1 {
+		t.Fatalf("unexpected response to initialize: %+v", messages[0])
+	}
+	if triggers := initialized.Capabilities.CompletionProvider.TriggerCharacters; len(triggers) == 0 {
+		t.Errorf("expected the completion to be triggered by characters")
+	}
+
+	var published struct {
+		URI         string `json:"uri"`
+		Diagnostics []struct {
+			Range struct {
+				Start struct{ Line, Character int }
+			}
+			Message string
+		}
+	}
+	if messages[1].Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected the diagnostics of the document but got %+v", messages[1])
+	}
+	if err := json.Unmarshal(messages[1].Params, &published); err != nil {
+		t.Fatal(err)
+	}
+	if len(published.Diagnostics) == 0 || published.Diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("expected a diagnostic of missing on the third line but got %+v", published.Diagnostics)
+	}
+
+	var items []struct {
+		Label    string
+		Kind     int
+		SortText string
+	}
+	if err := json.Unmarshal(messages[2].Result, &items); err != nil || len(items) == 0 {
+		t.Fatalf("expected completion items but got %s", messages[2].Result)
+	}
+	if items[0].Label != "Button1" {
+		t.Errorf("expected the component first but got %s", items[0].Label)
+	}
+	for i := 1; i < len(items); i++ {
+		if items[i-1].SortText >= items[i].SortText {
+			t.Fatalf("the sort text of %s doesn't keep it before %s", items[i-1].Label, items[i].Label)
+		}
+	}
+
+	if err := json.Unmarshal(messages[3].Result, &items); err != nil || len(items) == 0 {
+		t.Fatalf("expected the members of Button1 but got %s", messages[3].Result)
+	}
+	members := map[string]bool{}
+	for _, item := range items {
+		members[item.Label] = true
+		if item.Label == "if" {
+			t.Errorf("expected only the members of Button1 but got the keyword if")
+		}
+	}
+	if !members["Text"] || !members["Enabled"] {
+		t.Errorf("expected the properties of Button1 but got %+v", items)
+	}
+
+	if messages[4].Id == nil || *messages[4].Id != 4 || messages[4].Error != nil {
+		t.Errorf("unexpected response to shutdown: %+v", messages[4])
+	}
+}
+
+func TestLspDefinitionAndHover(t *testing.T) {
+	text := "@Button { Button1 }\n" +
+		"global count = 0\n" +
+		"func double(x) = { x * 2 }\n" +
+		"when Button1.Click {\n" +
+		"  this.count = double(this.count)\n" +
+		"}\n"
+	input := frame(t,
+		request(1, "initialize", map[string]any{"processId": nil, "capabilities": map[string]any{}}),
+		// a malformed notification is logged, the server carries on
+		notification("textDocument/didOpen", "Screen1.mist"),
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{
+			"uri": "file:///project/Screen1.mist", "languageId": "falcon", "version": 1, "text": text,
+		}}),
+		request(2, "textDocument/definition", positionAt(4, 16)),
+		request(3, "textDocument/definition", positionAt(4, 28)),
+		request(4, "textDocument/definition", positionAt(2, 20)),
+		request(5, "textDocument/hover", positionAt(4, 16)),
+		request(6, "textDocument/hover", positionAt(3, 7)),
+		request(7, "textDocument/hover", positionAt(2, 23)),
+		request(8, "shutdown", nil),
+		notification("exit", nil),
+	)
+	messages := unframe(t, mustRun(t, input, "lsp", "--stdio"))
+	if len(messages) != 10 {
+		t.Fatalf("expected 8 responses, the log and the diagnostics but got %d messages", len(messages))
+	}
+	var logged struct {
+		Type    int
+		Message string
+	}
+	if err := json.Unmarshal(messages[1].Params, &logged); err != nil || messages[1].Method != "window/logMessage" ||
+		logged.Type != 1 || !strings.HasPrefix(logged.Message, "textDocument/didOpen: ") {
+		t.Errorf("expected the malformed notification to be logged as an error but got %+v", messages[1])
+	}
+
+	type position struct{ Line, Character int }
+	type span struct{ Start, End position }
+	type location struct {
+		URI   string
+		Range span
+	}
+	definitions := []struct {
+		name     string
+		expected *location
+	}{
+		{"procedure called", &location{"file:///project/Screen1.mist", span{position{2, 5}, position{2, 11}}}},
+		{"global", &location{"file:///project/Screen1.mist", span{position{1, 7}, position{1, 12}}}},
+		{"parameter", nil},
+	}
+	for i, definition := range definitions {
+		var found *location
+		if err := json.Unmarshal(messages[3+i].Result, &found); err != nil {
+			t.Fatal(err)
+		}
+		if (found == nil) != (definition.expected == nil) || found != nil && *found != *definition.expected {
+			t.Errorf("expected the definition of the %s at %+v but got %s", definition.name, definition.expected,
+				messages[3+i].Result)
+		}
+	}
+
+	hovers := []struct {
+		name  string
+		code  string
+		start position
+	}{
+		{"procedure called", "func double(x) = any", position{4, 15}},
+		{"component", "Button1: Button", position{3, 5}},
+		{"number", "2\n// number", position{2, 23}},
+	}
+	for i, hover := range hovers {
+		var found struct {
+			Contents struct{ Kind, Value string }
+			Range    span
+		}
+		if err := json.Unmarshal(messages[6+i].Result, &found); err != nil {
+			t.Fatal(err)
+		}
+		if found.Contents.Value != "```falcon\n"+hover.code+"\n```" || found.Range.Start != hover.start {
+			t.Errorf("expected the hover of the %s to show %q at %+v but got %s", hover.name, hover.code, hover.start,
+				messages[6+i].Result)
+		}
+	}
+}
+
+func TestLspIncludesTheLibrary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "lib.mist", "func go() {\n  println(\"gone\")\n}\n")
+	uri := "file://" + filepath.ToSlash(dir) + "/Screen1.mist"
+	input := frame(t,
+		request(1, "initialize", map[string]any{"processId": nil, "capabilities": map[string]any{}}),
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{
+			"uri": uri, "languageId": "falcon", "version": 1, "text": "func start() {\n  go()\n}\n",
+		}}),
+		request(2, "textDocument/definition", map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": 1, "character": 3},
+		}),
+		request(3, "shutdown", nil),
+		notification("exit", nil),
+	)
+	messages := unframe(t, mustRun(t, input, "lsp", "--stdio"))
+	if len(messages) != 4 {
+		t.Fatalf("expected 3 responses and the diagnostics but got %d messages", len(messages))
+	}
+	var published struct{ Diagnostics []json.RawMessage }
+	if err := json.Unmarshal(messages[1].Params, &published); err != nil || len(published.Diagnostics) != 0 {
+		t.Errorf("expected go() to be found in the library but got %s", messages[1].Params)
+	}
+	var found struct {
+		URI   string
+		Range struct{ Start struct{ Line, Character int } }
+	}
+	if err := json.Unmarshal(messages[2].Result, &found); err != nil {
+		t.Fatal(err)
+	}
+	if found.URI != "file://"+filepath.ToSlash(dir)+"/lib.mist" || found.Range.Start.Line != 0 || found.Range.Start.Character != 5 {
+		t.Errorf("expected the definition of go() in the library but got %s", messages[2].Result)
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"Falcon/code/diff"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+var mergeCommand = &Command{
+	Name:    "merge",
+	Usage:   "merge [-o file.mist] [-json] [-components extension.json] [-design Screen1.aiml] base.mist text.mist blocks.mist",
+	Summary: "Merges the source edited as text with the source decompiled from the edited blocks",
+	Run:     runMerge,
+}
+
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	asJson := fs.Bool("json", false, "print diagnostics as JSON")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	designFile := fs.String("design", "", "screen design (.aiml or .scm) declaring the components, found next to the text by default")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		return usageErrorf("expected 3 arguments but got %", strconv.Itoa(len(positional)))
+	}
+	var versions []string
+	for _, path := range positional {
+		content, err := readInput(path)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, content)
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	screen, err := loadDesign(*designFile, positional[1])
+	if err != nil {
+		return err
+	}
+	options := diff.Options{Components: components, Screen: screen}
+	result, diagnostics, err := diff.Merge(versions[0], versions[1], versions[2], options)
+	if reportErr := reportDiagnostics(diagnostics, *asJson); reportErr != nil {
+		return reportErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*output, result.Source); err != nil {
+		return err
+	}
+	for _, conflict := range result.Conflicts {
+		fmt.Fprintf(stderr, "conflict: %s was changed in the text and in the blocks\n", conflict.Key)
+	}
+	if len(result.Conflicts) > 0 {
+		return errors.New(strconv.Itoa(len(result.Conflicts)) + " conflict(s)")
+	}
+	return nil
+}
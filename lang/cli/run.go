@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"Falcon/code/context"
+	"Falcon/code/interp"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var runCommand = &Command{
+	Name:    "run",
+	Usage:   "run [-design Screen1.aiml] [-fire Button1.Click | -call procedure] [-seed n] [file.mist] [arg ...]",
+	Summary: "Runs Falcon source code without a device, calling a procedure or firing an event",
+	Run:     runRun,
+}
+
+func runRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	call := fs.String("call", "", "procedure to call once the program is loaded")
+	fire := fs.String("fire", "", "component event to fire once the program is loaded")
+	designFile := fs.String("design", "", "screen design (.aiml or .scm) the components are created from, found next to the file by default")
+	seed := fs.Int64("seed", 0, "seed of the random generator, for reproducible runs")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	input := ""
+	if len(positional) > 0 {
+		input = positional[0]
+		positional = positional[1:]
+	}
+	if *call != "" && *fire != "" {
+		return usageErrorf("-call and -fire cannot be used together")
+	}
+	if *call == "" && *fire == "" && len(positional) > 0 {
+		return usageErrorf("arguments are only passed with -call or -fire")
+	}
+	sourceCode, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	screen, err := loadDesign(*designFile, input)
+	if err != nil {
+		return err
+	}
+	options := parseOptions{screen: screen}
+	library, diagnostics, err := includeLibrary(input, &options)
+	if err != nil {
+		return err
+	}
+	expressions, langParser, sourceDiagnostics := parseSourceWith(inputName(input), sourceCode, options)
+	if err := reportDiagnostics(append(diagnostics, sourceDiagnostics...), false); err != nil {
+		return err
+	}
+	expressions, located := withLibrary(library, expressions, options, langParser)
+	interpreter := interp.New().Locate(located)
+	interpreter.Output = stdout
+	if *seed != 0 {
+		interpreter.Seed(*seed)
+	}
+	if screen != nil {
+		interpreter.LoadDesign(screen)
+	}
+	if err := interpreter.Load(expressions); err != nil {
+		return runtimeError(err)
+	}
+	callArgs := make([]interp.Value, len(positional))
+	for k, arg := range positional {
+		// numeric strings are numbers to the interpreter
+		callArgs[k] = arg
+	}
+	if *fire != "" {
+		component, event, ok := strings.Cut(*fire, ".")
+		if !ok {
+			return usageErrorf("expected an event like Button1.Click but got '%'", *fire)
+		}
+		if err := interpreter.Fire(component, event, callArgs...); err != nil {
+			return runtimeError(err)
+		}
+		return nil
+	}
+	if *call == "" {
+		return nil
+	}
+	result, err := interpreter.Call(*call, callArgs...)
+	if err != nil {
+		return runtimeError(err)
+	}
+	if result != nil {
+		fmt.Fprintln(stdout, interp.ToText(result))
+	}
+	return nil
+}
+
+// runtimeError reports the diagnostic of a failed run
+func runtimeError(err error) error {
+	var diagnostic *context.Diagnostic
+	if errors.As(err, &diagnostic) {
+		return reportDiagnostics([]*context.Diagnostic{diagnostic}, false)
+	}
+	return err
+}
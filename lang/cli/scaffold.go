@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"Falcon/scaffold"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var scaffoldCommand = &Command{
+	Name:    "scaffold",
+	Usage:   "scaffold [-o file.mist] [-json] [-components extension.json] Screen1.aiml",
+	Summary: "Writes the handlers and the globals of the components of a screen design into its source",
+	Run:     runScaffold,
+}
+
+func runScaffold(args []string) error {
+	fs := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	output := fs.String("o", "", "source file to write the skeleton into, Screen1.mist next to Screen1.aiml by default, - for stdout")
+	asJson := fs.Bool("json", false, "print diagnostics as JSON")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf("expected a single screen design")
+	}
+	designFile := positional[0]
+	screen, err := loadDesign(designFile, "")
+	if err != nil {
+		return err
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	sourceFile := *output
+	if sourceFile == "" {
+		sourceFile = strings.TrimSuffix(designFile, filepath.Ext(designFile)) + ".mist"
+	}
+	// an existing source is merged into, the stdout starts from an empty one
+	var sourceCode string
+	if sourceFile != "-" {
+		content, err := os.ReadFile(sourceFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		sourceCode = string(content)
+	}
+	merged, diagnostics, err := scaffold.Merge(inputName(sourceFile), sourceCode, screen, components)
+	if reportErr := reportDiagnostics(diagnostics, *asJson); reportErr != nil {
+		return reportErr
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(sourceFile, merged)
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/context"
+	"Falcon/code/interp"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/design"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var testCommand = &Command{
+	Name:    "test",
+	Usage:   "test [-junit report.xml] [-run name] [file_test.mist | directory ...]",
+	Summary: "Runs the test blocks of *_test.mist files against their source files",
+	Run:     runTest,
+}
+
+const testSuffix = "_test.mist"
+
+func runTest(args []string) error {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	junit := flags.String("junit", "", "write a JUnit XML report to the file")
+	filter := flags.String("run", "", "only run the tests whose name contains the text")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		positional = []string{"."}
+	}
+	testFiles, err := findTestFiles(positional)
+	if err != nil {
+		return err
+	}
+	if len(testFiles) == 0 {
+		return errors.New("no " + testSuffix + " files found")
+	}
+	var suites []*testSuite
+	for _, testFile := range testFiles {
+		suites = append(suites, runTestFile(testFile, *filter))
+	}
+	passed, failed := 0, 0
+	for _, suite := range suites {
+		for _, result := range suite.Results {
+			if result.Diagnostic == nil {
+				passed++
+				fmt.Fprintf(stdout, "PASS  %s > %s\n", suite.Name, result.Name)
+				continue
+			}
+			failed++
+			fmt.Fprintf(stdout, "FAIL  %s > %s\n", suite.Name, result.Name)
+			fmt.Fprintf(stdout, "      %s%s\n", result.Diagnostic.Position(), result.Diagnostic.Message)
+		}
+	}
+	fmt.Fprintf(stdout, "\n%d passed, %d failed\n", passed, failed)
+	if *junit != "" {
+		report, err := junitReport(suites)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*junit, report, 0644); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " test(s) failed")
+	}
+	return nil
+}
+
+// findTestFiles expands the directories into the test files they hold, sorted by path
+func findTestFiles(paths []string) ([]string, error) {
+	var testFiles []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			testFiles = append(testFiles, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), testSuffix) {
+				testFiles = append(testFiles, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(testFiles)
+	return testFiles, nil
+}
+
+type testResult struct {
+	Name       string
+	Duration   time.Duration
+	Output     string
+	Diagnostic *context.Diagnostic // nil when the test passed
+}
+
+type testSuite struct {
+	Name     string
+	Results  []*testResult
+	Duration time.Duration
+}
+
+// runTestFile runs every test of the file. Screen1_test.mist is run along with Screen1.mist
+// and the design Screen1.aiml or Screen1.scm, when they are present next to it.
+func runTestFile(testFile string, filter string) *testSuite {
+	suite := &testSuite{Name: filepath.Base(testFile)}
+	start := time.Now()
+	defer func() { suite.Duration = time.Since(start) }()
+
+	program, diagnostic := loadTestFile(testFile)
+	if diagnostic != nil {
+		// nothing can run, the file is reported as a single failure
+		suite.Results = append(suite.Results, &testResult{Name: suite.Name, Diagnostic: diagnostic})
+		return suite
+	}
+	for _, test := range program.tests {
+		if !strings.Contains(test.Name, filter) {
+			continue
+		}
+		suite.Results = append(suite.Results, runSingleTest(program, test))
+	}
+	return suite
+}
+
+// runSingleTest runs the test on a fresh interpreter, so that tests do not see each other's changes
+func runSingleTest(program *testProgram, test *procedures.Test) *testResult {
+	result := &testResult{Name: test.Name}
+	start := time.Now()
+	var output bytes.Buffer
+	interpreter := interp.New().Locate(program.located)
+	interpreter.Output = &output
+	if program.screen != nil {
+		interpreter.LoadDesign(program.screen)
+	}
+	err := interpreter.Load(program.expressions)
+	if err == nil {
+		err = interpreter.RunTest(test)
+	}
+	if err != nil {
+		var diagnostic *context.Diagnostic
+		if !errors.As(err, &diagnostic) {
+			diagnostic = &context.Diagnostic{Code: context.CodeInternal, Message: err.Error()}
+		}
+		result.Diagnostic = diagnostic
+	}
+	result.Duration = time.Since(start)
+	result.Output = output.String()
+	return result
+}
+
+// testProgram is a test file along with the source file it tests and its design
+type testProgram struct {
+	expressions []ast.Expr // of the library and the source file, followed by the ones of the test file
+	tests       []*procedures.Test
+	located     []mistparser.LocatedExpr
+	screen      *design.Component
+}
+
+// loadTestFile parses the test file along with its source file, and reads the design if there is one
+func loadTestFile(testFile string) (*testProgram, *context.Diagnostic) {
+	base := strings.TrimSuffix(testFile, testSuffix)
+	screen, err := loadDesign("", base+".mist")
+	if err != nil {
+		return nil, &context.Diagnostic{Code: context.CodeInternal, Message: err.Error()}
+	}
+	program := &testProgram{screen: screen}
+	var sourceParser *mistparser.LangParser
+	if sourceCode, err := os.ReadFile(base + ".mist"); err == nil {
+		options := parseOptions{screen: screen}
+		library, diagnostics, err := includeLibrary(base+".mist", &options)
+		if err != nil {
+			return nil, &context.Diagnostic{Code: context.CodeInternal, Message: err.Error()}
+		}
+		expressions, langParser, sourceDiagnostics := parseSourceWith(filepath.Base(base+".mist"), string(sourceCode), options)
+		if diagnostic := firstError(append(diagnostics, sourceDiagnostics...)); diagnostic != nil {
+			return nil, diagnostic
+		}
+		program.expressions, program.located = withLibrary(library, expressions, options, langParser)
+		sourceParser = langParser
+	}
+	testCode, err := os.ReadFile(testFile)
+	if err != nil {
+		return nil, &context.Diagnostic{Code: context.CodeInternal, Message: err.Error()}
+	}
+	options := parseOptions{included: sourceParser, screen: screen}
+	expressions, testParser, diagnostics := parseSourceWith(filepath.Base(testFile), string(testCode), options)
+	if diagnostic := firstError(diagnostics); diagnostic != nil {
+		return nil, diagnostic
+	}
+	for _, expression := range expressions {
+		if test, ok := expression.(*procedures.Test); ok {
+			program.tests = append(program.tests, test)
+		}
+	}
+	program.expressions = append(program.expressions, expressions...)
+	program.located = append(program.located, testParser.Located...)
+	return program, nil
+}
+
+func firstError(diagnostics []*context.Diagnostic) *context.Diagnostic {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == context.SeverityError {
+			return diagnostic
+		}
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport builds the report, failed assertions are failures and anything else is an error
+func junitReport(suites []*testSuite) ([]byte, error) {
+	var report junitTestSuites
+	var total time.Duration
+	for _, suite := range suites {
+		junitSuite := junitTestSuite{Name: suite.Name, Time: seconds(suite.Duration)}
+		for _, result := range suite.Results {
+			testCase := junitTestCase{
+				Name:      result.Name,
+				ClassName: strings.TrimSuffix(suite.Name, ".mist"),
+				Time:      seconds(result.Duration),
+				SystemOut: result.Output,
+			}
+			if diagnostic := result.Diagnostic; diagnostic != nil {
+				problem := &junitProblem{
+					Message: diagnostic.Message,
+					Type:    diagnostic.Code,
+					Text:    diagnostic.Render(false),
+				}
+				if diagnostic.Code == context.CodeAssertion {
+					testCase.Failure = problem
+					junitSuite.Failures++
+				} else {
+					testCase.Error = problem
+					junitSuite.Errors++
+				}
+			}
+			junitSuite.Cases = append(junitSuite.Cases, testCase)
+			junitSuite.Tests++
+		}
+		report.Tests += junitSuite.Tests
+		report.Failures += junitSuite.Failures
+		report.Errors += junitSuite.Errors
+		report.Suites = append(report.Suites, junitSuite)
+		total += suite.Duration
+	}
+	report.Time = seconds(total)
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+func seconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', 3, 64)
+}
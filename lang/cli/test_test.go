@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTestBlocksFireAndStub(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "Screen1.aiml", `<Screen id="Screen1">
+  <Button id="Button1" Text="Pet"/>
+  <Label id="Label1"/>
+  <TinyDB id="TinyDB1"/>
+</Screen>`)
+	writeFile(t, dir, "Screen1.mist", `global count = 0
+
+when Button1.Click {
+  this.count = this.count + 1
+  Label1.Text = "Petted " _ this.count
+}
+
+when Screen1.Initialize {
+  Label1.Text = TinyDB1.GetValue("name", "kitty")
+}
+`)
+	writeFile(t, dir, "Screen1_test.mist", `test "click" {
+  fire(Button1, "Click")
+  fire(Button1, "Click")
+  assertEquals("Petted 2", Label1.Text)
+}
+
+test "stub of a component" {
+  stub(TinyDB1, "GetValue", "Felix")
+  fire(Screen1, "Initialize")
+  assertEquals("Felix", Label1.Text)
+}
+
+test "stub of a type" {
+  stub("TinyDB", "GetValue", "Tom")
+  fire(Screen1, "Initialize")
+  assertEquals("Tom", Label1.Text)
+}
+
+test "not stubbed" {
+  fire(Screen1, "Initialize")
+  assertEquals("Felix", Label1.Text)
+}
+`)
+	out, _, code := run(t, "", "test", dir)
+	if code != ExitError {
+		t.Fatalf("expected the last test to fail:\n%s", out)
+	}
+	for _, line := range []string{"PASS  Screen1_test.mist > click", "PASS  Screen1_test.mist > stub of a component",
+		"PASS  Screen1_test.mist > stub of a type", "FAIL  Screen1_test.mist > not stubbed", "3 passed, 1 failed"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %s in:\n%s", line, out)
+		}
+	}
+}
+
+func TestFireOnlyInTests(t *testing.T) {
+	_, errOut, code := run(t, "@Button { Button1 }\nfunc f() {\n  fire(Button1, \"Click\")\n}\n", "compile")
+	if code == ExitOk || !strings.Contains(errOut, "fire() can only be used in tests") {
+		t.Errorf("expected fire() to be refused outside of tests but got:\n%s", errOut)
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"Falcon/code/parsers/blocklytoyail"
+	"flag"
+)
+
+var yailCommand = &Command{
+	Name:    "yail",
+	Usage:   "yail [-o file.yail] [-components extension.json] [blocks.xml]",
+	Summary: "Generates App Inventor YAIL from Blockly XML",
+	Run:     runYail,
+}
+
+func runYail(args []string) error {
+	fs := flag.NewFlagSet("yail", flag.ContinueOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	componentsFile := fs.String("components", "", "component descriptors of the extensions used, in the simple_components.json format")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	input, err := singleInput(positional)
+	if err != nil {
+		return err
+	}
+	xmlContent, err := readInput(input)
+	if err != nil {
+		return err
+	}
+	components, err := loadComponents(*componentsFile)
+	if err != nil {
+		return err
+	}
+	parser := blocklytoyail.NewParser(xmlContent)
+	parser.Components = components
+	return writeOutput(*output, parser.GenerateYail())
+}
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"encoding/xml"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -10,18 +12,102 @@ type XmlRoot struct {
 	XMLName xml.Name `xml:"xml"`
 	XMLNS   string   `xml:"xmlns,attr"`
 	Blocks  []Block  `xml:"block"`
+	// YaCodeBlocks ends the blocks of a screen in an App Inventor project
+	YaCodeBlocks *YaCodeBlocks `xml:"yacodeblocks,omitempty"`
+}
+
+type YaCodeBlocks struct {
+	YaVersion       string `xml:"ya-version,attr"`
+	LanguageVersion string `xml:"language-version,attr"`
 }
 
 type Block struct {
 	XMLName    xml.Name    `xml:"block"`
 	Type       string      `xml:"type,attr"`
+	ID         string      `xml:"id,attr,omitempty"`
+	Collapsed  bool        `xml:"collapsed,attr,omitempty"`
+	Disabled   bool        `xml:"disabled,attr,omitempty"`
+	Inline     *bool       `xml:"inline,attr,omitempty"`
+	X          *int        `xml:"x,attr,omitempty"`
+	Y          *int        `xml:"y,attr,omitempty"`
 	Mutation   *Mutation   `xml:"mutation,omitempty"`
 	Fields     []Field     `xml:"field"`
+	Comment    *Comment    `xml:"comment,omitempty"`
 	Values     []Value     `xml:"value"`
 	Statements []Statement `xml:"statement"`
 	Next       *Next       `xml:"next"`
+
+	// Expr is the expression the block was generated from, when it is known
+	Expr Expr `xml:"-"`
+}
+
+// BlockOf generates the block of an expression plugged into an input, linked to the expression and
+// in the state of its annotations
+func BlockOf(expr Expr, flags ...bool) Block {
+	aBlock := expr.Blockly(flags...)
+	aBlock.Expr = expr
+	if len(flags) == 0 || !flags[0] {
+		// a statement is given its state along with its comments, on the block that may wrap it
+		attachState(expr, &aBlock)
+	}
+	return aBlock
+}
+
+// Declaration names what a root block declares, such as an event handler or a procedure.
+// It is empty for the other blocks.
+func (b *Block) Declaration() string {
+	switch b.Type {
+	case "component_event":
+		if b.Mutation == nil {
+			return ""
+		}
+		if b.Mutation.IsGeneric {
+			return "event any " + b.Mutation.ComponentType + "." + b.Mutation.EventName
+		}
+		return "event " + b.Mutation.InstanceName + "." + b.Mutation.EventName
+	case "procedures_defnoreturn", "procedures_defreturn":
+		return "procedure " + b.field("NAME")
+	case "global_declaration":
+		return "global " + b.field("NAME")
+	}
+	return ""
+}
+
+func (b *Block) field(name string) string {
+	for _, field := range b.Fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Position returns where the block is on the workspace, only the root blocks are placed
+func (b *Block) Position() (x int, y int, placed bool) {
+	if b.X == nil || b.Y == nil {
+		return 0, 0, false
+	}
+	return *b.X, *b.Y, true
+}
+
+func (b *Block) SetPosition(x int, y int) {
+	b.X, b.Y = &x, &y
 }
 
+type Comment struct {
+	XMLName xml.Name `xml:"comment"`
+	Pinned  bool     `xml:"pinned,attr"`
+	Height  int      `xml:"h,attr,omitempty"`
+	Width   int      `xml:"w,attr,omitempty"`
+	Text    string   `xml:",chardata"`
+}
+
+// default size of the comment bubble of a block
+const (
+	commentHeight = 80
+	commentWidth  = 160
+)
+
 type Field struct {
 	Name  string `xml:"name,attr"`
 	Value string `xml:",chardata"`
@@ -82,10 +168,11 @@ type Arg struct {
 	Name string `xml:"name,attr"`
 }
 
+// FieldsFromMap makes the fields sorted by their names, so that the blocks generated are the same every time
 func FieldsFromMap(m map[string]string) []Field {
 	fields := make([]Field, 0, len(m))
-	for k, v := range m {
-		fields = append(fields, Field{k, v})
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		fields = append(fields, Field{k, m[k]})
 	}
 	return fields
 }
@@ -109,16 +196,16 @@ func ToArgs(names []string) []Arg {
 func ValuesByPrefix(namePrefix string, operands []Expr) []Value {
 	values := make([]Value, len(operands))
 	for i, operand := range operands {
-		values[i] = Value{Name: namePrefix + strconv.Itoa(i), Block: operand.Blockly(false)}
+		values[i] = Value{Name: namePrefix + strconv.Itoa(i), Block: BlockOf(operand, false)}
 	}
 	return values
 }
 
 func ValueArgsByPrefix(on Expr, onName string, namePrefix string, operands []Expr) []Value {
 	values := make([]Value, len(operands)+1)
-	values[0] = Value{Name: onName, Block: on.Blockly()}
+	values[0] = Value{Name: onName, Block: BlockOf(on)}
 	for i, operand := range operands {
-		values[i+1] = Value{Name: namePrefix + strconv.Itoa(i), Block: operand.Blockly(false)}
+		values[i+1] = Value{Name: namePrefix + strconv.Itoa(i), Block: BlockOf(operand, false)}
 	}
 	return values
 }
@@ -129,7 +216,7 @@ func MakeValues(operands []Expr, names ...string) []Value {
 	}
 	values := make([]Value, len(operands))
 	for i, operand := range operands {
-		values[i] = Value{Name: names[i], Block: operand.Blockly(false)}
+		values[i] = Value{Name: names[i], Block: BlockOf(operand, false)}
 	}
 	return values
 }
@@ -139,9 +226,9 @@ func MakeValueArgs(on Expr, onName string, operands []Expr, names ...string) []V
 		panic("len(operands) != len(names)")
 	}
 	values := make([]Value, len(operands)+1)
-	values[0] = Value{Name: onName, Block: on.Blockly()}
+	values[0] = Value{Name: onName, Block: BlockOf(on)}
 	for i, operand := range operands {
-		values[i+1] = Value{Name: names[i], Block: operand.Blockly(false)}
+		values[i+1] = Value{Name: names[i], Block: BlockOf(operand, false)}
 	}
 	return values
 }
@@ -172,14 +259,113 @@ func CreateStatement(name string, body []Expr) Statement {
 func ensureStatement(expr Expr) Block {
 	// First evaluate Blockly(). True indicates we expect a statement.
 	// This gives time for if expressions to mutate to if statement.
-	aBlock := expr.Blockly(true)
+	aBlock := BlockOf(expr, true)
 	if expr.Consumable(true) {
 		// It's still consumable, wrap around evaluate but ignore result
-		return Block{Type: "controls_eval_but_ignore", Values: []Value{{Block: aBlock}}}
+		aBlock = Block{Type: "controls_eval_but_ignore", Values: []Value{{Block: aBlock}}, Expr: expr}
 	}
+	attachComment(expr, &aBlock, false)
+	attachState(expr, &aBlock)
+	return aBlock
+}
+
+// RootBlock generates the block of a top level statement, along with its comments and its state
+func RootBlock(expr Expr) Block {
+	aBlock := BlockOf(expr, true)
+	attachComment(expr, &aBlock, false)
+	attachState(expr, &aBlock)
 	return aBlock
 }
 
+// TypedRootBlock is a RootBlock that keeps the type annotations of a declaration in its comment
+func TypedRootBlock(expr Expr) Block {
+	aBlock := BlockOf(expr, true)
+	attachComment(expr, &aBlock, true)
+	attachState(expr, &aBlock)
+	return aBlock
+}
+
+func attachState(expr Expr, block *Block) {
+	if meta := MetaOf(expr); meta != nil {
+		state := meta.State
+		block.ID, block.Collapsed, block.Disabled, block.Inline = state.ID, state.Collapsed, state.Disabled, state.Inline
+	}
+}
+
+// StateOf reads the state of a block in the workspace, the id is only kept when asked for
+func StateOf(block Block, keepId bool) BlockState {
+	state := BlockState{Collapsed: block.Collapsed, Disabled: block.Disabled, Inline: block.Inline}
+	if keepId {
+		state.ID = block.ID
+	}
+	return state
+}
+
+func attachComment(expr Expr, block *Block, keepTypes bool) {
+	var lines []string
+	if meta := MetaOf(expr); meta != nil {
+		if text := meta.CommentText(); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	if commented, ok := expr.(LineCommented); ok {
+		for _, comment := range commented.LineComments() {
+			lines = append(lines, TrailingCommentPrefix+comment)
+		}
+	}
+	if typed, ok := expr.(Typed); ok && keepTypes {
+		if comment := typed.TypeComment(); comment != "" {
+			lines = append(lines, TypeCommentPrefix+comment)
+		}
+	}
+	if len(lines) > 0 {
+		block.Comment = &Comment{Height: commentHeight, Width: commentWidth, Text: strings.Join(lines, "\n")}
+	}
+}
+
+// RestoreTypes moves the type annotations kept in a block comment back to the declaration,
+// and returns the rest of the comment
+func RestoreTypes(expr Expr, comment string) string {
+	typed, ok := expr.(Typed)
+	if !ok {
+		return comment
+	}
+	var rest []string
+	for _, line := range strings.Split(comment, "\n") {
+		annotation, found := strings.CutPrefix(strings.TrimSpace(line), TypeCommentPrefix)
+		if found && typed.SetTypeComment(annotation) {
+			continue
+		}
+		rest = append(rest, line)
+	}
+	return strings.Join(rest, "\n")
+}
+
+// RestoreTrailing moves the comments kept for the end of the lines in a block comment back to
+// the statement, and returns the rest of the comment
+func RestoreTrailing(expr Expr, comment string) string {
+	meta := MetaOf(expr)
+	if meta == nil {
+		return comment
+	}
+	commented, lineCommented := expr.(LineCommented)
+	var rest []string
+	for _, line := range strings.Split(comment, "\n") {
+		trailing, found := strings.CutPrefix(strings.TrimSpace(line), TrailingCommentPrefix)
+		switch {
+		case !found:
+			rest = append(rest, line)
+		case lineCommented && commented.SetLineComment(trailing):
+		case !lineCommented && meta.Trailing == "":
+			meta.Trailing = trailing
+		default:
+			// the line it ended isn't there anymore
+			rest = append(rest, trailing)
+		}
+	}
+	return strings.Join(rest, "\n")
+}
+
 func ToStatements(namePrefix string, bodies [][]Expr) []Statement {
 	var statements []Statement
 	for i, aBody := range bodies {
@@ -201,7 +387,7 @@ func MakeLocalNames(names ...string) []LocalName {
 func JoinExprs(separator string, expressions []Expr) string {
 	exprStrings := make([]string, len(expressions))
 	for i, expr := range expressions {
-		exprStrings[i] = expr.String()
+		exprStrings[i] = Plugged(expr)
 	}
 	return strings.Join(exprStrings, separator)
 }
@@ -8,6 +8,7 @@ import (
 )
 
 type BinaryExpr struct {
+	ast.Meta
 	Where    *lex.Token
 	Operands []ast.Expr
 	Operator lex.Type
@@ -17,9 +18,9 @@ func (b *BinaryExpr) String() string {
 	myPrecedence := lex.PrecedenceOf(b.Where.Flags[0])
 	stringified := make([]string, len(b.Operands))
 	for i, operand := range b.Operands {
-		operandStr := operand.String()
-		// If operand is a BinaryExpr with lower precedence, wrap it
-		if binExpr, ok := operand.(*BinaryExpr); ok {
+		operandStr := ast.Plugged(operand)
+		// If operand is a BinaryExpr with lower precedence, wrap it, unless its annotations did
+		if binExpr, ok := operand.(*BinaryExpr); ok && binExpr.State.Annotations() == "" {
 			if lex.PrecedenceOf(binExpr.Where.Flags[0]) < myPrecedence {
 				operandStr = "(" + operandStr + ")"
 			}
@@ -157,13 +158,13 @@ func (b *BinaryExpr) boolExpr() ast.Block {
 		fieldOp = "OR"
 	}
 	values := []ast.Value{
-		{Name: "A", Block: b.Operands[0].Blockly(false)},
-		{Name: "B", Block: b.Operands[1].Blockly(false)},
+		{Name: "A", Block: ast.BlockOf(b.Operands[0], false)},
+		{Name: "B", Block: ast.BlockOf(b.Operands[1], false)},
 	}
 	lenOperands := len(b.Operands)
 	if lenOperands > 2 {
 		for i := 2; i < lenOperands; i++ {
-			values = append(values, ast.Value{Name: "BOOL" + strconv.Itoa(i), Block: b.Operands[i].Blockly(false)})
+			values = append(values, ast.Value{Name: "BOOL" + strconv.Itoa(i), Block: ast.BlockOf(b.Operands[i], false)})
 		}
 	}
 	return ast.Block{
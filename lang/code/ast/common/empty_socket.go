@@ -4,7 +4,9 @@ import (
 	"Falcon/code/ast"
 )
 
-type EmptySocket struct{}
+type EmptySocket struct {
+	ast.Meta
+}
 
 func (e *EmptySocket) String() string {
 	return "undefined"
@@ -10,6 +10,7 @@ import (
 )
 
 type FuncCall struct {
+	ast.Meta
 	Where *lex.Token
 	Name  string
 	Args  []ast.Expr
@@ -42,8 +43,8 @@ var signatures = map[string]*FuncCallSignature{
 	"atan":     makeSignature("atan", 1, ast.SignNumb),
 	"degrees":  makeSignature("degrees", 1, ast.SignNumb),
 	"radians":  makeSignature("radians", 1, ast.SignNumb),
-	"decToHex": makeSignature("decToHex", 1, ast.SignNumb),
-	"decToBin": makeSignature("decToBin", 1, ast.SignNumb),
+	"decToHex": makeSignature("decToHex", 1, ast.SignText),
+	"decToBin": makeSignature("decToBin", 1, ast.SignText),
 	"hexToDec": makeSignature("hexToDec", 1, ast.SignNumb),
 	"binToDec": makeSignature("binToDec", 1, ast.SignNumb),
 
@@ -57,12 +58,19 @@ var signatures = map[string]*FuncCallSignature{
 	"min":         makeSignature("min", -1, ast.SignNumb),
 	"max":         makeSignature("max", -1, ast.SignNumb),
 
+	"mod":           makeSignature("mod", 2, ast.SignNumb),
+	"rem":           makeSignature("rem", 2, ast.SignNumb),
+	"quot":          makeSignature("quot", 2, ast.SignNumb),
+	"aTan2":         makeSignature("aTan2", 2, ast.SignNumb),
+	"formatDecimal": makeSignature("formatDecimal", 2, ast.SignNumb),
+
 	"avgOf":     makeSignature("avgOf", 1, ast.SignNumb),
 	"maxOf":     makeSignature("maxOf", 1, ast.SignNumb),
 	"minOf":     makeSignature("minOf", 1, ast.SignNumb),
 	"geoMeanOf": makeSignature("geoMeanOf", 1, ast.SignNumb),
 	"stdDevOf":  makeSignature("stdDevOf", 1, ast.SignNumb),
 	"stdErrOf":  makeSignature("stdErrOf", 1, ast.SignNumb),
+	"modeOf":    makeSignature("modeOf", 1, ast.SignList),
 
 	"println":              makeSignature("println", 1, ast.SignVoid),
 	"openScreen":           makeSignature("openScreen", 1, ast.SignVoid),
@@ -85,12 +93,26 @@ var signatures = map[string]*FuncCallSignature{
 	"call":  makeSignature("call", -1-(3), ast.SignVoid),
 	"vcall": makeSignature("vcall", -1-(3), ast.SignAny),
 	"every": makeSignature("every", 1, ast.SignAny),
+
+	"assertEquals": makeSignature("assertEquals", 2, ast.SignVoid),
+	"assertTrue":   makeSignature("assertTrue", 1, ast.SignVoid),
+	"fire":         makeSignature("fire", -1-(2), ast.SignVoid),
+	"stub":         makeSignature("stub", 3, ast.SignVoid),
 }
 
 func MakeFuncCall(name string, args ...ast.Expr) ast.Expr {
 	return &FuncCall{Where: lex.MakeFakeToken(lex.Func), Name: name, Args: args}
 }
 
+// Builtins returns the signatures of all the in-built functions
+func Builtins() []*FuncCallSignature {
+	builtins := make([]*FuncCallSignature, 0, len(signatures))
+	for _, signature := range signatures {
+		builtins = append(builtins, signature)
+	}
+	return builtins
+}
+
 func TestSignature(funcName string, argsCount int) (string, *FuncCallSignature) {
 	callSignature, ok := signatures[funcName]
 	if !ok {
@@ -117,15 +139,15 @@ func TestSignature(funcName string, argsCount int) (string, *FuncCallSignature)
 
 func (f *FuncCall) String() string {
 	if f.Name == "rem" {
-		return f.Args[0].String() + " % " + f.Args[1].String()
+		return ast.Plugged(f.Args[0]) + " % " + ast.Plugged(f.Args[1])
 	}
 	if f.Name == "neg" {
 		if f.Args[0].Continuous() {
-			return "-" + f.Args[0].String()
+			return "-" + ast.Plugged(f.Args[0])
 		}
-		return "-(" + f.Args[0].String() + ")"
+		return "-(" + ast.Plugged(f.Args[0]) + ")"
 	}
-	return sugar.Format("%(%)", f.Name, ast.JoinExprs(", ", f.Args))
+	return ast.Enclose(f.Name, "(", f.Args, ")")
 }
 
 func (f *FuncCall) Blockly(flags ...bool) ast.Block {
@@ -201,6 +223,9 @@ func (f *FuncCall) Blockly(flags ...bool) ast.Block {
 		return f.genericCall(true)
 	case "every":
 		return f.everyComponent()
+	case "assertEquals", "assertTrue", "fire", "stub":
+		f.Where.Error("%() can only be used in tests", f.Name)
+		panic("never reached")
 	default:
 		f.Where.Error("Cannot find %()", f.Name)
 		panic("never reached")
@@ -216,7 +241,9 @@ func (f *FuncCall) Consumable(flags ...bool) bool {
 		f.Name == "openScreen" || f.Name == "openScreenWithValue" ||
 		f.Name == "closeScreen" || f.Name == "closeScreenWithValue" ||
 		f.Name == "closeApp" || f.Name == "closeScreenWithPlainText" ||
-		f.Name == "set" || f.Name == "call" {
+		f.Name == "set" || f.Name == "call" ||
+		f.Name == "assertEquals" || f.Name == "assertTrue" ||
+		f.Name == "fire" || f.Name == "stub" {
 		return false
 	}
 	return true
@@ -292,7 +319,7 @@ func (f *FuncCall) genericGet() ast.Block {
 			ComponentType: compType.Content,
 		},
 		Fields: []ast.Field{{Name: "PROP", Value: vGet.Content}},
-		Values: []ast.Value{{Name: "COMPONENT", Block: f.Args[1].Blockly(false)}},
+		Values: []ast.Value{{Name: "COMPONENT", Block: ast.BlockOf(f.Args[1], false)}},
 	}
 }
 
@@ -424,7 +451,7 @@ func (f *FuncCall) mathConversions() ast.Block {
 	return ast.Block{
 		Type:   blockType,
 		Fields: []ast.Field{{Name: "OP", Value: fieldOp}},
-		Values: []ast.Value{{Name: "NUM", Block: f.Args[0].Blockly(false)}},
+		Values: []ast.Value{{Name: "NUM", Block: ast.BlockOf(f.Args[0], false)}},
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 )
 
 type Question struct {
+	ast.Meta
 	Where    *lex.Token
 	On       ast.Expr
 	Question string
@@ -18,7 +19,7 @@ func (q *Question) String() string {
 	if !q.On.Continuous() {
 		pFormat = "(%) ? %"
 	}
-	return sugar.Format(pFormat, q.On.String(), q.Question)
+	return sugar.Format(pFormat, ast.Plugged(q.On), q.Question)
 }
 
 func (q *Question) Blockly(flags ...bool) ast.Block {
@@ -78,35 +79,35 @@ func (q *Question) evenOrOdd() ast.Block {
 func (q *Question) listIsEmpty() ast.Block {
 	return ast.Block{
 		Type:   "lists_is_empty",
-		Values: []ast.Value{{Name: "LIST", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "LIST", Block: ast.BlockOf(q.On, false)}},
 	}
 }
 
 func (q *Question) textIsEmpty() ast.Block {
 	return ast.Block{
 		Type:   "text_isEmpty",
-		Values: []ast.Value{{Name: "VALUE", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "VALUE", Block: ast.BlockOf(q.On, false)}},
 	}
 }
 
 func (q *Question) dictQuestion() ast.Block {
 	return ast.Block{
 		Type:   "dictionaries_is_dict",
-		Values: []ast.Value{{Name: "THING", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "THING", Block: ast.BlockOf(q.On, false)}},
 	}
 }
 
 func (q *Question) listQuestion() ast.Block {
 	return ast.Block{
 		Type:   "lists_is_list",
-		Values: []ast.Value{{Name: "ITEM", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "ITEM", Block: ast.BlockOf(q.On, false)}},
 	}
 }
 
 func (q *Question) textQuestion() ast.Block {
 	return ast.Block{
 		Type:   "text_is_string",
-		Values: []ast.Value{{Name: "ITEM", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "ITEM", Block: ast.BlockOf(q.On, false)}},
 	}
 }
 
@@ -125,6 +126,6 @@ func (q *Question) mathQuestion() ast.Block {
 	return ast.Block{
 		Type:   "math_is_a_number",
 		Fields: []ast.Field{{Name: "OP", Value: fieldOp}},
-		Values: []ast.Value{{Name: "NUM", Block: q.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "NUM", Block: ast.BlockOf(q.On, false)}},
 	}
 }
@@ -8,13 +8,14 @@ import (
 )
 
 type Transform struct {
+	ast.Meta
 	Where *lex.Token
 	On    ast.Expr
 	Name  string
 }
 
 func (t *Transform) String() string {
-	return sugar.Format("%::%", t.On.String(), t.Name)
+	return sugar.Format("%::%", ast.Plugged(t.On), t.Name)
 }
 
 func (t *Transform) Blockly(flags ...bool) ast.Block {
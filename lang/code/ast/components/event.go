@@ -7,6 +7,7 @@ import (
 )
 
 type Event struct {
+	ast.Meta
 	ComponentName string
 	ComponentType string
 	Event         string
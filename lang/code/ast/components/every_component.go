@@ -6,6 +6,7 @@ import (
 )
 
 type EveryComponent struct {
+	ast.Meta
 	Type string
 }
 
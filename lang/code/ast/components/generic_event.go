@@ -7,6 +7,7 @@ import (
 )
 
 type GenericEvent struct {
+	ast.Meta
 	ComponentType string
 	Event         string
 	Parameters    []string
@@ -6,6 +6,7 @@ import (
 )
 
 type GenericMethodCall struct {
+	ast.Meta
 	Component     ast.Expr
 	ComponentType string
 	Method        string
@@ -20,7 +21,7 @@ func (g *GenericMethodCall) String() string {
 	} else {
 		callType = "call"
 	}
-	return sugar.Format("%(\"%\", %, \"%\", %)", callType, g.ComponentType, g.Component.String(), g.Method, ast.JoinExprs(", ", g.Args))
+	return sugar.Format("%(\"%\", %, \"%\", %)", callType, g.ComponentType, ast.Plugged(g.Component), g.Method, ast.JoinExprs(", ", g.Args))
 }
 
 func (g *GenericMethodCall) Blockly(flags ...bool) ast.Block {
@@ -6,13 +6,14 @@ import (
 )
 
 type GenericPropertyGet struct {
+	ast.Meta
 	Component     ast.Expr
 	ComponentType string
 	Property      string
 }
 
 func (g *GenericPropertyGet) String() string {
-	return sugar.Format("get(\"%\", %, \"%\")", g.ComponentType, g.Component.String(), g.Property)
+	return sugar.Format("get(\"%\", %, \"%\")", g.ComponentType, ast.Plugged(g.Component), g.Property)
 }
 
 func (g *GenericPropertyGet) Blockly(flags ...bool) ast.Block {
@@ -25,7 +26,7 @@ func (g *GenericPropertyGet) Blockly(flags ...bool) ast.Block {
 			ComponentType: g.ComponentType,
 		},
 		Fields: []ast.Field{{Name: "PROP", Value: g.Property}},
-		Values: []ast.Value{{Name: "COMPONENT", Block: g.Component.Blockly(false)}},
+		Values: []ast.Value{{Name: "COMPONENT", Block: ast.BlockOf(g.Component, false)}},
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 )
 
 type GenericPropertySet struct {
+	ast.Meta
 	Component     ast.Expr
 	ComponentType string
 	Property      string
@@ -13,7 +14,7 @@ type GenericPropertySet struct {
 }
 
 func (g *GenericPropertySet) String() string {
-	return sugar.Format("set(\"%\", %, \"%\", %)", g.ComponentType, g.Component.String(), g.Property, g.Value.String())
+	return sugar.Format("set(\"%\", %, \"%\", %)", g.ComponentType, ast.Plugged(g.Component), g.Property, ast.Plugged(g.Value))
 }
 
 func (g *GenericPropertySet) Blockly(flags ...bool) ast.Block {
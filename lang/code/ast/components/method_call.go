@@ -2,18 +2,21 @@ package components
 
 import (
 	"Falcon/code/ast"
-	"Falcon/code/sugar"
 )
 
 type MethodCall struct {
+	ast.Meta
 	ComponentName string
 	ComponentType string
 	Method        string
 	Args          []ast.Expr
+
+	// Signatures is the result of the method known from the component registry, nil when unknown
+	Signatures []ast.Signature
 }
 
 func (m *MethodCall) String() string {
-	return sugar.Format("%.%(%)", m.ComponentName, m.Method, ast.JoinExprs(", ", m.Args))
+	return ast.Enclose(m.ComponentName+"."+m.Method, "(", m.Args, ")")
 }
 
 func (m *MethodCall) Blockly(flags ...bool) ast.Block {
@@ -35,9 +38,13 @@ func (m *MethodCall) Continuous() bool {
 }
 
 func (m *MethodCall) Consumable(flags ...bool) bool {
-	return false // may be consumable too
+	// a method that gives back a value is consumable
+	return len(m.Signatures) > 0 && m.Signatures[0] != ast.SignVoid
 }
 
 func (m *MethodCall) Signature() []ast.Signature {
+	if m.Signatures != nil {
+		return m.Signatures
+	}
 	return []ast.Signature{ast.SignAny}
 }
@@ -6,9 +6,13 @@ import (
 )
 
 type PropertyGet struct {
+	ast.Meta
 	ComponentName string
 	ComponentType string
 	Property      string
+
+	// Signatures is the type of the property known from the component registry, nil when unknown
+	Signatures []ast.Signature
 }
 
 func (p *PropertyGet) String() string {
@@ -41,5 +45,8 @@ func (p *PropertyGet) Consumable(flags ...bool) bool {
 }
 
 func (p *PropertyGet) Signature() []ast.Signature {
+	if p.Signatures != nil {
+		return p.Signatures
+	}
 	return []ast.Signature{ast.SignAny}
 }
@@ -6,6 +6,7 @@ import (
 )
 
 type PropertySet struct {
+	ast.Meta
 	ComponentName string
 	ComponentType string
 	Property      string
@@ -13,11 +14,11 @@ type PropertySet struct {
 }
 
 func (p *PropertySet) String() string {
-	return sugar.Format("%.% = %", p.ComponentName, p.Property, p.Value.String())
+	return sugar.Format("%.% = %", p.ComponentName, p.Property, ast.Plugged(p.Value))
 }
 
 func (p *PropertySet) Blockly(flags ...bool) ast.Block {
-	newValue := p.Value.Blockly(false)
+	newValue := ast.BlockOf(p.Value, false)
 	// explicitly mark as value for consumption
 	if newValue.Mutation != nil {
 		newValue.Mutation.Shape = "value"
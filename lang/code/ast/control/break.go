@@ -5,6 +5,7 @@ import (
 )
 
 type Break struct {
+	ast.Meta
 	// Hola Amigo!
 }
 
@@ -5,19 +5,20 @@ import (
 )
 
 type Do struct {
+	ast.Meta
 	Body   []ast.Expr
 	Result ast.Expr
 }
 
 func (d *Do) String() string {
-	return ast.JoinExprs("\n", d.Body) + "\n" + d.Result.String()
+	return "{\n" + ast.Pad(ast.JoinStatements(d.Body)+"\n"+ast.Plugged(d.Result)) + "}"
 }
 
 func (d *Do) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:       "controls_do_then_return",
 		Statements: ast.OptionalStatement("STM", d.Body),
-		Values:     []ast.Value{{Name: "VALUE", Block: d.Result.Blockly(false)}},
+		Values:     []ast.Value{{Name: "VALUE", Block: ast.BlockOf(d.Result, false)}},
 	}
 }
 
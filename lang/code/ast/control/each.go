@@ -6,20 +6,21 @@ import (
 )
 
 type Each struct {
+	ast.Meta
 	IName    string
 	Iterable ast.Expr
 	Body     []ast.Expr
 }
 
 func (e *Each) String() string {
-	return sugar.Format("for (% in %) {\n%}", e.IName, e.Iterable.String(), ast.PadBody(e.Body))
+	return sugar.Format("for (% in %) {\n%}", e.IName, ast.Plugged(e.Iterable), ast.PadBody(e.Body))
 }
 
 func (e *Each) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:       "controls_forEach",
 		Fields:     []ast.Field{{Name: "VAR", Value: e.IName}},
-		Values:     []ast.Value{{Name: "LIST", Block: e.Iterable.Blockly(false)}},
+		Values:     []ast.Value{{Name: "LIST", Block: ast.BlockOf(e.Iterable, false)}},
 		Statements: ast.OptionalStatement("DO", e.Body),
 	}
 }
@@ -6,6 +6,7 @@ import (
 )
 
 type EachPair struct {
+	ast.Meta
 	KeyName   string
 	ValueName string
 	Iterable  ast.Expr
@@ -13,7 +14,7 @@ type EachPair struct {
 }
 
 func (e *EachPair) String() string {
-	return sugar.Format("for (%, % in %) {\n%}", e.KeyName, e.ValueName, e.Iterable.String(), ast.PadBody(e.Body))
+	return sugar.Format("for (%, % in %) {\n%}", e.KeyName, e.ValueName, ast.Plugged(e.Iterable), ast.PadBody(e.Body))
 }
 
 func (e *EachPair) Blockly(flags ...bool) ast.Block {
@@ -23,7 +24,7 @@ func (e *EachPair) Blockly(flags ...bool) ast.Block {
 			{Name: "KEY", Value: e.KeyName},
 			{Name: "VALUE", Value: e.ValueName},
 		},
-		Values:     []ast.Value{{Name: "DICT", Block: e.Iterable.Blockly(false)}},
+		Values:     []ast.Value{{Name: "DICT", Block: ast.BlockOf(e.Iterable, false)}},
 		Statements: ast.OptionalStatement("DO", e.Body),
 	}
 }
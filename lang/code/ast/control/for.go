@@ -6,6 +6,7 @@ import (
 )
 
 type For struct {
+	ast.Meta
 	IName string
 	From  ast.Expr
 	To    ast.Expr
@@ -15,7 +16,7 @@ type For struct {
 
 func (f *For) String() string {
 	return sugar.Format("for (%: % .. % step %) {\n%}",
-		f.IName, f.From.String(), f.To.String(), f.By.String(), ast.PadBody(f.Body))
+		f.IName, ast.Plugged(f.From), ast.Plugged(f.To), ast.Plugged(f.By), ast.PadBody(f.Body))
 }
 
 func (f *For) Blockly(flags ...bool) ast.Block {
@@ -6,6 +6,7 @@ import (
 )
 
 type If struct {
+	ast.Meta
 	Conditions []ast.Expr
 	Bodies     [][]ast.Expr
 	ElseBody   []ast.Expr
@@ -21,7 +22,7 @@ func (i *If) String() string {
 	builder.WriteString("if ")
 	for {
 		builder.WriteString("(")
-		builder.WriteString(i.Conditions[currI].String())
+		builder.WriteString(ast.Plugged(i.Conditions[currI]))
 		builder.WriteString(") {\n")
 		builder.WriteString(ast.PadBody(i.Bodies[currI]))
 		builder.WriteString("}")
@@ -8,6 +8,7 @@ import (
 )
 
 type SimpleIf struct {
+	ast.Meta
 	condition ast.Expr
 
 	smartThen ast.Expr
@@ -27,6 +28,11 @@ func MakeSimpleIf(condition ast.Expr, then []ast.Expr, elze []ast.Expr) *SimpleI
 	}
 }
 
+// Branches returns the condition along with the then and the else bodies
+func (s *SimpleIf) Branches() (ast.Expr, []ast.Expr, []ast.Expr) {
+	return s.condition, s.normalThen, s.normalElse
+}
+
 func (s *SimpleIf) String() string {
 	var branches []string
 	currIf := s
@@ -45,12 +51,12 @@ func (s *SimpleIf) String() string {
 		var thenString string
 		if len(currIf.normalThen) == 1 {
 			ifFormat += "if (%) % "
-			thenString = currIf.normalThen[0].String()
+			thenString = ast.Plugged(currIf.normalThen[0])
 		} else {
 			ifFormat += "if (%) {\n%} "
 			thenString = ast.PadBody(currIf.normalThen)
 		}
-		branches = append(branches, sugar.Format(ifFormat, currIf.condition.String(), thenString))
+		branches = append(branches, sugar.Format(ifFormat, ast.Plugged(currIf.condition), thenString))
 		// check for nested If branch
 		nextIf, hasNextIf := currIf.normalElse[0].(*SimpleIf)
 		if len(currIf.normalElse) == 1 && hasNextIf {
@@ -63,7 +69,7 @@ func (s *SimpleIf) String() string {
 		var elseString string
 		if len(currIf.normalElse) == 1 {
 			elseFormat = "else %"
-			elseString = currIf.normalElse[0].String()
+			elseString = ast.Plugged(currIf.normalElse[0])
 		} else {
 			elseFormat = "else {\n%}"
 			elseString = ast.PadBody(currIf.normalElse)
@@ -6,18 +6,19 @@ import (
 )
 
 type While struct {
+	ast.Meta
 	Condition ast.Expr
 	Body      []ast.Expr
 }
 
 func (w *While) String() string {
-	return sugar.Format("while (%) {\n%}", w.Condition.String(), ast.PadBody(w.Body))
+	return sugar.Format("while (%) {\n%}", ast.Plugged(w.Condition), ast.PadBody(w.Body))
 }
 
 func (w *While) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:       "controls_while",
-		Values:     []ast.Value{{Name: "TEST", Block: w.Condition.Blockly(false)}},
+		Values:     []ast.Value{{Name: "TEST", Block: ast.BlockOf(w.Condition, false)}},
 		Statements: ast.OptionalStatement("DO", w.Body),
 	}
 }
@@ -4,18 +4,86 @@ import (
 	"strings"
 )
 
+// LineWidth is the width argument lists and transformer chains are kept within
+const LineWidth = 80
+
 func Pad(code string) string {
-	return "  " + strings.Replace(code, "\n", "\n  ", -1) + "\n"
+	return PadDirect(code) + "\n"
 }
 
 func PadDirect(code string) string {
-	return "  " + strings.Replace(code, "\n", "\n  ", -1)
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		// empty lines are not indented, they'd carry trailing spaces
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
 func PadBody(blocks []Expr) string {
 	var builder strings.Builder
-	for _, block := range blocks {
-		builder.WriteString(Pad(block.String()))
+	for i, block := range blocks {
+		if i > 0 && IsSpaced(block) {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(Pad(FormatStatement(block)))
+	}
+	return builder.String()
+}
+
+// JoinStatements puts the statements one per line, along with their comments
+func JoinStatements(blocks []Expr) string {
+	return joinStatements(blocks, false)
+}
+
+// FollowStatements is JoinStatements for statements that follow a line, such as the
+// ones after local declarations
+func FollowStatements(blocks []Expr) string {
+	return joinStatements(blocks, true)
+}
+
+func joinStatements(blocks []Expr, following bool) string {
+	var builder strings.Builder
+	for i, block := range blocks {
+		if i > 0 || following {
+			builder.WriteString("\n")
+			if IsSpaced(block) {
+				builder.WriteString("\n")
+			}
+		}
+		builder.WriteString(FormatStatement(block))
 	}
 	return builder.String()
 }
+
+// Plugged writes a value plugged into an input of another block. The annotations of the state of
+// its block come first and the value goes between parentheses, e.g. @collapsed ([1, 2, 3]).
+func Plugged(expr Expr) string {
+	meta := MetaOf(expr)
+	if meta == nil {
+		return expr.String()
+	}
+	annotations := meta.State.Annotations()
+	if annotations == "" {
+		return expr.String()
+	}
+	return annotations + " (" + expr.String() + ")"
+}
+
+// IsSpaced tells if the statement was preceded by an empty line in the source
+func IsSpaced(expr Expr) bool {
+	meta := MetaOf(expr)
+	return meta != nil && meta.Spaced
+}
+
+// Enclose joins the expressions between the open and the close symbols following the prefix.
+// When the result does not fit in a line, every expression is put on a line of its own.
+func Enclose(prefix string, open string, exprs []Expr, close string) string {
+	inline := prefix + open + JoinExprs(", ", exprs) + close
+	if len(exprs) == 0 || len(inline) <= LineWidth || strings.Contains(inline, "\n") {
+		return inline
+	}
+	return prefix + open + "\n" + Pad(JoinExprs(",\n", exprs)) + close
+}
@@ -6,6 +6,7 @@ import (
 )
 
 type Boolean struct {
+	ast.Meta
 	Value bool
 }
 
@@ -42,17 +43,18 @@ func (b *Boolean) Signature() []ast.Signature {
 }
 
 type Not struct {
+	ast.Meta
 	Expr ast.Expr
 }
 
 func (n *Not) String() string {
-	return sugar.Format("!%", n.Expr.String())
+	return sugar.Format("!%", ast.Plugged(n.Expr))
 }
 
 func (n *Not) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:   "logic_negate",
-		Values: []ast.Value{{Name: "BOOL", Block: n.Expr.Blockly(false)}},
+		Values: []ast.Value{{Name: "BOOL", Block: ast.BlockOf(n.Expr, false)}},
 	}
 }
 
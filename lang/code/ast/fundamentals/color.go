@@ -6,6 +6,7 @@ import (
 )
 
 type Color struct {
+	ast.Meta
 	Where *lex.Token
 	Hex   string
 }
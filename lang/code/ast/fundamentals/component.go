@@ -5,6 +5,7 @@ import (
 )
 
 type Component struct {
+	ast.Meta
 	Name string
 	Type string
 }
@@ -6,6 +6,7 @@ import (
 )
 
 type Dictionary struct {
+	ast.Meta
 	Elements []ast.Expr
 }
 
@@ -34,12 +35,13 @@ func (d *Dictionary) Signature() []ast.Signature {
 }
 
 type Pair struct {
+	ast.Meta
 	Key   ast.Expr
 	Value ast.Expr
 }
 
 func (p *Pair) String() string {
-	return sugar.Format("% : %", p.Key.String(), p.Value.String())
+	return sugar.Format("% : %", ast.Plugged(p.Key), ast.Plugged(p.Value))
 }
 
 func (p *Pair) Blockly(flags ...bool) ast.Block {
@@ -62,6 +64,7 @@ func (p *Pair) Signature() []ast.Signature {
 }
 
 type WalkAll struct {
+	ast.Meta
 }
 
 func (w *WalkAll) String() string {
@@ -5,6 +5,7 @@ import (
 )
 
 type HelperDropdown struct {
+	ast.Meta
 	Key    string
 	Option string
 }
@@ -2,15 +2,15 @@ package fundamentals
 
 import (
 	"Falcon/code/ast"
-	"Falcon/code/sugar"
 )
 
 type List struct {
+	ast.Meta
 	Elements []ast.Expr
 }
 
 func (l *List) String() string {
-	return sugar.Format("[%]", ast.JoinExprs(", ", l.Elements))
+	return ast.Enclose("", "[", l.Elements, "]")
 }
 
 func (l *List) Blockly(flags ...bool) ast.Block {
@@ -5,6 +5,7 @@ import (
 )
 
 type Number struct {
+	ast.Meta
 	Content string
 }
 
@@ -7,6 +7,7 @@ import (
 )
 
 type SmartBody struct {
+	ast.Meta
 	Body []ast.Expr
 }
 
@@ -67,7 +68,7 @@ func (s *SmartBody) createDoSmt(doResult ast.Expr, doBody []ast.Expr) ast.Block
 			}
 			return s.createLocalResult(v.Names, v.Values, doExpr)
 		}
-		doExpr = doResult.Blockly(false)
+		doExpr = ast.BlockOf(doResult, false)
 	} else {
 		if !doResult.Consumable() {
 			panic("Cannot include a statement for the required variable result")
@@ -76,7 +77,7 @@ func (s *SmartBody) createDoSmt(doResult ast.Expr, doBody []ast.Expr) ast.Block
 			Type:       "controls_do_then_return",
 			Statements: ast.OptionalStatement("STM", doBody),
 			// TODO: we have set the flag to false, previously was true, verify effects
-			Values: []ast.Value{{Name: "VALUE", Block: doResult.Blockly(false)}},
+			Values: []ast.Value{{Name: "VALUE", Block: ast.BlockOf(doResult, false)}},
 		}
 	}
 	return doExpr
@@ -6,6 +6,7 @@ import (
 )
 
 type Text struct {
+	ast.Meta
 	Content string
 }
 
@@ -6,6 +6,7 @@ import (
 )
 
 type Get struct {
+	ast.Meta
 	List  ast.Expr
 	Index ast.Expr
 }
@@ -15,7 +16,7 @@ func (g *Get) String() string {
 	if !g.List.Continuous() {
 		pFormat = "(%)[%]"
 	}
-	return sugar.Format(pFormat, g.List.String(), g.Index.String())
+	return sugar.Format(pFormat, ast.Plugged(g.List), ast.Plugged(g.Index))
 }
 
 func (g *Get) Blockly(flags ...bool) ast.Block {
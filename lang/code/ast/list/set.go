@@ -6,6 +6,7 @@ import (
 )
 
 type Set struct {
+	ast.Meta
 	List  ast.Expr
 	Index ast.Expr
 	Value ast.Expr
@@ -16,7 +17,7 @@ func (s *Set) String() string {
 	if !s.List.Continuous() {
 		pFormat = "(%)[%] = %"
 	}
-	return sugar.Format(pFormat, s.List.String(), s.Index.String(), s.Value.String())
+	return sugar.Format(pFormat, ast.Plugged(s.List), ast.Plugged(s.Index), ast.Plugged(s.Value))
 }
 
 func (s *Set) Blockly(flags ...bool) ast.Block {
@@ -9,6 +9,7 @@ import (
 )
 
 type Transformer struct {
+	ast.Meta
 	Where       *lex.Token
 	List        ast.Expr
 	Name        string
@@ -53,28 +54,24 @@ func TestSignature(transformerName string, argsCount int, namesCount int) (strin
 }
 
 func (t *Transformer) String() string {
-	if len(t.Args) == 0 {
-		pFormat := "%\n  .% { % -> % }"
-		if !t.List.Continuous() {
-			pFormat = "(%)\n  .% { % -> %} "
-		}
-		return sugar.Format(pFormat,
-			t.List.String(),
-			t.Name,
-			strings.Join(t.Names, ", "),
-			t.Transformer.String())
+	list := ast.Plugged(t.List)
+	if !t.List.Continuous() {
+		list = "(" + list + ")"
+	}
+	call := "." + t.Name
+	if len(t.Args) > 0 {
+		call = ast.Enclose(call, "(", t.Args, ")")
+	}
+	if len(t.Names) > 0 {
+		call += sugar.Format(" { % -> % }", strings.Join(t.Names, ", "), ast.Plugged(t.Transformer))
 	} else {
-		pFormat := "%\n  .%(%) { % -> % }"
-		if !t.List.Continuous() {
-			pFormat = "(%)\n  .%(%) { % -> % }"
-		}
-		return sugar.Format(pFormat,
-			t.List.String(),
-			t.Name,
-			ast.JoinExprs(", ", t.Args),
-			strings.Join(t.Names, ", "),
-			t.Transformer.String())
+		call += sugar.Format(" { -> % }", ast.Plugged(t.Transformer))
+	}
+	// a long chain is broken with every transformer on its own line
+	if len(list)+len(call) <= ast.LineWidth && !strings.Contains(list+call, "\n") {
+		return list + call
 	}
+	return list + "\n" + ast.PadDirect(call)
 }
 
 func (t *Transformer) Blockly(flags ...bool) ast.Block {
@@ -131,8 +128,8 @@ func (t *Transformer) max() ast.Block {
 			{Name: "VAR2", Value: t.Names[1]},
 		},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "COMPARE", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "COMPARE", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -145,8 +142,8 @@ func (t *Transformer) min() ast.Block {
 			{Name: "VAR2", Value: t.Names[1]},
 		},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "COMPARE", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "COMPARE", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -156,8 +153,8 @@ func (t *Transformer) listSortByKey() ast.Block {
 		Type:   "lists_sort_key",
 		Fields: []ast.Field{{Name: "VAR", Value: t.Names[0]}},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "KEY", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "KEY", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -170,8 +167,8 @@ func (t *Transformer) listSort() ast.Block {
 			{Name: "VAR2", Value: t.Names[1]},
 		},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "COMPARE", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "COMPARE", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -184,9 +181,9 @@ func (t *Transformer) listReduce() ast.Block {
 			{Name: "VAR2", Value: t.Names[1]},
 		},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "INITANSWER", Block: t.Args[0].Blockly(false)},
-			{Name: "COMBINE", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "INITANSWER", Block: ast.BlockOf(t.Args[0], false)},
+			{Name: "COMBINE", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -196,8 +193,8 @@ func (t *Transformer) listFilter() ast.Block {
 		Type:   "lists_filter",
 		Fields: []ast.Field{{Name: "VAR", Value: t.Names[0]}},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "TEST", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "TEST", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
@@ -207,8 +204,8 @@ func (t *Transformer) listMap() ast.Block {
 		Type:   "lists_map",
 		Fields: []ast.Field{{Name: "VAR", Value: t.Names[0]}},
 		Values: []ast.Value{
-			{Name: "LIST", Block: t.List.Blockly(false)},
-			{Name: "TO", Block: t.Transformer.Blockly(false)},
+			{Name: "LIST", Block: ast.BlockOf(t.List, false)},
+			{Name: "TO", Block: ast.BlockOf(t.Transformer, false)},
 		},
 	}
 }
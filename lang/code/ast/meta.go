@@ -0,0 +1,139 @@
+package ast
+
+import "strings"
+
+// Meta holds the source trivia attached to a statement, so that it survives reformatting
+type Meta struct {
+	Comments []string // comment lines above the statement
+	Trailing string   // comment at the end of the statement's last line
+	After    []string // comment lines after the statement, at the end of a body or the file
+	Spaced   bool     // the statement is preceded by an empty line
+	Detached bool     // an empty line separates the comments above from the statement, e.g. a file header
+	State    BlockState
+}
+
+// BlockState is the state of a block in the workspace, written as annotations above a statement,
+// e.g. @collapsed, or in front of a value plugged into an input
+type BlockState struct {
+	ID        string
+	Collapsed bool
+	Disabled  bool
+	Inline    *bool // the inputs are inline or external, Blockly decides when nil
+}
+
+// BlockAnnotations are the names of the annotations of a block state, @id takes the id as a text
+var BlockAnnotations = []string{"collapsed", "disabled", "inline", "external", "id"}
+
+// Annotations writes the state as annotations, empty for a block in its default state
+func (s *BlockState) Annotations() string {
+	var annotations []string
+	if s.Collapsed {
+		annotations = append(annotations, "@collapsed")
+	}
+	if s.Disabled {
+		annotations = append(annotations, "@disabled")
+	}
+	if s.Inline != nil {
+		if *s.Inline {
+			annotations = append(annotations, "@inline")
+		} else {
+			annotations = append(annotations, "@external")
+		}
+	}
+	if s.ID != "" {
+		escaped := strings.ReplaceAll(s.ID, `\`, `\\`)
+		escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+		annotations = append(annotations, `@id("`+escaped+`")`)
+	}
+	return strings.Join(annotations, " ")
+}
+
+// LineCommented is a statement of several lines that keeps the comment at the end of each,
+// such as the declarations of locals
+type LineCommented interface {
+	// LineComments returns the comments of the lines, each following what names its line
+	LineComments() []string
+	// SetLineComment puts a comment back at the end of its line and tells if the line was found
+	SetLineComment(comment string) bool
+}
+
+// TrailingCommentPrefix starts the block comment line that keeps the comment at the end of the
+// statement's line, so that it goes back there rather than above the statement
+const TrailingCommentPrefix = "@end "
+
+func (m *Meta) GetMeta() *Meta {
+	return m
+}
+
+type Annotated interface {
+	GetMeta() *Meta
+}
+
+// MetaOf returns the trivia of the expression, or nil if it does not carry any
+func MetaOf(expr Expr) *Meta {
+	if annotated, ok := expr.(Annotated); ok {
+		return annotated.GetMeta()
+	}
+	return nil
+}
+
+// FormatStatement formats the expression as a statement, along with its comments
+func FormatStatement(expr Expr) string {
+	code := expr.String()
+	meta := MetaOf(expr)
+	if meta == nil {
+		return code
+	}
+	var builder strings.Builder
+	for _, comment := range meta.Comments {
+		builder.WriteString(CommentLine(comment))
+		builder.WriteString("\n")
+	}
+	if meta.Detached && len(meta.Comments) > 0 {
+		builder.WriteString("\n")
+	}
+	if annotations := meta.State.Annotations(); annotations != "" {
+		builder.WriteString(annotations)
+		builder.WriteString("\n")
+	}
+	builder.WriteString(code)
+	if meta.Trailing != "" {
+		builder.WriteString(" ")
+		builder.WriteString(CommentLine(meta.Trailing))
+	}
+	for _, comment := range meta.After {
+		builder.WriteString("\n")
+		builder.WriteString(CommentLine(comment))
+	}
+	return builder.String()
+}
+
+// CommentText joins all the comments of the statement into the text of a block comment
+func (m *Meta) CommentText() string {
+	var lines []string
+	lines = append(lines, m.Comments...)
+	if m.Trailing != "" {
+		lines = append(lines, TrailingCommentPrefix+m.Trailing)
+	}
+	lines = append(lines, m.After...)
+	return strings.Join(lines, "\n")
+}
+
+// SetCommentText sets the text of a block comment as the comment lines above the statement
+func (m *Meta) SetCommentText(text string) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		m.Comments = append(m.Comments, strings.TrimSpace(line))
+	}
+}
+
+// CommentLine writes the text of a comment as a // comment
+func CommentLine(comment string) string {
+	if comment == "" {
+		return "//"
+	}
+	return "// " + comment
+}
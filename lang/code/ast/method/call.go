@@ -8,6 +8,7 @@ import (
 )
 
 type Call struct {
+	ast.Meta
 	Where *lex.Token
 	On    ast.Expr
 	Name  string
@@ -114,11 +115,11 @@ func TestSignature(methodName string, argsCount int) (string, *CallSignature) {
 }
 
 func (c *Call) String() string {
-	pFormat := "%.%(%)"
+	on := ast.Plugged(c.On)
 	if !c.On.Continuous() {
-		pFormat = "(%).%(%)"
+		on = "(" + on + ")"
 	}
-	return sugar.Format(pFormat, c.On.String(), c.Name, ast.JoinExprs(", ", c.Args))
+	return ast.Enclose(on+"."+c.Name, "(", c.Args, ")")
 }
 
 func (c *Call) Blockly(flags ...bool) ast.Block {
@@ -159,5 +160,5 @@ func (c *Call) Signature() []ast.Signature {
 }
 
 func (c *Call) simpleOperand(blockType string, valueName string) ast.Block {
-	return ast.Block{Type: blockType, Values: []ast.Value{{Name: valueName, Block: c.On.Blockly(false)}}}
+	return ast.Block{Type: blockType, Values: []ast.Value{{Name: valueName, Block: ast.BlockOf(c.On, false)}}}
 }
@@ -47,7 +47,7 @@ func (c *Call) textChangeCase() ast.Block {
 	return ast.Block{
 		Type:   "text_changeCase",
 		Fields: []ast.Field{{Name: "OP", Value: fieldOp}},
-		Values: []ast.Value{{Name: "TEXT", Block: c.On.Blockly(false)}},
+		Values: []ast.Value{{Name: "TEXT", Block: ast.BlockOf(c.On, false)}},
 	}
 }
 
@@ -62,8 +62,8 @@ func (c *Call) textReplaceFrom() ast.Block {
 		Type:   "text_replace_mappings",
 		Fields: []ast.Field{{Name: "OP", Value: fieldOp}},
 		Values: []ast.Value{
-			{Name: "MAPPINGS", Block: c.Args[0].Blockly(false)},
-			{Name: "TEXT", Block: c.On.Blockly(false)},
+			{Name: "MAPPINGS", Block: ast.BlockOf(c.Args[0], false)},
+			{Name: "TEXT", Block: ast.BlockOf(c.On, false)},
 		},
 	}
 }
@@ -72,9 +72,9 @@ func (c *Call) textReplace() ast.Block {
 	return ast.Block{
 		Type: "text_replace_all",
 		Values: []ast.Value{
-			{Name: "TEXT", Block: c.On.Blockly()},
-			{Name: "SEGMENT", Block: c.Args[0].Blockly()},
-			{Name: "REPLACEMENT", Block: c.Args[1].Blockly()},
+			{Name: "TEXT", Block: ast.BlockOf(c.On)},
+			{Name: "SEGMENT", Block: ast.BlockOf(c.Args[0])},
+			{Name: "REPLACEMENT", Block: ast.BlockOf(c.Args[1])},
 		},
 	}
 }
@@ -83,9 +83,9 @@ func (c *Call) textSegment() ast.Block {
 	return ast.Block{
 		Type: "text_segment",
 		Values: []ast.Value{
-			{Name: "TEXT", Block: c.On.Blockly()},
-			{Name: "START", Block: c.Args[0].Blockly()},
-			{Name: "LENGTH", Block: c.Args[1].Blockly()},
+			{Name: "TEXT", Block: ast.BlockOf(c.On)},
+			{Name: "START", Block: ast.BlockOf(c.Args[0])},
+			{Name: "LENGTH", Block: ast.BlockOf(c.Args[1])},
 		},
 	}
 }
@@ -107,8 +107,8 @@ func (c *Call) textSplit() ast.Block {
 		Mutation: &ast.Mutation{Mode: fieldOp},
 		Fields:   []ast.Field{{Name: "OP", Value: fieldOp}},
 		Values: []ast.Value{
-			{Name: "TEXT", Block: c.On.Blockly()},
-			{Name: "AT", Block: c.Args[0].Blockly()},
+			{Name: "TEXT", Block: ast.BlockOf(c.On)},
+			{Name: "AT", Block: ast.BlockOf(c.Args[0])},
 		},
 	}
 }
@@ -128,8 +128,8 @@ func (c *Call) textContains() ast.Block {
 		Mutation: &ast.Mutation{Mode: fieldOp},
 		Fields:   []ast.Field{{Name: "OP", Value: fieldOp}},
 		Values: []ast.Value{
-			{Name: "TEXT", Block: c.On.Blockly()},
-			{Name: "PIECE", Block: c.Args[0].Blockly()},
+			{Name: "TEXT", Block: ast.BlockOf(c.On)},
+			{Name: "PIECE", Block: ast.BlockOf(c.Args[0])},
 		},
 	}
 }
@@ -138,8 +138,8 @@ func (c *Call) textStartsAt() ast.Block {
 	return ast.Block{
 		Type: "text_starts_at",
 		Values: []ast.Value{
-			{Name: "TEXT", Block: c.On.Blockly()},
-			{Name: "PIECE", Block: c.Args[0].Blockly()},
+			{Name: "TEXT", Block: ast.BlockOf(c.On)},
+			{Name: "PIECE", Block: ast.BlockOf(c.Args[0])},
 		},
 	}
 }
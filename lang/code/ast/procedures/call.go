@@ -2,10 +2,10 @@ package procedures
 
 import (
 	"Falcon/code/ast"
-	"Falcon/code/sugar"
 )
 
 type Call struct {
+	ast.Meta
 	Name       string
 	Parameters []string
 	Arguments  []ast.Expr
@@ -13,7 +13,7 @@ type Call struct {
 }
 
 func (v *Call) String() string {
-	return sugar.Format("%(%)", v.Name, ast.JoinExprs(", ", v.Arguments))
+	return ast.Enclose(v.Name, "(", v.Arguments, ")")
 }
 
 func (v *Call) Blockly(flags ...bool) ast.Block {
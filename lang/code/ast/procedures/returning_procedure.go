@@ -2,25 +2,49 @@ package procedures
 
 import (
 	"Falcon/code/ast"
-	"Falcon/code/ast/control"
 	"Falcon/code/sugar"
 	"strings"
 )
 
 type RetProcedure struct {
+	ast.Meta
 	Name       string
 	Parameters []string
 	Result     ast.Expr
+
+	// optional type annotations, an empty type is not annotated
+	ParameterTypes []string
+	ReturnType     string
 }
 
 func (v *RetProcedure) String() string {
-	var resultString string
-	if _, ok := v.Result.(*control.Do); !ok {
-		resultString = ast.Pad(v.Result.String())
+	resultString := ast.Plugged(v.Result)
+	if strings.Contains(resultString, "\n") && !strings.HasPrefix(resultString, "{") {
+		resultString = "\n" + ast.PadDirect(resultString)
 	} else {
-		resultString = ast.Pad("{\n" + ast.Pad(v.Result.String()) + "}")
+		resultString = " " + resultString
+	}
+	return sugar.Format("func %(%)% =%", v.Name, ast.JoinParameters(v.Parameters, v.ParameterTypes),
+		returnAnnotation(v.ReturnType), resultString)
+}
+
+func returnAnnotation(returnType string) string {
+	if returnType == "" {
+		return ""
+	}
+	return ": " + returnType
+}
+
+func (v *RetProcedure) TypeComment() string {
+	return typeComment(v.Parameters, v.ParameterTypes, v.ReturnType)
+}
+
+func (v *RetProcedure) SetTypeComment(comment string) bool {
+	types, returnType, ok := parseTypeComment(comment, v.Parameters)
+	if ok {
+		v.ParameterTypes, v.ReturnType = types, returnType
 	}
-	return sugar.Format("func %(%) =\n%", v.Name, strings.Join(v.Parameters, ", "), resultString)
+	return ok
 }
 
 func (v *RetProcedure) Blockly(flags ...bool) ast.Block {
@@ -28,7 +52,7 @@ func (v *RetProcedure) Blockly(flags ...bool) ast.Block {
 		Type:     "procedures_defreturn",
 		Mutation: &ast.Mutation{Args: ast.ToArgs(v.Parameters)},
 		Fields:   append(ast.ToFields("VAR", v.Parameters), ast.Field{Name: "NAME", Value: v.Name}),
-		Values:   []ast.Value{{Name: "RETURN", Block: v.Result.Blockly(false)}},
+		Values:   []ast.Value{{Name: "RETURN", Block: ast.BlockOf(v.Result, false)}},
 	}
 }
 
@@ -0,0 +1,38 @@
+package procedures
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/lex"
+	"Falcon/code/sugar"
+)
+
+// Test is a named block of statements run by `falcon test`, it has no blocks counterpart
+type Test struct {
+	ast.Meta
+	Where *lex.Token
+	Name  string
+	Body  []ast.Expr
+}
+
+func (t *Test) String() string {
+	name := &fundamentals.Text{Content: t.Name}
+	return sugar.Format("test % {\n%}", name.String(), ast.PadBody(t.Body))
+}
+
+func (t *Test) Blockly(flags ...bool) ast.Block {
+	t.Where.Error("Tests cannot be compiled to blocks, run them with falcon test")
+	return ast.Block{}
+}
+
+func (t *Test) Continuous() bool {
+	return false
+}
+
+func (t *Test) Consumable(flags ...bool) bool {
+	return false
+}
+
+func (t *Test) Signature() []ast.Signature {
+	return []ast.Signature{ast.SignVoid}
+}
@@ -3,17 +3,50 @@ package procedures
 import (
 	"Falcon/code/ast"
 	"Falcon/code/sugar"
-	"strings"
+	"slices"
 )
 
 type VoidProcedure struct {
+	ast.Meta
 	Name       string
 	Parameters []string
 	Body       []ast.Expr
+
+	// optional type annotations, an empty type is not annotated
+	ParameterTypes []string
 }
 
 func (v *VoidProcedure) String() string {
-	return sugar.Format("func %(%) {\n%}", v.Name, strings.Join(v.Parameters, ", "), ast.PadBody(v.Body))
+	return sugar.Format("func %(%) {\n%}", v.Name, ast.JoinParameters(v.Parameters, v.ParameterTypes), ast.PadBody(v.Body))
+}
+
+func (v *VoidProcedure) TypeComment() string {
+	return typeComment(v.Parameters, v.ParameterTypes, "")
+}
+
+func (v *VoidProcedure) SetTypeComment(comment string) bool {
+	types, returnType, ok := parseTypeComment(comment, v.Parameters)
+	if ok && returnType == "" {
+		v.ParameterTypes = types
+		return true
+	}
+	return false
+}
+
+// typeComment writes the annotations the way they are declared, like (w: number, h): number
+func typeComment(parameters []string, parameterTypes []string, returnType string) string {
+	if !slices.ContainsFunc(parameterTypes, func(t string) bool { return t != "" }) && returnType == "" {
+		return ""
+	}
+	return "(" + ast.JoinParameters(parameters, parameterTypes) + ")" + returnAnnotation(returnType)
+}
+
+func parseTypeComment(comment string, parameters []string) ([]string, string, bool) {
+	names, types, returnType, ok := ast.ParseParameters(comment)
+	if !ok || len(names) != len(parameters) {
+		return nil, "", false
+	}
+	return types, returnType, true
 }
 
 func (v *VoidProcedure) Blockly(flags ...bool) ast.Block {
@@ -1,5 +1,7 @@
 package ast
 
+import "strings"
+
 //go:generate stringer -type=Signature
 type Signature int
 
@@ -34,3 +36,80 @@ func CombineSignatures(first []Signature, second []Signature) []Signature {
 	}
 	return unique
 }
+
+// typeNames are the types a declaration can be annotated with
+var typeNames = map[string]Signature{
+	"number":    SignNumb,
+	"text":      SignText,
+	"boolean":   SignBool,
+	"list":      SignList,
+	"dict":      SignDict,
+	"component": SignComponent,
+	"any":       SignAny,
+}
+
+// ParseType returns the signature of a type annotation, an empty annotation is any type
+func ParseType(name string) (Signature, bool) {
+	if name == "" {
+		return SignAny, true
+	}
+	signature, ok := typeNames[name]
+	return signature, ok
+}
+
+// TypeCommentPrefix starts the block comment line that keeps the type annotations of a
+// declaration, the blocks have no place for them
+const TypeCommentPrefix = "@type "
+
+// Typed is a declaration whose type annotations can be kept in a block comment
+type Typed interface {
+	// TypeComment returns the annotations, or an empty text when there are none
+	TypeComment() string
+	// SetTypeComment restores the annotations and tells if the comment was understood
+	SetTypeComment(comment string) bool
+}
+
+// JoinParameters formats the parameters along with their type annotations, if any
+func JoinParameters(names []string, types []string) string {
+	parameters := make([]string, len(names))
+	for i, name := range names {
+		parameters[i] = name
+		if i < len(types) && types[i] != "" {
+			parameters[i] += ": " + types[i]
+		}
+	}
+	return strings.Join(parameters, ", ")
+}
+
+// ParseParameters reads the parameters written by JoinParameters, the result type
+// optionally follows them. An empty result means the comment is not understood.
+func ParseParameters(comment string) (names []string, types []string, result string, ok bool) {
+	comment = strings.TrimSpace(comment)
+	if !strings.HasPrefix(comment, "(") {
+		return nil, nil, "", false
+	}
+	end := strings.Index(comment, ")")
+	if end < 0 {
+		return nil, nil, "", false
+	}
+	if inner := strings.TrimSpace(comment[1:end]); inner != "" {
+		for _, parameter := range strings.Split(inner, ",") {
+			name, typeName, _ := strings.Cut(parameter, ":")
+			typeName = strings.TrimSpace(typeName)
+			if _, known := ParseType(typeName); !known {
+				return nil, nil, "", false
+			}
+			names = append(names, strings.TrimSpace(name))
+			types = append(types, typeName)
+		}
+	}
+	rest := strings.TrimSpace(comment[end+1:])
+	if rest != "" {
+		result, ok = strings.CutPrefix(rest, ":")
+		result = strings.TrimSpace(result)
+		if _, known := ParseType(result); !ok || !known {
+			return nil, nil, "", false
+		}
+	}
+	return names, types, result, true
+}
@@ -6,6 +6,7 @@ import (
 )
 
 type Get struct {
+	ast.Meta
 	Where          *lex.Token
 	Global         bool
 	Name           string
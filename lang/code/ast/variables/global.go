@@ -2,22 +2,43 @@ package variables
 
 import (
 	"Falcon/code/ast"
+	"strings"
 )
 
 type Global struct {
+	ast.Meta
 	Name  string
 	Value ast.Expr
+
+	// optional type annotation
+	Type string
 }
 
 func (g *Global) String() string {
-	return "global " + g.Name + " = " + g.Value.String()
+	if g.Type != "" {
+		return "global " + g.Name + ": " + g.Type + " = " + ast.Plugged(g.Value)
+	}
+	return "global " + g.Name + " = " + ast.Plugged(g.Value)
+}
+
+func (g *Global) TypeComment() string {
+	return g.Type
+}
+
+func (g *Global) SetTypeComment(comment string) bool {
+	comment = strings.TrimSpace(comment)
+	if _, ok := ast.ParseType(comment); !ok || comment == "" {
+		return false
+	}
+	g.Type = comment
+	return true
 }
 
 func (g *Global) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:   "global_declaration",
 		Fields: []ast.Field{{Name: "NAME", Value: g.Name}},
-		Values: []ast.Value{{Name: "VALUE", Block: g.Value.Blockly(false)}},
+		Values: []ast.Value{{Name: "VALUE", Block: ast.BlockOf(g.Value, false)}},
 	}
 }
 
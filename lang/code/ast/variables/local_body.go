@@ -2,24 +2,30 @@ package variables
 
 import (
 	"Falcon/code/ast"
+	"slices"
 	"strings"
 )
 
 type Var struct {
+	ast.Meta
 	Names  []string
 	Values []ast.Expr
-	Body   []ast.Expr
+	// Trailing comments at the end of the line of each local, empty when there's none
+	Trailings []string
+	Body      []ast.Expr
 }
 
 func (v *Var) String() string {
 	var builder strings.Builder
 	localLines := make([]string, len(v.Names))
 	for k, name := range v.Names {
-		localLines[k] = "local " + name + " = " + v.Values[k].String()
+		localLines[k] = "local " + name + " = " + ast.Plugged(v.Values[k])
+		if k < len(v.Trailings) && v.Trailings[k] != "" {
+			localLines[k] += " " + ast.CommentLine(v.Trailings[k])
+		}
 	}
 	builder.WriteString(strings.Join(localLines, "\n"))
-	builder.WriteString("\n")
-	builder.WriteString(ast.JoinExprs("\n", v.Body))
+	builder.WriteString(ast.FollowStatements(v.Body))
 	return builder.String()
 }
 
@@ -44,3 +50,26 @@ func (v *Var) Consumable(flags ...bool) bool {
 func (v *Var) Signature() []ast.Signature {
 	return []ast.Signature{ast.SignVoid}
 }
+
+func (v *Var) LineComments() []string {
+	var comments []string
+	for k, comment := range v.Trailings {
+		if comment != "" {
+			comments = append(comments, v.Names[k]+": "+comment)
+		}
+	}
+	return comments
+}
+
+func (v *Var) SetLineComment(comment string) bool {
+	name, trailing, found := strings.Cut(comment, ": ")
+	k := slices.Index(v.Names, name)
+	if !found || k < 0 {
+		return false
+	}
+	for len(v.Trailings) < len(v.Names) {
+		v.Trailings = append(v.Trailings, "")
+	}
+	v.Trailings[k] = trailing
+	return true
+}
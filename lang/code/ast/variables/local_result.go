@@ -6,6 +6,7 @@ import (
 )
 
 type VarResult struct {
+	ast.Meta
 	Names  []string
 	Values []ast.Expr
 	Result ast.Expr
@@ -21,8 +22,8 @@ func (v *VarResult) String() string {
 	combinedValues = v.Values
 
 	for {
-		// check for nested var results!
-		if vr, ok := result.(*VarResult); ok {
+		// check for nested var results! an annotated one is a block of its own
+		if vr, ok := result.(*VarResult); ok && vr.State.Annotations() == "" {
 			combinedNames = append(combinedNames, vr.Names...)
 			combinedValues = append(combinedValues, vr.Values...)
 			result = vr.Result
@@ -35,23 +36,22 @@ func (v *VarResult) String() string {
 	builder.WriteString("{\n")
 	localLines := make([]string, len(combinedNames))
 	for k, name := range combinedNames {
-		localLines[k] = "local " + name + " = " + combinedValues[k].String()
+		localLines[k] = "local " + name + " = " + ast.Plugged(combinedValues[k])
 	}
 	builder.WriteString(ast.PadDirect(strings.Join(localLines, "\n")))
 	builder.WriteString("\n")
-	builder.WriteString(ast.PadDirect(result.String()))
+	builder.WriteString(ast.PadDirect(ast.Plugged(result)))
 	builder.WriteString("\n}")
 	return builder.String()
 }
 
 func (v *VarResult) Blockly(flags ...bool) ast.Block {
-	println("called!")
 	return ast.Block{
 		Type:     "local_declaration_expression",
 		Mutation: &ast.Mutation{LocalNames: ast.MakeLocalNames(v.Names...)},
 		Fields:   ast.ToFields("VAR", v.Names),
 		Values: append(ast.ValuesByPrefix("DECL", v.Values),
-			ast.Value{Name: "RETURN", Block: v.Result.Blockly(false)}),
+			ast.Value{Name: "RETURN", Block: ast.BlockOf(v.Result, false)}),
 	}
 }
 
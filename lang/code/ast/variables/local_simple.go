@@ -6,6 +6,7 @@ import (
 )
 
 type SimpleVar struct {
+	ast.Meta
 	Name  string
 	Value ast.Expr
 	Body  []ast.Expr
@@ -16,9 +17,8 @@ func (v *SimpleVar) String() string {
 	builder.WriteString("local ")
 	builder.WriteString(v.Name)
 	builder.WriteString(" = ")
-	builder.WriteString(v.Value.String())
-	builder.WriteString("\n")
-	builder.WriteString(ast.JoinExprs("\n", v.Body))
+	builder.WriteString(ast.Plugged(v.Value))
+	builder.WriteString(ast.FollowStatements(v.Body))
 	return builder.String()
 }
 
@@ -27,7 +27,7 @@ func (v *SimpleVar) Blockly(flags ...bool) ast.Block {
 		Type:       "local_declaration_statement",
 		Mutation:   &ast.Mutation{LocalNames: ast.MakeLocalNames(v.Name)},
 		Fields:     []ast.Field{{Name: "VAR0", Value: v.Name}},
-		Values:     []ast.Value{{Name: "DECL0", Block: v.Value.Blockly(false)}},
+		Values:     []ast.Value{{Name: "DECL0", Block: ast.BlockOf(v.Value, false)}},
 		Statements: ast.OptionalStatement("STACK", v.Body),
 	}
 }
@@ -1,8 +1,13 @@
 package variables
 
-import "Falcon/code/ast"
+import (
+	"Falcon/code/ast"
+	"Falcon/code/lex"
+)
 
 type Set struct {
+	ast.Meta
+	Where  *lex.Token // the name of the variable, nil when the source is unknown
 	Global bool
 	Name   string
 	Expr   ast.Expr
@@ -10,9 +15,9 @@ type Set struct {
 
 func (s Set) String() string {
 	if s.Global {
-		return "this." + s.Name + " = " + s.Expr.String()
+		return "this." + s.Name + " = " + ast.Plugged(s.Expr)
 	}
-	return s.Name + " = " + s.Expr.String()
+	return s.Name + " = " + ast.Plugged(s.Expr)
 }
 
 func (s Set) Blockly(flags ...bool) ast.Block {
@@ -25,7 +30,7 @@ func (s Set) Blockly(flags ...bool) ast.Block {
 	return ast.Block{
 		Type:   "lexical_variable_set",
 		Fields: []ast.Field{{Name: "VAR", Value: name}},
-		Values: []ast.Value{{Name: "VALUE", Block: s.Expr.Blockly(false)}},
+		Values: []ast.Value{{Name: "VALUE", Block: ast.BlockOf(s.Expr, false)}},
 	}
 }
 
@@ -0,0 +1,302 @@
+package check
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/ast/list"
+	"Falcon/code/ast/method"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/ast/variables"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// the types of globals and procedures depend on each other, they settle within a few passes
+const maxPasses = 5
+
+// Checker infers the types of a program from the signatures of its nodes, and reports
+// the values used where another type is expected
+type Checker struct {
+	// Strict also reports the values App Inventor converts at runtime, like a text used as
+	// a number, and the variables that are assigned a value of another type
+	Strict bool
+
+	// the types of the values the globals are declared with
+	declared map[string]ast.Signature
+
+	// the types the declarations are annotated with
+	annotatedGlobals    map[string]ast.Signature
+	annotatedParameters map[string][]ast.Signature
+	annotatedReturns    map[string]ast.Signature
+
+	// the types settled by the previous pass
+	globals map[string]ast.Signature
+	returns map[string]ast.Signature
+
+	// the types seen during the current pass
+	seenGlobals map[string]ast.Signature
+	seenReturns map[string]ast.Signature
+
+	reporting   bool
+	diagnostics []*context.Diagnostic
+	where       *lex.Token
+	// the first tokens of the expressions parsed from the source
+	starts map[ast.Expr]*lex.Token
+}
+
+type scope struct {
+	names  map[string]ast.Signature
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{names: map[string]ast.Signature{}, parent: parent}
+}
+
+// owner returns the scope that declares the name
+func (s *scope) owner(name string) *scope {
+	for curr := s; curr != nil; curr = curr.parent {
+		if _, ok := curr.names[name]; ok {
+			return curr
+		}
+	}
+	return nil
+}
+
+func NewChecker(strict bool) *Checker {
+	return &Checker{Strict: strict}
+}
+
+// Locate tells where the expressions parsed from the source start, so that the ones without a token
+// of their own, such as a text, are reported at their place rather than at the expression around them
+func (c *Checker) Locate(located []mistparser.LocatedExpr) *Checker {
+	c.starts = map[ast.Expr]*lex.Token{}
+	for _, aLocated := range located {
+		if _, found := c.starts[aLocated.Expr]; !found && aLocated.Expr != nil {
+			c.starts[aLocated.Expr] = aLocated.Start
+		}
+	}
+	return c
+}
+
+// Check returns the type diagnostics of the program, warnings unless the checker is strict
+func (c *Checker) Check(program []ast.Expr) []*context.Diagnostic {
+	c.declared = map[string]ast.Signature{}
+	c.annotations(program)
+	c.globals = map[string]ast.Signature{}
+	c.returns = map[string]ast.Signature{}
+	for pass := 0; pass < maxPasses; pass++ {
+		globals, returns := c.globals, c.returns
+		c.pass(program, false)
+		if maps.Equal(globals, c.globals) && maps.Equal(returns, c.returns) {
+			break
+		}
+	}
+	c.diagnostics = nil
+	c.pass(program, true)
+	return c.diagnostics
+}
+
+// annotations collects the types the globals and the procedures are annotated with,
+// an unannotated parameter is of any type
+func (c *Checker) annotations(program []ast.Expr) {
+	c.annotatedGlobals = map[string]ast.Signature{}
+	c.annotatedParameters = map[string][]ast.Signature{}
+	c.annotatedReturns = map[string]ast.Signature{}
+	for _, expr := range program {
+		switch e := expr.(type) {
+		case *variables.Global:
+			if e.Type != "" {
+				c.annotatedGlobals[e.Name], _ = ast.ParseType(e.Type)
+			}
+		case *procedures.VoidProcedure:
+			c.annotatedParameters[e.Name] = parseTypes(e.ParameterTypes)
+		case *procedures.RetProcedure:
+			c.annotatedParameters[e.Name] = parseTypes(e.ParameterTypes)
+			if e.ReturnType != "" {
+				c.annotatedReturns[e.Name], _ = ast.ParseType(e.ReturnType)
+			}
+		}
+	}
+}
+
+func parseTypes(typeNames []string) []ast.Signature {
+	signatures := make([]ast.Signature, len(typeNames))
+	for i, typeName := range typeNames {
+		signatures[i], _ = ast.ParseType(typeName)
+	}
+	return signatures
+}
+
+func (c *Checker) pass(program []ast.Expr, reporting bool) {
+	c.reporting = reporting
+	c.seenGlobals = map[string]ast.Signature{}
+	c.seenReturns = map[string]ast.Signature{}
+	root := newScope(nil)
+	for _, expr := range program {
+		c.where = nil
+		c.infer(expr, root)
+	}
+	c.globals, c.returns = c.seenGlobals, c.seenReturns
+}
+
+type compatibility int
+
+const (
+	compatible compatibility = iota
+	coerced                  // App Inventor converts the value at runtime
+	incompatible
+)
+
+func compare(expected ast.Signature, actual ast.Signature) compatibility {
+	if expected == actual || isDynamic(expected) || isDynamic(actual) {
+		return compatible
+	}
+	switch expected {
+	case ast.SignNumb, ast.SignBool:
+		// numeric texts and the texts "true" and "false"
+		if actual == ast.SignText {
+			return coerced
+		}
+	case ast.SignText:
+		if actual == ast.SignNumb || actual == ast.SignBool {
+			return coerced
+		}
+		if actual == ast.SignHelper {
+			return compatible
+		}
+	case ast.SignDict:
+		// a list of pairs
+		if actual == ast.SignList {
+			return coerced
+		}
+	}
+	return incompatible
+}
+
+func isDynamic(signature ast.Signature) bool {
+	return signature == ast.SignAny || signature == ast.SignOfEvent
+}
+
+// join is the type of a value that is one of the two
+func join(first ast.Signature, second ast.Signature) ast.Signature {
+	if first == second {
+		return first
+	}
+	return ast.SignAny
+}
+
+func signatureOf(expr ast.Expr) ast.Signature {
+	signatures := expr.Signature()
+	if len(signatures) != 1 {
+		return ast.SignAny
+	}
+	return signatures[0]
+}
+
+// expect infers the expression and reports it when its type does not fit where it is used
+func (c *Checker) expect(expr ast.Expr, expected ast.Signature, s *scope, usage string) ast.Signature {
+	actual := c.infer(expr, s)
+	result := compare(expected, actual)
+	if text, ok := expr.(*fundamentals.Text); ok && expected == ast.SignNumb {
+		// a literal text is known to be a number or not
+		if _, err := strconv.ParseFloat(strings.TrimSpace(text.Content), 64); err == nil {
+			result = compatible
+		} else {
+			result = incompatible
+		}
+	}
+	if result == compatible || (result == coerced && !c.Strict) {
+		return actual
+	}
+	where := c.tokenOf(expr)
+	if where == nil {
+		where = c.where
+	}
+	c.report(where, "% expects % but got %", usage, describe(expected), describe(actual))
+	return actual
+}
+
+// report adds a diagnostic during the last pass, a warning unless the checker is strict
+func (c *Checker) report(where *lex.Token, message string, args ...string) {
+	if !c.reporting {
+		return
+	}
+	severity := context.SeverityWarning
+	if c.Strict {
+		severity = context.SeverityError
+	}
+	var codeContext *context.CodeContext
+	var span context.Span
+	if where != nil {
+		codeContext = where.Context
+		span = where.Span()
+	}
+	diagnostic := codeContext.NewDiagnostic(severity, context.CodeType, span, message, args...)
+	for _, reported := range c.diagnostics {
+		// the operands of a binary expression without a token of their own are reported at the operator
+		if reported.Span == diagnostic.Span && reported.Message == diagnostic.Message {
+			return
+		}
+	}
+	c.diagnostics = append(c.diagnostics, diagnostic)
+}
+
+// tokenOf returns the source token of the expression, else the token it starts with
+func (c *Checker) tokenOf(expr ast.Expr) *lex.Token {
+	var where *lex.Token
+	switch e := expr.(type) {
+	case *common.BinaryExpr:
+		where = e.Where
+	case *common.FuncCall:
+		where = e.Where
+	case *common.Question:
+		where = e.Where
+	case *common.Transform:
+		where = e.Where
+	case *method.Call:
+		where = e.Where
+	case *list.Transformer:
+		where = e.Where
+	case *variables.Get:
+		where = e.Where
+	case *variables.Set:
+		where = e.Where
+	case *fundamentals.Color:
+		where = e.Where
+	}
+	if where == nil {
+		where = c.starts[expr]
+	}
+	if where == nil || where.Column < 0 {
+		return nil
+	}
+	return where
+}
+
+func describe(signature ast.Signature) string {
+	switch signature {
+	case ast.SignBool:
+		return "a boolean"
+	case ast.SignNumb:
+		return "a number"
+	case ast.SignText:
+		return "a text"
+	case ast.SignList:
+		return "a list"
+	case ast.SignDict:
+		return "a dictionary"
+	case ast.SignComponent:
+		return "a component"
+	case ast.SignHelper:
+		return "an option"
+	case ast.SignVoid:
+		return "nothing"
+	}
+	return "any value"
+}
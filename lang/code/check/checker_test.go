@@ -0,0 +1,76 @@
+package check
+
+import (
+	"Falcon/code/context"
+	"Falcon/code/parsers/mistparser/misttest"
+	"strconv"
+	"testing"
+)
+
+func checkSource(t *testing.T, sourceCode string, strict bool) []*context.Diagnostic {
+	t.Helper()
+	parser, expressions := misttest.Parse(t, sourceCode)
+	return NewChecker(strict).Locate(parser.Located).Check(expressions)
+}
+
+// a diagnostic expected at a line and a column
+type expected struct {
+	line, column int
+	message      string
+}
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		strict   bool
+		expected []expected
+	}{
+		{"well typed", "global n = 1 + 2\nfunc f(x) = { x * n }\n", false, nil},
+		{"program", misttest.Program, true, nil},
+		{"coerced number", "println(\"2\" + 1)\n", false, nil},
+		{"strict coerced number", "global t = \"a\"\nprintln(this.t + 1)\n", true,
+			[]expected{{2, 9, "+ expects a number but got a text"}}},
+		{"each operand", "println(true + false)\n", false, []expected{
+			{1, 9, "+ expects a number but got a boolean"},
+			{1, 16, "+ expects a number but got a boolean"},
+		}},
+		{"annotated global", "global g: number = \"a\"\n", false,
+			[]expected{{1, 20, "this.g expects a number but got a text"}}},
+		{"condition in a procedure", "func f() {\n  if (1) {\n    println(1)\n  }\n}\n", false,
+			[]expected{{2, 7, "if expects a boolean but got a number"}}},
+		{"global assignment", "global g: number = 0\nfunc f() {\n  this.g = [1]\n}\n", false,
+			[]expected{{3, 3, "this.g holds a number but is assigned a list"}}},
+		{"local assignment", "func f() {\n  local y = 1\n  y = \"t\"\n}\n", true,
+			[]expected{{3, 3, "y holds a number but is assigned a text"}}},
+		{"annotated parameter", "func f(n: number) {\n  println(n)\n}\nf([1])\n", false,
+			[]expected{{4, 3, "the parameter n of f() expects a number but got a list"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := checkSource(t, test.source, test.strict)
+			if len(diagnostics) != len(test.expected) {
+				t.Fatalf("expected %d diagnostics but got %v", len(test.expected), diagnostics)
+			}
+			for i, diagnostic := range diagnostics {
+				want := test.expected[i]
+				if diagnostic.Line != want.line || diagnostic.Column != want.column || diagnostic.Message != want.message {
+					t.Errorf("expected %s at %d:%d but got %s at %s:%s", want.message, want.line, want.column,
+						diagnostic.Message, strconv.Itoa(diagnostic.Line), strconv.Itoa(diagnostic.Column))
+				}
+				if diagnostic.EndColumn <= diagnostic.Column {
+					t.Errorf("%s has an empty span", diagnostic.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerWithoutLocations(t *testing.T) {
+	_, expressions := misttest.Parse(t, "func f() {\n  println(true + false)\n}\n")
+	diagnostics := NewChecker(false).Check(expressions)
+	// both operands are reported at the operator, once
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 || diagnostics[0].Column != 16 {
+		t.Fatalf("expected a diagnostic at 2:16 but got %v", diagnostics)
+	}
+}
@@ -0,0 +1,411 @@
+package check
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/ast/components"
+	"Falcon/code/ast/control"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/ast/list"
+	"Falcon/code/ast/method"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/ast/variables"
+	"Falcon/code/lex"
+)
+
+// infer returns the type of the expression, checking the expressions inside it
+func (c *Checker) infer(expr ast.Expr, s *scope) ast.Signature {
+	if where := c.tokenOf(expr); where != nil {
+		enclosing := c.where
+		c.where = where
+		defer func() { c.where = enclosing }()
+	}
+	switch e := expr.(type) {
+	case *fundamentals.Not:
+		c.expect(e.Expr, ast.SignBool, s, "not")
+		return ast.SignBool
+	case *fundamentals.List:
+		c.inferAll(e.Elements, s)
+		return ast.SignList
+	case *fundamentals.Dictionary:
+		c.inferAll(e.Elements, s)
+		return ast.SignDict
+	case *fundamentals.Pair:
+		c.infer(e.Key, s)
+		c.infer(e.Value, s)
+		return ast.SignAny
+	case *fundamentals.SmartBody:
+		return c.body(e.Body, newScope(s))
+
+	case *common.BinaryExpr:
+		return c.binary(e, s)
+	case *common.FuncCall:
+		return c.funcCall(e, s)
+	case *common.Question:
+		c.infer(e.On, s)
+		return ast.SignBool
+	case *common.Transform:
+		c.infer(e.On, s)
+		return ast.SignText
+	case *common.EmptySocket:
+		return ast.SignAny
+
+	case *control.If:
+		for k, condition := range e.Conditions {
+			c.expect(condition, ast.SignBool, s, "if")
+			c.body(e.Bodies[k], newScope(s))
+		}
+		c.body(e.ElseBody, newScope(s))
+		return ast.SignVoid
+	case *control.SimpleIf:
+		condition, then, elze := e.Branches()
+		c.expect(condition, ast.SignBool, s, "if")
+		return join(c.body(then, newScope(s)), c.body(elze, newScope(s)))
+	case *control.While:
+		c.expect(e.Condition, ast.SignBool, s, "while")
+		c.body(e.Body, newScope(s))
+		return ast.SignVoid
+	case *control.For:
+		c.expect(e.From, ast.SignNumb, s, "for")
+		c.expect(e.To, ast.SignNumb, s, "for")
+		c.expect(e.By, ast.SignNumb, s, "for")
+		loop := newScope(s)
+		loop.names[e.IName] = ast.SignNumb
+		c.body(e.Body, loop)
+		return ast.SignVoid
+	case *control.Each:
+		c.expect(e.Iterable, ast.SignList, s, "for each item")
+		loop := newScope(s)
+		loop.names[e.IName] = ast.SignAny
+		c.body(e.Body, loop)
+		return ast.SignVoid
+	case *control.EachPair:
+		c.expect(e.Iterable, ast.SignDict, s, "for each key and value")
+		loop := newScope(s)
+		loop.names[e.KeyName] = ast.SignAny
+		loop.names[e.ValueName] = ast.SignAny
+		c.body(e.Body, loop)
+		return ast.SignVoid
+	case *control.Do:
+		local := newScope(s)
+		c.body(e.Body, local)
+		return c.infer(e.Result, local)
+
+	case *list.Get:
+		c.expect(e.List, ast.SignList, s, "the list index")
+		c.expect(e.Index, ast.SignNumb, s, "the list index")
+		return ast.SignAny
+	case *list.Set:
+		c.expect(e.List, ast.SignList, s, "the list index")
+		c.expect(e.Index, ast.SignNumb, s, "the list index")
+		c.infer(e.Value, s)
+		return ast.SignVoid
+	case *list.Transformer:
+		return c.transformer(e, s)
+	case *method.Call:
+		return c.methodCall(e, s)
+
+	case *procedures.Call:
+		return c.procedureCall(e, s)
+	case *procedures.VoidProcedure:
+		c.body(e.Body, typedParameters(e.Parameters, c.annotatedParameters[e.Name], s))
+		return ast.SignVoid
+	case *procedures.RetProcedure:
+		local := typedParameters(e.Parameters, c.annotatedParameters[e.Name], s)
+		if returnType, ok := c.annotatedReturns[e.Name]; ok {
+			c.expect(e.Result, returnType, local, "the result of "+e.Name+"()")
+			c.seenReturns[e.Name] = returnType
+		} else {
+			c.seenReturns[e.Name] = c.infer(e.Result, local)
+		}
+		return ast.SignVoid
+	case *procedures.Test:
+		c.body(e.Body, newScope(s))
+		return ast.SignVoid
+
+	case *variables.Get:
+		return c.variable(e, s)
+	case *variables.Set:
+		c.assign(e, c.infer(e.Expr, s), s)
+		return ast.SignVoid
+	case *variables.Global:
+		if annotated, ok := c.annotatedGlobals[e.Name]; ok {
+			c.expect(e.Value, annotated, s, "this."+e.Name)
+			c.declared[e.Name] = annotated
+			c.defineGlobal(e.Name, annotated)
+			return ast.SignVoid
+		}
+		value := c.infer(e.Value, s)
+		c.declared[e.Name] = value
+		c.defineGlobal(e.Name, value)
+		return ast.SignVoid
+	case *variables.Var:
+		c.body(e.Body, c.declare(e.Names, e.Values, s))
+		return ast.SignVoid
+	case *variables.SimpleVar:
+		c.body(e.Body, c.declare([]string{e.Name}, []ast.Expr{e.Value}, s))
+		return ast.SignVoid
+	case *variables.VarResult:
+		return c.infer(e.Result, c.declare(e.Names, e.Values, s))
+
+	case *components.Event:
+		c.body(e.Body, parameters(e.Parameters, s))
+		return ast.SignVoid
+	case *components.GenericEvent:
+		c.body(e.Body, parameters(e.Parameters, s))
+		return ast.SignVoid
+	case *components.PropertySet:
+		c.infer(e.Value, s)
+		return ast.SignVoid
+	case *components.MethodCall:
+		c.inferAll(e.Args, s)
+		return signatureOf(e)
+	case *components.GenericPropertyGet:
+		c.expect(e.Component, ast.SignComponent, s, "the property ."+e.Property)
+		return ast.SignAny
+	case *components.GenericPropertySet:
+		c.expect(e.Component, ast.SignComponent, s, "the property ."+e.Property)
+		c.infer(e.Value, s)
+		return ast.SignVoid
+	case *components.GenericMethodCall:
+		c.expect(e.Component, ast.SignComponent, s, "the method ."+e.Method+"()")
+		c.inferAll(e.Args, s)
+		return ast.SignAny
+	case *components.EveryComponent:
+		return ast.SignList
+	}
+	// literals and the remaining component nodes know their type
+	return signatureOf(expr)
+}
+
+func (c *Checker) inferAll(exprs []ast.Expr, s *scope) {
+	for _, expr := range exprs {
+		c.infer(expr, s)
+	}
+}
+
+// body checks the statements, the type of the body is the type of its last expression
+func (c *Checker) body(exprs []ast.Expr, s *scope) ast.Signature {
+	result := ast.SignVoid
+	for _, expr := range exprs {
+		result = c.infer(expr, s)
+	}
+	return result
+}
+
+// parameters declares the parameters of a procedure or an event, their types are unknown
+func parameters(names []string, s *scope) *scope {
+	local := newScope(s)
+	for _, name := range names {
+		local.names[name] = ast.SignAny
+	}
+	return local
+}
+
+// typedParameters declares the parameters of a procedure with the types they are annotated with
+func typedParameters(names []string, signatures []ast.Signature, s *scope) *scope {
+	local := parameters(names, s)
+	for i, signature := range signatures {
+		local.names[names[i]] = signature
+	}
+	return local
+}
+
+func (c *Checker) procedureCall(call *procedures.Call, s *scope) ast.Signature {
+	annotated := c.annotatedParameters[call.Name]
+	for i, argument := range call.Arguments {
+		if i < len(annotated) && i < len(call.Parameters) {
+			c.expect(argument, annotated[i], s, "the parameter "+call.Parameters[i]+" of "+call.Name+"()")
+		} else {
+			c.infer(argument, s)
+		}
+	}
+	if !call.Returning {
+		return ast.SignVoid
+	}
+	if result, ok := c.returns[call.Name]; ok {
+		return result
+	}
+	return ast.SignAny
+}
+
+// declare types local variables in order, each one sees the ones declared before it
+func (c *Checker) declare(names []string, values []ast.Expr, s *scope) *scope {
+	local := newScope(s)
+	for k, name := range names {
+		local.names[name] = c.infer(values[k], local)
+	}
+	return local
+}
+
+func (c *Checker) variable(get *variables.Get, s *scope) ast.Signature {
+	if get.Global {
+		if signature, ok := c.globals[get.Name]; ok {
+			return signature
+		}
+		return ast.SignAny
+	}
+	if owner := s.owner(get.Name); owner != nil {
+		return owner.names[get.Name]
+	}
+	return ast.SignAny
+}
+
+// assign widens the type of the variable when it is given a value of another type
+func (c *Checker) assign(set *variables.Set, value ast.Signature, s *scope) {
+	where := c.tokenOf(set)
+	if where == nil {
+		where = c.where
+	}
+	if set.Global {
+		if annotated, ok := c.annotatedGlobals[set.Name]; ok {
+			// an annotated global keeps its type
+			if compare(annotated, value) == incompatible || (c.Strict && changes(annotated, value)) {
+				c.report(where, "this.% holds % but is assigned %", set.Name, describe(annotated), describe(value))
+			}
+			return
+		}
+		if previous, ok := c.declared[set.Name]; ok && c.Strict && changes(previous, value) {
+			c.report(where, "this.% holds % but is assigned %", set.Name, describe(previous), describe(value))
+		}
+		c.defineGlobal(set.Name, value)
+		return
+	}
+	owner := s.owner(set.Name)
+	if owner == nil {
+		return
+	}
+	previous := owner.names[set.Name]
+	if c.Strict && changes(previous, value) {
+		c.report(where, "% holds % but is assigned %", set.Name, describe(previous), describe(value))
+	}
+	owner.names[set.Name] = join(previous, value)
+}
+
+// changes tells if a variable of a known type is assigned a value of another known type
+func changes(previous ast.Signature, value ast.Signature) bool {
+	return previous != value && !isDynamic(previous) && !isDynamic(value)
+}
+
+func (c *Checker) defineGlobal(name string, value ast.Signature) {
+	if previous, ok := c.seenGlobals[name]; ok {
+		value = join(previous, value)
+	}
+	c.seenGlobals[name] = value
+}
+
+func (c *Checker) binary(b *common.BinaryExpr, s *scope) ast.Signature {
+	usage := *b.Where.Content
+	switch b.Operator {
+	case lex.Equals, lex.NotEquals:
+		c.inferAll(b.Operands, s)
+		return ast.SignBool
+	case lex.LogicAnd, lex.LogicOr:
+		c.expectAll(b.Operands, ast.SignBool, s, usage)
+		return ast.SignBool
+	case lex.Underscore:
+		// anything can be joined into a text
+		c.inferAll(b.Operands, s)
+		return ast.SignText
+	case lex.LessThan, lex.LessThanEqual, lex.GreatThan, lex.GreaterThanEqual:
+		c.expectAll(b.Operands, ast.SignNumb, s, usage)
+		return ast.SignBool
+	case lex.TextEquals, lex.TextNotEquals, lex.TextLessThan, lex.TextGreaterThan:
+		c.expectAll(b.Operands, ast.SignText, s, usage)
+		return ast.SignBool
+	}
+	// arithmetic and bitwise operators
+	c.expectAll(b.Operands, ast.SignNumb, s, usage)
+	return ast.SignNumb
+}
+
+func (c *Checker) expectAll(exprs []ast.Expr, expected ast.Signature, s *scope, usage string) {
+	for _, expr := range exprs {
+		c.expect(expr, expected, s, usage)
+	}
+}
+
+// parameterTypes of the in-built functions whose arguments all have the same type
+var parameterTypes = map[string]ast.Signature{
+	"sqrt": ast.SignNumb, "abs": ast.SignNumb, "neg": ast.SignNumb, "log": ast.SignNumb,
+	"exp": ast.SignNumb, "round": ast.SignNumb, "ceil": ast.SignNumb, "floor": ast.SignNumb,
+	"sin": ast.SignNumb, "cos": ast.SignNumb, "tan": ast.SignNumb, "asin": ast.SignNumb,
+	"acos": ast.SignNumb, "atan": ast.SignNumb, "aTan2": ast.SignNumb, "degrees": ast.SignNumb,
+	"radians": ast.SignNumb, "decToHex": ast.SignNumb, "decToBin": ast.SignNumb,
+	"randInt": ast.SignNumb, "setRandSeed": ast.SignNumb, "min": ast.SignNumb, "max": ast.SignNumb,
+	"mod": ast.SignNumb, "rem": ast.SignNumb, "quot": ast.SignNumb, "formatDecimal": ast.SignNumb,
+	"splitColor": ast.SignNumb,
+
+	"hexToDec": ast.SignText, "binToDec": ast.SignText, "dec": ast.SignText, "bin": ast.SignText,
+	"octal": ast.SignText, "hexa": ast.SignText, "openScreen": ast.SignText,
+	"closeScreenWithPlainText": ast.SignText,
+
+	"avgOf": ast.SignList, "maxOf": ast.SignList, "minOf": ast.SignList, "geoMeanOf": ast.SignList,
+	"stdDevOf": ast.SignList, "stdErrOf": ast.SignList, "modeOf": ast.SignList,
+	"copyList": ast.SignList, "makeColor": ast.SignList,
+
+	"copyDict":   ast.SignDict,
+	"assertTrue": ast.SignBool,
+}
+
+func (c *Checker) funcCall(f *common.FuncCall, s *scope) ast.Signature {
+	if f.Name == "every" {
+		// the argument names a component type
+		return ast.SignList
+	}
+	expected, ok := parameterTypes[f.Name]
+	for _, arg := range f.Args {
+		if ok {
+			c.expect(arg, expected, s, f.Name+"()")
+		} else {
+			c.infer(arg, s)
+		}
+	}
+	if _, signature := common.TestSignature(f.Name, len(f.Args)); signature != nil {
+		return signature.Signature
+	}
+	return ast.SignAny
+}
+
+// receivers of the method modules
+var receivers = map[string]ast.Signature{
+	"text": ast.SignText,
+	"list": ast.SignList,
+	"dict": ast.SignDict,
+}
+
+func (c *Checker) methodCall(call *method.Call, s *scope) ast.Signature {
+	_, signature := method.TestSignature(call.Name, len(call.Args))
+	if signature == nil {
+		c.infer(call.On, s)
+		c.inferAll(call.Args, s)
+		return ast.SignAny
+	}
+	c.expect(call.On, receivers[signature.Module], s, "."+call.Name+"()")
+	c.inferAll(call.Args, s)
+	return signature.Signature
+}
+
+func (c *Checker) transformer(t *list.Transformer, s *scope) ast.Signature {
+	c.expect(t.List, ast.SignList, s, "."+t.Name+" {}")
+	initial := ast.SignAny
+	for _, arg := range t.Args {
+		initial = c.infer(arg, s)
+	}
+	lambda := newScope(s)
+	for _, name := range t.Names {
+		lambda.names[name] = ast.SignAny
+	}
+	switch t.Name {
+	case "filter", "sort", "min", "max":
+		c.expect(t.Transformer, ast.SignBool, lambda, "."+t.Name+" {}")
+	case "reduce":
+		return join(initial, c.infer(t.Transformer, lambda))
+	default:
+		c.infer(t.Transformer, lambda)
+	}
+	if t.Name == "min" || t.Name == "max" {
+		return ast.SignAny
+	}
+	return ast.SignList
+}
@@ -18,7 +18,8 @@ func (c *CodeContext) ReportError(
 	message string,
 	args ...string,
 ) {
-	panic(c.BuildError(true, column, row, highlightWordSize, message, args...))
+	span := Span{Line: column, Column: row - highlightWordSize + 1, EndColumn: row + 1}
+	panic(c.NewDiagnostic(SeverityError, CodeSyntax, span, message, args...))
 }
 
 func (c *CodeContext) BuildError(
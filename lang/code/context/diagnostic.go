@@ -0,0 +1,129 @@
+package context
+
+import (
+	"Falcon/code/sugar"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Diagnostic codes, they let tools tell apart the kind of problem without parsing messages
+const (
+	CodeLexical    = "lexical"
+	CodeSyntax     = "syntax"
+	CodeUnresolved = "unresolved"
+	CodeInternal   = "internal"
+	CodeRuntime    = "runtime"
+	CodeAssertion  = "assertion"
+	CodeType       = "type"
+	CodeComponent  = "component"
+	CodeDesign     = "design"
+)
+
+// Span is a range of a single line in a source file. Lines and columns are 1-based,
+// EndColumn is exclusive.
+type Span struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+}
+
+type RelatedSpan struct {
+	Span
+	Message string `json:"message"`
+}
+
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Span
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Related []RelatedSpan `json:"related,omitempty"`
+
+	context *CodeContext
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Render(true)
+}
+
+// Render builds a human-readable message pointing at the source line, if the source is known
+func (d *Diagnostic) Render(decorate bool) string {
+	if d.context == nil || d.context.SourceCode == nil || d.Line < 1 {
+		return d.Position() + d.Message
+	}
+	width := max(d.EndColumn-d.Column, 1)
+	return d.context.BuildError(decorate, d.Line, d.Column-1+width, width, d.Message)
+}
+
+// Position returns the "file:line:column: " prefix of the diagnostic
+func (d *Diagnostic) Position() string {
+	var parts []string
+	if d.File != "" {
+		parts = append(parts, d.File)
+	}
+	if d.Line > 0 {
+		parts = append(parts, strconv.Itoa(d.Line), strconv.Itoa(d.Column))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, ":") + ": "
+}
+
+func (d *Diagnostic) WithRelated(span Span, message string) *Diagnostic {
+	d.Related = append(d.Related, RelatedSpan{Span: span, Message: message})
+	return d
+}
+
+func (c *CodeContext) NewDiagnostic(
+	severity Severity,
+	code string,
+	span Span,
+	message string,
+	args ...string,
+) *Diagnostic {
+	if c != nil {
+		span.File = c.FileName
+	}
+	return &Diagnostic{
+		Severity: severity,
+		Span:     span,
+		Code:     code,
+		Message:  sugar.Format(message, args...),
+		context:  c,
+	}
+}
+
+func HasErrors(diagnostics []*Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
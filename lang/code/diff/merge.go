@@ -0,0 +1,352 @@
+package diff
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/components"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/ast/variables"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/components/registry"
+	"Falcon/design"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Options tells how the components of the sources are known
+type Options struct {
+	Components *registry.Registry // the component descriptors, the default ones when nil
+	Screen     *design.Component  // the design declaring the components, if any
+}
+
+// Declaration is a top level statement of a source, such as a func, a when or a global
+type Declaration struct {
+	// Key tells the declaration apart from the others of the source, e.g. func double
+	Key  string
+	Expr ast.Expr
+	// Text is the declaration as it is written, along with the comments and the empty lines above it
+	Text string
+}
+
+// Source is a version of a source file split into its declarations
+type Source struct {
+	Header       string // the component definitions, before the first declaration
+	Declarations []*Declaration
+	Footer       string // the comments after the last declaration
+}
+
+// Conflict is a declaration changed differently in the text and in the blocks
+type Conflict struct {
+	Key    string
+	Text   string // empty when the declaration was removed from the text
+	Blocks string // empty when the declaration was removed from the blocks
+}
+
+type Result struct {
+	Source    string
+	Conflicts []Conflict
+}
+
+// the names the versions are given in the conflict markers and the diagnostics
+const (
+	BaseName   = "base"
+	TextName   = "text"
+	BlocksName = "blocks"
+)
+
+// Merge merges the source edited as text and the source regenerated from the edited blocks, both
+// made from the base source, the one last synced. The declarations are compared by their meaning,
+// a declaration kept unchanged in the blocks keeps the formatting and the comments of the text.
+func Merge(base string, text string, blocks string, options Options) (*Result, []*context.Diagnostic, error) {
+	// the source regenerated from the blocks has no component definitions, the ones of the text are used
+	textSource, textParser, diagnostics := split(TextName, text, options.parser(nil))
+	baseSource, _, baseDiagnostics := split(BaseName, base, options.parser(textParser))
+	blocksSource, _, blocksDiagnostics := split(BlocksName, blocks, options.parser(textParser))
+	diagnostics = append(append(diagnostics, baseDiagnostics...), blocksDiagnostics...)
+	if context.HasErrors(diagnostics) {
+		return nil, diagnostics, errors.New("the sources to merge must have no errors")
+	}
+	return merge(baseSource, textSource, blocksSource), diagnostics, nil
+}
+
+// parse is how a version of the source is parsed
+type parse func(fileName string, sourceCode string) ([]ast.Expr, *mistparser.LangParser, []*context.Diagnostic)
+
+// parser parses the sources with the components of the options, and those declared by another source
+func (o Options) parser(declared *mistparser.LangParser) parse {
+	return func(fileName string, sourceCode string) ([]ast.Expr, *mistparser.LangParser, []*context.Diagnostic) {
+		codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: fileName}
+		tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+		parser := mistparser.NewLangParser(true, tokens)
+		if o.Components != nil {
+			parser.Resolver.Components = o.Components
+		}
+		if o.Screen != nil {
+			parser.SetDesign(o.Screen)
+		}
+		if declared != nil {
+			parser.IncludeComponents(declared)
+		}
+		expressions, parseDiagnostics := parser.ParseAll()
+		return expressions, parser, append(diagnostics, parseDiagnostics...)
+	}
+}
+
+func merge(base *Source, text *Source, blocks *Source) *Result {
+	result := &Result{}
+	baseByKey, textByKey, blocksByKey := base.byKey(), text.byKey(), blocks.byKey()
+
+	var merged []string
+	emit := func(key string) {
+		inBase, inText, inBlocks := baseByKey[key], textByKey[key], blocksByKey[key]
+		codeSide := pick(codeOf(inBase), codeOf(inText), codeOf(inBlocks))
+		commentSide := pick(commentsOf(inBase), commentsOf(inText), commentsOf(inBlocks))
+		switch {
+		case codeSide == conflicting:
+			conflict := Conflict{Key: key, Text: textOf(inText), Blocks: textOf(inBlocks)}
+			result.Conflicts = append(result.Conflicts, conflict)
+			merged = append(merged, spaced(conflictText(conflict)))
+		case codeSide == fromText && inText == nil, codeSide == fromBlocks && inBlocks == nil:
+			// removed
+		case codeSide == fromText && commentSide != fromBlocks:
+			merged = append(merged, inText.Text)
+		case codeSide == fromBlocks && commentSide != fromText || inText == nil:
+			merged = append(merged, spaced(inBlocks.Text))
+		default:
+			// the code of a side along with the comments of the other one
+			code, comments := inText, inBlocks
+			if codeSide == fromBlocks {
+				code, comments = inBlocks, inText
+			}
+			merged = append(merged, spaced(withComments(code, comments)+"\n"))
+		}
+	}
+
+	emitted := map[string]bool{}
+	// the declarations are in the order of the text, the ones added in the blocks follow the
+	// declaration they follow in the blocks
+	pending := map[string][]string{}
+	var leading []string
+	previous := ""
+	for _, declaration := range blocks.Declarations {
+		if _, found := textByKey[declaration.Key]; !found {
+			if previous == "" {
+				leading = append(leading, declaration.Key)
+			} else {
+				pending[previous] = append(pending[previous], declaration.Key)
+			}
+			continue
+		}
+		previous = declaration.Key
+	}
+	var emitWithFollowers func(key string)
+	emitWithFollowers = func(key string) {
+		if emitted[key] {
+			return
+		}
+		emitted[key] = true
+		emit(key)
+		for _, follower := range pending[key] {
+			emitWithFollowers(follower)
+		}
+	}
+	for _, key := range leading {
+		emitWithFollowers(key)
+	}
+	for _, declaration := range text.Declarations {
+		emitWithFollowers(declaration.Key)
+	}
+	// the declarations removed from the text but edited in the blocks
+	for _, declaration := range base.Declarations {
+		emitWithFollowers(declaration.Key)
+	}
+
+	source := text.Header + strings.Join(merged, "") + text.Footer
+	if text.Header == "" {
+		// the first declaration is not separated from anything
+		source = strings.TrimLeft(source, "\n")
+	}
+	result.Source = source
+	return result
+}
+
+// spaced separates a declaration that comes from the blocks from the previous one by an empty line
+func spaced(declaration string) string {
+	return "\n" + strings.TrimLeft(declaration, "\n")
+}
+
+func textOf(declaration *Declaration) string {
+	if declaration == nil {
+		return ""
+	}
+	return strings.TrimLeft(declaration.Text, "\n")
+}
+
+func conflictText(conflict Conflict) string {
+	var builder strings.Builder
+	builder.WriteString("<<<<<<< " + TextName + "\n")
+	builder.WriteString(conflict.Text)
+	builder.WriteString("=======\n")
+	builder.WriteString(conflict.Blocks)
+	builder.WriteString(">>>>>>> " + BlocksName + "\n")
+	return builder.String()
+}
+
+type side int
+
+const (
+	fromText side = iota
+	fromBlocks
+	conflicting
+)
+
+// pick tells which side of a part of a declaration is kept, the text wins when both sides agree
+func pick(base string, text string, blocks string) side {
+	switch {
+	case text == blocks, blocks == base:
+		return fromText
+	case text == base:
+		return fromBlocks
+	}
+	return conflicting
+}
+
+// codeOf is the meaning of a declaration, its formatted code without the comments around it.
+// It is empty when there's no declaration.
+func codeOf(declaration *Declaration) string {
+	if declaration == nil {
+		return ""
+	}
+	meta := ast.MetaOf(declaration.Expr)
+	if meta == nil {
+		return declaration.Expr.String()
+	}
+	saved := *meta
+	meta.Comments, meta.Trailing, meta.After = nil, "", nil
+	code := ast.FormatStatement(declaration.Expr)
+	*meta = saved
+	return code
+}
+
+// commentsOf is the comments around a declaration
+func commentsOf(declaration *Declaration) string {
+	if declaration == nil {
+		return ""
+	}
+	meta := ast.MetaOf(declaration.Expr)
+	if meta == nil {
+		return ""
+	}
+	return strings.Join(meta.Comments, "\n") + "\x00" + meta.Trailing + "\x00" + strings.Join(meta.After, "\n")
+}
+
+// withComments formats the declaration with the comments of another one
+func withComments(declaration *Declaration, commented *Declaration) string {
+	meta, commentedMeta := ast.MetaOf(declaration.Expr), ast.MetaOf(commented.Expr)
+	if meta == nil || commentedMeta == nil {
+		return ast.FormatStatement(declaration.Expr)
+	}
+	saved := *meta
+	meta.Comments, meta.Trailing, meta.After = commentedMeta.Comments, commentedMeta.Trailing, commentedMeta.After
+	code := ast.FormatStatement(declaration.Expr)
+	*meta = saved
+	return code
+}
+
+func (s *Source) byKey() map[string]*Declaration {
+	declarations := make(map[string]*Declaration, len(s.Declarations))
+	for _, declaration := range s.Declarations {
+		declarations[declaration.Key] = declaration
+	}
+	return declarations
+}
+
+// split parses the source and cuts it into its declarations. A declaration takes the lines after the
+// previous one up to the end of its own, its comments and the empty lines above it included.
+func split(fileName string, sourceCode string, parse parse) (*Source, *mistparser.LangParser, []*context.Diagnostic) {
+	expressions, parser, diagnostics := parse(fileName, sourceCode)
+	source := &Source{}
+	lines := strings.SplitAfter(sourceCode, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	statements := map[ast.Expr]mistparser.LocatedExpr{}
+	for _, located := range parser.Located {
+		if located.Statement {
+			statements[located.Expr] = located
+		}
+	}
+	occurrences := map[string]int{}
+	// the lines before the first declaration, the component definitions among them
+	cut := 0
+	for i, expression := range expressions {
+		located, ok := statements[expression]
+		if !ok || located.End.Column < 1 {
+			continue
+		}
+		if i == 0 {
+			cut = headerEnd(parser, located.Start)
+			source.Header = strings.Join(lines[:min(cut, len(lines))], "")
+		}
+		end := min(located.End.Column, len(lines))
+		key := keyOf(expression)
+		occurrences[key]++
+		if occurrences[key] > 1 {
+			key += "#" + strconv.Itoa(occurrences[key])
+		}
+		source.Declarations = append(source.Declarations, &Declaration{
+			Key:  key,
+			Expr: expression,
+			Text: ensureNewline(strings.Join(lines[min(cut, end):end], "")),
+		})
+		cut = end
+	}
+	if cut < len(lines) {
+		rest := strings.Join(lines[cut:], "")
+		if len(source.Declarations) == 0 {
+			source.Header = rest
+		} else {
+			source.Footer = rest
+		}
+	}
+	return source, parser, diagnostics
+}
+
+// headerEnd is the number of lines taken by the component definitions, up to the line of the
+// token before the first declaration
+func headerEnd(parser *mistparser.LangParser, first *lex.Token) int {
+	index := slices.Index(parser.Tokens, first)
+	if index < 1 {
+		return 0
+	}
+	return min(parser.Tokens[index-1].Column, first.Column-1)
+}
+
+func ensureNewline(text string) string {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		return text + "\n"
+	}
+	return text
+}
+
+// keyOf names a top level statement by what it declares
+func keyOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *procedures.VoidProcedure:
+		return "func " + e.Name
+	case *procedures.RetProcedure:
+		return "func " + e.Name
+	case *components.Event:
+		return "when " + e.ComponentName + "." + e.Event
+	case *components.GenericEvent:
+		return "when any " + e.ComponentType + "." + e.Event
+	case *variables.Global:
+		return "global " + e.Name
+	case *procedures.Test:
+		return "test " + e.Name
+	}
+	return "statement"
+}
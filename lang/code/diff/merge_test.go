@@ -0,0 +1,74 @@
+package diff
+
+import (
+	"Falcon/code/parsers/mistparser/misttest"
+	"strings"
+	"testing"
+)
+
+// base is the source both sides are changed from
+const base = misttest.Program
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		blocks    string
+		expected  string
+		conflicts []string
+	}{
+		{"unchanged", base, base, base, nil},
+		{"edited as text", strings.Replace(base, `"hi"`, `"hello"`, 1), base,
+			strings.Replace(base, `"hi"`, `"hello"`, 1), nil},
+		{"edited as blocks", base, strings.Replace(base, "n = 1", "n = 2", 1),
+			strings.Replace(base, "n = 1", "n = 2", 1), nil},
+		{"comment of the text kept", strings.Replace(base, "func double", "// twice the value\nfunc double", 1),
+			strings.Replace(base, "x * 2", "x + x", 1),
+			// the code of the blocks along with the comments of the text is formatted
+			strings.Replace(base, "func double(x) = { x * 2 }", "// twice the value\nfunc double(x) = {\n  x + x\n}", 1), nil},
+		{"formatting of the text kept", strings.Replace(base, "{ x * 2 }", "{  x*2  }", 1), base,
+			strings.Replace(base, "{ x * 2 }", "{  x*2  }", 1), nil},
+		{"added as text", base + "\nfunc triple(x) = { x * 3 }\n", base,
+			base + "\nfunc triple(x) = { x * 3 }\n", nil},
+		{"removed from the blocks", base, "global n = 1\n\nfunc double(x) = { x * 2 }\n",
+			"global n = 1\n\nfunc double(x) = { x * 2 }\n", nil},
+		{"changed on both sides", strings.Replace(base, `"hi"`, `"hello"`, 1), strings.Replace(base, `"hi"`, `"hey"`, 1),
+			"", []string{"func greet"}},
+		{"removed as text and changed as blocks", "global n = 1\n\nfunc double(x) = { x * 2 }\n",
+			strings.Replace(base, `"hi"`, `"hey"`, 1), "", []string{"func greet"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, diagnostics, err := Merge(base, test.text, test.blocks, Options{})
+			if err != nil {
+				t.Fatalf("%v: %v", err, diagnostics)
+			}
+			keys := make([]string, len(result.Conflicts))
+			for i, conflict := range result.Conflicts {
+				keys[i] = conflict.Key
+			}
+			if strings.Join(keys, ", ") != strings.Join(test.conflicts, ", ") {
+				t.Fatalf("expected the conflicts %v but got %v", test.conflicts, keys)
+			}
+			if len(test.conflicts) > 0 {
+				if !strings.Contains(result.Source, "<<<<<<< "+TextName) || !strings.Contains(result.Source, ">>>>>>> "+BlocksName) {
+					t.Errorf("expected conflict markers in:\n%s", result.Source)
+				}
+				return
+			}
+			if result.Source != test.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", test.expected, result.Source)
+			}
+		})
+	}
+}
+
+func TestMergeNeedsValidSources(t *testing.T) {
+	_, diagnostics, err := Merge(base, "func broken( {\n", base, Options{})
+	if err == nil {
+		t.Fatal("expected the merge to fail")
+	}
+	if len(diagnostics) == 0 || diagnostics[0].File != TextName {
+		t.Errorf("expected the errors of the text but got %v", diagnostics)
+	}
+}
@@ -0,0 +1,338 @@
+package interp
+
+import (
+	"Falcon/code/ast/common"
+	"Falcon/code/context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (i *Interpreter) funcCall(f *common.FuncCall, s *scope) Value {
+	if isComponentCall(f.Name) {
+		return i.genericCall(f, s)
+	}
+	args := i.evalAll(f.Args, s)
+	i.where = f.Where
+	switch f.Name {
+	case "sqrt":
+		return math.Sqrt(i.number(args[0]))
+	case "abs":
+		return math.Abs(i.number(args[0]))
+	case "neg":
+		return -i.number(args[0])
+	case "log":
+		number := i.number(args[0])
+		if number <= 0 {
+			i.fail("Cannot take the logarithm of %", FormatNumber(number))
+		}
+		return math.Log(number)
+	case "exp":
+		return math.Exp(i.number(args[0]))
+	case "round":
+		// Scheme rounds halves to the even neighbour
+		return math.RoundToEven(i.number(args[0]))
+	case "ceil":
+		return math.Ceil(i.number(args[0]))
+	case "floor":
+		return math.Floor(i.number(args[0]))
+
+	// App Inventor measures angles in degrees
+	case "sin":
+		return math.Sin(toRadians(i.number(args[0])))
+	case "cos":
+		return math.Cos(toRadians(i.number(args[0])))
+	case "tan":
+		return math.Tan(toRadians(i.number(args[0])))
+	case "asin":
+		return toDegrees(math.Asin(i.number(args[0])))
+	case "acos":
+		return toDegrees(math.Acos(i.number(args[0])))
+	case "atan":
+		return toDegrees(math.Atan(i.number(args[0])))
+	case "aTan2":
+		return toDegrees(math.Atan2(i.number(args[0]), i.number(args[1])))
+	case "degrees":
+		// in the range [0, 360)
+		return floorMod(toDegrees(i.number(args[0])), 360)
+	case "radians":
+		// in the range (-π, π]
+		radians := floorMod(toRadians(i.number(args[0])), 2*math.Pi)
+		if radians > math.Pi {
+			radians -= 2 * math.Pi
+		}
+		return radians
+
+	case "decToHex":
+		return strconv.FormatInt(i.integer(args[0]), 16)
+	case "decToBin":
+		return strconv.FormatInt(i.integer(args[0]), 2)
+	case "hexToDec", "hexa":
+		return i.radix(args[0], 16)
+	case "binToDec", "bin":
+		return i.radix(args[0], 2)
+	case "octal":
+		return i.radix(args[0], 8)
+	case "dec":
+		return i.radix(args[0], 10)
+
+	case "randInt":
+		from, to := i.integer(args[0]), i.integer(args[1])
+		if from > to {
+			from, to = to, from
+		}
+		return float64(from + i.random.Int63n(to-from+1))
+	case "randFloat":
+		return i.random.Float64()
+	case "setRandSeed":
+		i.Seed(i.integer(args[0]))
+		return nil
+	case "min", "max":
+		result := i.number(args[0])
+		for _, arg := range args[1:] {
+			if f.Name == "min" {
+				result = math.Min(result, i.number(arg))
+			} else {
+				result = math.Max(result, i.number(arg))
+			}
+		}
+		return result
+	case "avgOf", "maxOf", "minOf", "geoMeanOf", "stdDevOf", "stdErrOf":
+		return i.mathOnList(f.Name, i.numbers(args[0]))
+	case "modeOf":
+		return i.modeOf(i.list(args[0]))
+	case "mod", "rem", "quot":
+		return i.divide(f.Name, i.number(args[0]), i.number(args[1]))
+	case "formatDecimal":
+		places := i.integer(args[1])
+		if places < 0 {
+			i.fail("Cannot format with % decimal places", strconv.FormatInt(places, 10))
+		}
+		return strconv.FormatFloat(i.number(args[0]), 'f', int(places), 64)
+
+	case "println":
+		fmt.Fprintln(i.Output, ToText(args[0]))
+		return nil
+	case "getStartValue", "getPlainStartText":
+		// nothing is passed to a headless screen
+		return ""
+	case "openScreen", "openScreenWithValue", "closeScreen", "closeScreenWithValue",
+		"closeApp", "closeScreenWithPlainText":
+		// there are no screens to switch between
+		return nil
+
+	case "assertEquals":
+		if !Equal(args[0], args[1]) {
+			panic(i.diagnostic(context.CodeAssertion, "Expected % but got %", quoted(args[0]), quoted(args[1])))
+		}
+		return nil
+	case "assertTrue":
+		if args[0] != true {
+			panic(i.diagnostic(context.CodeAssertion, "Expected true but got %", quoted(args[0])))
+		}
+		return nil
+	case "fire":
+		i.fire(i.componentValue(args[0]).Name, i.text(args[1]), args[2:])
+		return nil
+	case "stub":
+		i.stub(args[0], i.text(args[1]), args[2])
+		return nil
+
+	case "copyList":
+		return deepCopy(i.list(args[0]))
+	case "copyDict":
+		return deepCopy(i.dict(args[0]))
+	case "makeColor":
+		rgba := i.numbers(args[0])
+		if len(rgba) < 3 {
+			i.fail("Expected a list of red, green, blue and an optional alpha")
+		}
+		alpha := int64(255)
+		if len(rgba) > 3 {
+			alpha = int64(rgba[3])
+		}
+		return packColor(int64(rgba[0]), int64(rgba[1]), int64(rgba[2]), alpha)
+	case "splitColor":
+		return unpackColor(i.integer(args[0]))
+	}
+	i.fail("Cannot find function %()", f.Name)
+	panic("unreachable")
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// quoted shows the value in an assertion message, texts are put in quotes
+func quoted(value Value) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return ToText(value)
+}
+
+// floorMod takes the sign of the divisor, unlike math.Mod
+func floorMod(dividend float64, divisor float64) float64 {
+	return dividend - divisor*math.Floor(dividend/divisor)
+}
+
+func (i *Interpreter) radix(value Value, base int) Value {
+	text := strings.TrimSpace(i.text(value))
+	number, err := strconv.ParseInt(text, base, 64)
+	if err != nil {
+		i.fail("% is not a number of base %", strconv.Quote(text), strconv.Itoa(base))
+	}
+	return float64(number)
+}
+
+func (i *Interpreter) divide(operation string, dividend float64, divisor float64) Value {
+	if divisor == 0 {
+		i.fail("Division by zero")
+	}
+	switch operation {
+	case "mod":
+		return floorMod(dividend, divisor)
+	case "rem":
+		return math.Mod(dividend, divisor)
+	default:
+		return math.Trunc(dividend / divisor)
+	}
+}
+
+func (i *Interpreter) numbers(value Value) []float64 {
+	items := i.list(value).Items
+	numbers := make([]float64, len(items))
+	for k, item := range items {
+		numbers[k] = i.number(item)
+	}
+	return numbers
+}
+
+func (i *Interpreter) mathOnList(operation string, numbers []float64) Value {
+	if len(numbers) == 0 {
+		i.fail("Cannot take %() of an empty list", operation)
+	}
+	n := float64(len(numbers))
+	switch operation {
+	case "maxOf":
+		result := numbers[0]
+		for _, number := range numbers {
+			result = math.Max(result, number)
+		}
+		return result
+	case "minOf":
+		result := numbers[0]
+		for _, number := range numbers {
+			result = math.Min(result, number)
+		}
+		return result
+	case "geoMeanOf":
+		product := 1.0
+		for _, number := range numbers {
+			product *= number
+		}
+		return math.Pow(product, 1/n)
+	}
+	sum := 0.0
+	for _, number := range numbers {
+		sum += number
+	}
+	mean := sum / n
+	if operation == "avgOf" {
+		return mean
+	}
+	squares := 0.0
+	for _, number := range numbers {
+		squares += (number - mean) * (number - mean)
+	}
+	deviation := math.Sqrt(squares / n)
+	if operation == "stdDevOf" {
+		return deviation
+	}
+	return deviation / math.Sqrt(n)
+}
+
+// modeOf returns all the most frequent items, in the order they first appear
+func (i *Interpreter) modeOf(items *List) Value {
+	var distinct []Value
+	counts := map[string]int{}
+	best := 0
+	for _, item := range items.Items {
+		key := keyOf(item)
+		if counts[key] == 0 {
+			distinct = append(distinct, item)
+		}
+		counts[key]++
+		best = max(best, counts[key])
+	}
+	modes := NewList()
+	for _, item := range distinct {
+		if counts[keyOf(item)] == best {
+			modes.Items = append(modes.Items, item)
+		}
+	}
+	return modes
+}
+
+// deepCopy copies the nested lists and dictionaries too
+func deepCopy(value Value) Value {
+	switch v := value.(type) {
+	case *List:
+		copied := make([]Value, len(v.Items))
+		for k, item := range v.Items {
+			copied[k] = deepCopy(item)
+		}
+		return NewList(copied...)
+	case *Dict:
+		copied := NewDict()
+		for _, key := range v.keys {
+			copied.Set(key, deepCopy(v.values[keyOf(key)]))
+		}
+		return copied
+	}
+	return value
+}
+
+// compareValues is the default ordering: numbers come before texts, which come before the rest
+func compareValues(a Value, b Value) int {
+	rank := func(value Value) int {
+		if _, ok := ToNumber(value); ok {
+			return 0
+		}
+		if _, ok := value.(string); ok {
+			return 1
+		}
+		return 2
+	}
+	rankA, rankB := rank(a), rank(b)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+	switch rankA {
+	case 0:
+		x, _ := ToNumber(a)
+		y, _ := ToNumber(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case 1:
+		return strings.Compare(a.(string), b.(string))
+	}
+	return 0
+}
+
+func sortValues(items []Value) {
+	sort.SliceStable(items, func(x, y int) bool {
+		return compareValues(items[x], items[y]) < 0
+	})
+}
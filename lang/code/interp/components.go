@@ -0,0 +1,225 @@
+package interp
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/ast/components"
+	"Falcon/code/ast/variables"
+	"Falcon/design"
+	"strconv"
+	"strings"
+)
+
+// Component is a simulated component instance, such as Button1, its properties live in memory
+type Component struct {
+	Name       string
+	Type       string
+	Properties map[string]Value
+}
+
+// Property returns the value of the property, an empty text when it was never set
+func (c *Component) Property(name string) Value {
+	if value, ok := c.Properties[name]; ok {
+		return value
+	}
+	return ""
+}
+
+func (c *Component) SetProperty(name string, value Value) {
+	c.Properties[name] = value
+}
+
+// Invocation records a method called on a component
+type Invocation struct {
+	Component string
+	Method    string
+	Args      []Value
+}
+
+// MethodStub scripts what a component method does, its result is handed to the caller
+type MethodStub func(component *Component, args []Value) Value
+
+// LoadDesign creates the components of the screen design along with their designer properties
+func (i *Interpreter) LoadDesign(screen *design.Component) {
+	component := i.component(screen.Id, screen.ComponentType())
+	for property, value := range screen.Properties {
+		component.Properties[property] = designValue(value)
+	}
+	for k := range screen.Children {
+		i.LoadDesign(&screen.Children[k])
+	}
+}
+
+// designValue reads a designer property, booleans and &HAARRGGBB colors are converted
+func designValue(value string) Value {
+	switch strings.ToLower(value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if hex, ok := strings.CutPrefix(value, "&H"); ok {
+		if argb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return float64(int32(uint32(argb)))
+		}
+	}
+	return value
+}
+
+// Component returns the component of that name, created by the design or by the program
+func (i *Interpreter) Component(name string) (*Component, bool) {
+	component, ok := i.components[name]
+	return component, ok
+}
+
+// Stub scripts a component method. The target is either an instance method, Notifier1.ShowAlert,
+// or a method of all the components of a type, Notifier.ShowAlert. The instance stub is preferred.
+// Methods without a stub do nothing and give nothing back.
+func (i *Interpreter) Stub(target string, stub MethodStub) {
+	i.stubs[target] = stub
+}
+
+// Fire runs the handler of the component event, followed by the generic handler of its type
+func (i *Interpreter) Fire(componentName string, event string, args ...Value) error {
+	return i.protect(func() {
+		i.where = nil
+		i.fire(componentName, event, args)
+	})
+}
+
+func (i *Interpreter) fire(componentName string, event string, args []Value) {
+	handler, handled := i.events[componentName+"."+event]
+	component, ok := i.components[componentName]
+	if !ok {
+		if !handled {
+			i.fail("Cannot find component %", componentName)
+		}
+		component = i.component(componentName, handler.ComponentType)
+	}
+	if descriptor, known := i.Components.Component(component.Type); known {
+		if _, ok := descriptor.Event(event); !ok {
+			i.fail("Component type % has no event %", component.Type, event)
+		}
+	}
+	i.depth++
+	defer func() { i.depth-- }()
+	if i.depth > maxDepth {
+		i.fail("Too many nested events %.%, does the event fire itself?", componentName, event)
+	}
+	if handled {
+		i.runBody(handler.Body, i.bind(componentName+"."+event, handler.Parameters, args))
+	}
+	if generic, ok := i.genericEvents[component.Type+"."+event]; ok {
+		genericArgs := args
+		if len(generic.Parameters) == len(args)+2 {
+			// the handler names the component and notAlreadyHandled parameters too
+			genericArgs = append([]Value{component, !handled}, args...)
+		}
+		i.runBody(generic.Body, i.bind("any "+component.Type+"."+event, generic.Parameters, genericArgs))
+	}
+}
+
+// stub scripts a method of a test, the target is a component or the name of a component type
+// and the method gives back the result each time it is called
+func (i *Interpreter) stub(target Value, method string, result Value) {
+	name, ok := target.(string)
+	if component, isComponent := target.(*Component); isComponent {
+		name, ok = component.Name, true
+	}
+	if !ok {
+		i.fail("Expected a component or a component type to stub but got % %", TypeName(target), ToText(target))
+	}
+	i.Stub(name+"."+method, func(*Component, []Value) Value {
+		return result
+	})
+}
+
+func (i *Interpreter) invoke(component *Component, method string, args []Value) Value {
+	i.Invocations = append(i.Invocations, Invocation{Component: component.Name, Method: method, Args: args})
+	stub, ok := i.stubs[component.Name+"."+method]
+	if !ok {
+		stub, ok = i.stubs[component.Type+"."+method]
+	}
+	if !ok {
+		return nil
+	}
+	return stub(component, args)
+}
+
+func (i *Interpreter) componentValue(value Value) *Component {
+	component, ok := value.(*Component)
+	if !ok {
+		i.fail("Expected a component but got % %", TypeName(value), ToText(value))
+	}
+	return component
+}
+
+// every lists the components of the type, in the order they were created
+func (i *Interpreter) every(componentType string) *List {
+	all := NewList()
+	for _, component := range i.componentOrder {
+		if component.Type == componentType {
+			all.Items = append(all.Items, component)
+		}
+	}
+	return all
+}
+
+func (i *Interpreter) componentExpr(expr ast.Expr, s *scope) Value {
+	switch e := expr.(type) {
+	case *components.PropertyGet:
+		return i.component(e.ComponentName, e.ComponentType).Property(e.Property)
+	case *components.PropertySet:
+		value := i.eval(e.Value, s)
+		i.component(e.ComponentName, e.ComponentType).SetProperty(e.Property, value)
+		return nil
+	case *components.MethodCall:
+		args := i.evalAll(e.Args, s)
+		return i.invoke(i.component(e.ComponentName, e.ComponentType), e.Method, args)
+	case *components.GenericPropertyGet:
+		return i.componentValue(i.eval(e.Component, s)).Property(e.Property)
+	case *components.GenericPropertySet:
+		component := i.componentValue(i.eval(e.Component, s))
+		component.SetProperty(e.Property, i.eval(e.Value, s))
+		return nil
+	case *components.GenericMethodCall:
+		component := i.componentValue(i.eval(e.Component, s))
+		return i.invoke(component, e.Method, i.evalAll(e.Args, s))
+	case *components.EveryComponent:
+		return i.every(e.Type)
+	}
+	panic("unreachable")
+}
+
+// genericCall runs set(), get(), call(), vcall() and every()
+func (i *Interpreter) genericCall(f *common.FuncCall, s *scope) Value {
+	if f.Name == "every" {
+		componentType, ok := f.Args[0].(*variables.Get)
+		if !ok {
+			i.fail("Expected a component type for every()")
+		}
+		return i.every(componentType.Name)
+	}
+	args := i.evalAll(f.Args, s)
+	i.where = f.Where
+	component := i.componentValue(args[1])
+	member := i.text(args[2])
+	switch f.Name {
+	case "set":
+		component.SetProperty(member, args[3])
+		return nil
+	case "get":
+		return component.Property(member)
+	default:
+		return i.invoke(component, member, args[3:])
+	}
+}
+
+// isComponentCall tells if the function works on components
+func isComponentCall(name string) bool {
+	switch name {
+	case "set", "get", "call", "vcall", "every":
+		return true
+	}
+	return false
+}
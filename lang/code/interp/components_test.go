@@ -0,0 +1,67 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func property(t *testing.T, interpreter *Interpreter, component string, name string) Value {
+	t.Helper()
+	found, ok := interpreter.Component(component)
+	if !ok {
+		t.Fatalf("no component %s", component)
+	}
+	return found.Property(name)
+}
+
+func TestFire(t *testing.T) {
+	interpreter := load(t, `@Button { Button1 }
+@Label { Label1 }
+when Button1.Click {
+  Label1.Text = "clicked"
+}
+when any Button.Click(component, notAlreadyHandled) {
+  Label1.Visible = notAlreadyHandled
+}
+`)
+	if err := interpreter.Fire("Button1", "Click"); err != nil {
+		t.Fatal(err)
+	}
+	if text := property(t, interpreter, "Label1", "Text"); text != "clicked" {
+		t.Errorf("expected the text clicked but got %v", text)
+	}
+	if visible := property(t, interpreter, "Label1", "Visible"); visible != false {
+		t.Errorf("expected the generic handler to see the event handled but got %v", visible)
+	}
+	if err := interpreter.Fire("Button2", "Click"); err == nil {
+		t.Error("expected an error for an unknown component")
+	}
+	// Label1 exists with no handler, its events are checked against its type
+	err := interpreter.Fire("Label1", "Click")
+	if err == nil || !strings.Contains(err.Error(), "Component type Label has no event Click") {
+		t.Errorf("expected an error for an unknown event but got %v", err)
+	}
+	if err := interpreter.Fire("Button1", "LongClick"); err != nil {
+		t.Errorf("expected an event without handler to do nothing but got %v", err)
+	}
+}
+
+func TestStub(t *testing.T) {
+	interpreter := load(t, `@Clock { Clock1, Clock2 }
+@Label { Label1 }
+func check() {
+  Label1.Text = Clock1.FormatTime("a") _ Clock2.FormatTime("b")
+}
+`)
+	interpreter.Stub("Clock.FormatTime", func(*Component, []Value) Value { return "type" })
+	interpreter.Stub("Clock1.FormatTime", func(_ *Component, args []Value) Value { return args[0] })
+	if _, err := interpreter.Call("check"); err != nil {
+		t.Fatal(err)
+	}
+	if text := property(t, interpreter, "Label1", "Text"); text != "atype" {
+		t.Errorf("expected the instance stub then the type stub but got %v", text)
+	}
+	if len(interpreter.Invocations) != 2 || interpreter.Invocations[1].Component != "Clock2" {
+		t.Errorf("expected both calls recorded but got %v", interpreter.Invocations)
+	}
+}
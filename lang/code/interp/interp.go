@@ -0,0 +1,590 @@
+package interp
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/ast/components"
+	"Falcon/code/ast/control"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/ast/list"
+	"Falcon/code/ast/method"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/ast/variables"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/components/registry"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// maxDepth bounds the procedure calls in flight, a runaway recursion is reported
+// instead of overflowing the stack
+const maxDepth = 10000
+
+// Interpreter runs Falcon programs headlessly, following the App Inventor semantics
+type Interpreter struct {
+	// Output receives what println() prints
+	Output io.Writer
+
+	// Invocations records the component methods called, in order
+	Invocations []Invocation
+
+	// Components describes the component types, the events fired are checked against it
+	Components *registry.Registry
+
+	globals        map[string]Value
+	procedures     map[string]ast.Expr
+	events         map[string]*components.Event
+	genericEvents  map[string]*components.GenericEvent
+	components     map[string]*Component
+	componentOrder []*Component
+	stubs          map[string]MethodStub
+	random         *rand.Rand
+
+	// the token closest to the expression being evaluated, runtime errors point at it
+	where *lex.Token
+	depth int
+	// where the expressions parsed from the source start
+	starts map[ast.Expr]*lex.Token
+}
+
+type scope struct {
+	names  map[string]Value
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{names: map[string]Value{}, parent: parent}
+}
+
+// owner returns the scope that declares the name
+func (s *scope) owner(name string) *scope {
+	for curr := s; curr != nil; curr = curr.parent {
+		if _, ok := curr.names[name]; ok {
+			return curr
+		}
+	}
+	return nil
+}
+
+// breakSignal unwinds the body of the innermost loop
+type breakSignal struct{}
+
+func New() *Interpreter {
+	return &Interpreter{
+		Output:        os.Stdout,
+		Components:    registry.Default(),
+		globals:       map[string]Value{},
+		procedures:    map[string]ast.Expr{},
+		events:        map[string]*components.Event{},
+		genericEvents: map[string]*components.GenericEvent{},
+		components:    map[string]*Component{},
+		stubs:         map[string]MethodStub{},
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Locate tells where the expressions parsed from the source start, so that an error about a value,
+// such as an index out of bounds, points at the expression that gave it
+func (i *Interpreter) Locate(located []mistparser.LocatedExpr) *Interpreter {
+	if i.starts == nil {
+		i.starts = map[ast.Expr]*lex.Token{}
+	}
+	for _, aLocated := range located {
+		if _, found := i.starts[aLocated.Expr]; !found && aLocated.Expr != nil {
+			i.starts[aLocated.Expr] = aLocated.Start
+		}
+	}
+	return i
+}
+
+// Load defines the procedures and the event handlers of the program, then runs the top level
+// statements in order, global declarations included.
+func (i *Interpreter) Load(program []ast.Expr) error {
+	return i.protect(func() {
+		for _, expr := range program {
+			i.define(expr)
+		}
+		root := newScope(nil)
+		for _, expr := range program {
+			switch expr.(type) {
+			case *procedures.VoidProcedure, *procedures.RetProcedure, *procedures.Test,
+				*components.Event, *components.GenericEvent:
+				continue
+			}
+			i.eval(expr, root)
+		}
+	})
+}
+
+// Call invokes a procedure of the loaded program, the result is nil for void procedures
+func (i *Interpreter) Call(name string, args ...Value) (result Value, err error) {
+	err = i.protect(func() {
+		result = i.call(name, args)
+	})
+	return
+}
+
+// Eval evaluates an expression against the globals and procedures loaded so far
+func (i *Interpreter) Eval(expr ast.Expr) (result Value, err error) {
+	err = i.protect(func() {
+		result = i.eval(expr, newScope(nil))
+	})
+	return
+}
+
+// RunTest runs the body of the test against the program loaded, a failed assertion is reported
+// as a diagnostic of code assertion
+func (i *Interpreter) RunTest(test *procedures.Test) error {
+	return i.protect(func() {
+		i.runBody(test.Body, newScope(nil))
+	})
+}
+
+func (i *Interpreter) Global(name string) (Value, bool) {
+	value, ok := i.globals[name]
+	return value, ok
+}
+
+func (i *Interpreter) SetGlobal(name string, value Value) {
+	i.globals[name] = value
+}
+
+// HasProcedure tells if a procedure of that name was loaded
+func (i *Interpreter) HasProcedure(name string) bool {
+	_, ok := i.procedures[name]
+	return ok
+}
+
+// Seed makes randInt(), randFloat() and .random() reproducible
+func (i *Interpreter) Seed(seed int64) {
+	i.random = rand.New(rand.NewSource(seed))
+}
+
+// protect turns runtime errors raised while running fn into an error
+func (i *Interpreter) protect(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			i.depth = 0
+			switch r := r.(type) {
+			case *context.Diagnostic:
+				err = r
+			case breakSignal:
+				err = i.diagnostic(context.CodeRuntime, "break used outside of a loop")
+			default:
+				panic(r)
+			}
+		}
+	}()
+	fn()
+	return nil
+}
+
+func (i *Interpreter) diagnostic(code string, message string, args ...string) *context.Diagnostic {
+	if i.where == nil {
+		var unknown *context.CodeContext
+		return unknown.NewDiagnostic(context.SeverityError, code, context.Span{}, message, args...)
+	}
+	return i.where.Diagnostic(code, message, args...)
+}
+
+func (i *Interpreter) fail(message string, args ...string) {
+	panic(i.diagnostic(context.CodeRuntime, message, args...))
+}
+
+func (i *Interpreter) define(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *procedures.VoidProcedure:
+		i.procedures[e.Name] = e
+	case *procedures.RetProcedure:
+		i.procedures[e.Name] = e
+	case *components.Event:
+		i.events[e.ComponentName+"."+e.Event] = e
+	case *components.GenericEvent:
+		i.genericEvents[e.ComponentType+"."+e.Event] = e
+	}
+}
+
+func (i *Interpreter) call(name string, args []Value) Value {
+	procedure, ok := i.procedures[name]
+	if !ok {
+		i.fail("Cannot find procedure %()", name)
+	}
+	i.depth++
+	defer func() { i.depth-- }()
+	if i.depth > maxDepth {
+		i.fail("Too many nested calls to %(), is the recursion endless?", name)
+	}
+	switch p := procedure.(type) {
+	case *procedures.VoidProcedure:
+		i.runBody(p.Body, i.bind(name, p.Parameters, args))
+		return nil
+	case *procedures.RetProcedure:
+		return i.eval(p.Result, i.bind(name, p.Parameters, args))
+	}
+	panic("unreachable")
+}
+
+// bind creates the scope of a procedure call, procedures do not see the locals of the caller
+func (i *Interpreter) bind(name string, parameters []string, args []Value) *scope {
+	if len(parameters) != len(args) {
+		i.fail("Expected % args but got % for procedure %()",
+			strconv.Itoa(len(parameters)), strconv.Itoa(len(args)), name)
+	}
+	callScope := newScope(nil)
+	for k, parameter := range parameters {
+		callScope.names[parameter] = args[k]
+	}
+	return callScope
+}
+
+// runBody runs the statements one after the other, the value of the last one is returned
+func (i *Interpreter) runBody(body []ast.Expr, s *scope) Value {
+	var result Value
+	for _, expr := range body {
+		result = i.eval(expr, s)
+	}
+	return result
+}
+
+// iterate runs one round of a loop body, and tells if the loop was broken
+func (i *Interpreter) iterate(body []ast.Expr, s *scope) (broken bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(breakSignal); !ok {
+				panic(r)
+			}
+			broken = true
+		}
+	}()
+	i.runBody(body, s)
+	return false
+}
+
+// evalAt evaluates the expression, the errors about its value then point at it when it is located
+func (i *Interpreter) evalAt(expr ast.Expr, s *scope) Value {
+	value := i.eval(expr, s)
+	if start, ok := i.starts[expr]; ok {
+		i.where = start
+	}
+	return value
+}
+
+func (i *Interpreter) evalAll(exprs []ast.Expr, s *scope) []Value {
+	values := make([]Value, len(exprs))
+	for k, expr := range exprs {
+		values[k] = i.eval(expr, s)
+	}
+	return values
+}
+
+func (i *Interpreter) eval(expr ast.Expr, s *scope) Value {
+	switch e := expr.(type) {
+	case *fundamentals.Number:
+		return i.numberLiteral(e.Content)
+	case *fundamentals.Text:
+		return e.Content
+	case *fundamentals.Boolean:
+		return e.Value
+	case *fundamentals.Not:
+		return !i.truth(e.Expr, s)
+	case *fundamentals.Color:
+		i.where = e.Where
+		return i.hexColor(e.Hex)
+	case *fundamentals.List:
+		return NewList(i.evalAll(e.Elements, s)...)
+	case *fundamentals.Dictionary:
+		return i.dictionary(e, s)
+	case *fundamentals.Pair:
+		// outside a dictionary, a pair is a list of two
+		return NewList(i.eval(e.Key, s), i.eval(e.Value, s))
+	case *fundamentals.WalkAll:
+		return walkAll{}
+	case *fundamentals.HelperDropdown:
+		return e.Option
+	case *fundamentals.SmartBody:
+		return i.runBody(e.Body, s)
+	case *fundamentals.Component:
+		return i.component(e.Name, e.Type)
+
+	case *common.BinaryExpr:
+		return i.binary(e, s)
+	case *common.FuncCall:
+		return i.funcCall(e, s)
+	case *common.Question:
+		return i.question(e, s)
+	case *common.Transform:
+		// obfuscation only matters to the blocks
+		return i.eval(e.On, s)
+	case *common.EmptySocket:
+		i.fail("Cannot run an empty socket")
+
+	case *control.If:
+		for k, condition := range e.Conditions {
+			if i.truth(condition, s) {
+				return i.runBody(e.Bodies[k], s)
+			}
+		}
+		return i.runBody(e.ElseBody, s)
+	case *control.SimpleIf:
+		condition, then, elze := e.Branches()
+		if i.truth(condition, s) {
+			return i.runBody(then, s)
+		}
+		return i.runBody(elze, s)
+	case *control.While:
+		for i.truth(e.Condition, s) {
+			if i.iterate(e.Body, newScope(s)) {
+				break
+			}
+		}
+		return nil
+	case *control.For:
+		i.forLoop(e, s)
+		return nil
+	case *control.Each:
+		i.eachLoop(e, s)
+		return nil
+	case *control.EachPair:
+		i.eachPairLoop(e, s)
+		return nil
+	case *control.Break:
+		panic(breakSignal{})
+	case *control.Do:
+		i.runBody(e.Body, s)
+		return i.eval(e.Result, s)
+
+	case *list.Get:
+		return i.listGet(e, s)
+	case *list.Set:
+		i.listSet(e, s)
+		return nil
+	case *list.Transformer:
+		return i.transform(e, s)
+	case *method.Call:
+		return i.methodCall(e, s)
+
+	case *procedures.Call:
+		args := i.evalAll(e.Arguments, s)
+		return i.call(e.Name, args)
+	case *procedures.VoidProcedure, *procedures.RetProcedure:
+		i.define(e)
+		return nil
+	case *procedures.Test:
+		// tests only run through RunTest
+		return nil
+
+	case *variables.Get:
+		i.where = e.Where
+		return i.variable(e.Global, e.Name, s)
+	case *variables.Set:
+		i.assign(e.Global, e.Name, i.eval(e.Expr, s), s)
+		return nil
+	case *variables.Global:
+		i.globals[e.Name] = i.eval(e.Value, s)
+		return nil
+	case *variables.Var:
+		return i.runBody(e.Body, i.declare(e.Names, e.Values, s))
+	case *variables.SimpleVar:
+		return i.runBody(e.Body, i.declare([]string{e.Name}, []ast.Expr{e.Value}, s))
+	case *variables.VarResult:
+		return i.eval(e.Result, i.declare(e.Names, e.Values, s))
+
+	case *components.Event, *components.GenericEvent:
+		// events only run when they are fired
+		i.define(e)
+		return nil
+	case *components.PropertyGet, *components.PropertySet, *components.MethodCall,
+		*components.GenericPropertyGet, *components.GenericPropertySet, *components.GenericMethodCall,
+		*components.EveryComponent:
+		return i.componentExpr(e, s)
+	}
+	i.fail("Cannot run %", expr.String())
+	panic("unreachable")
+}
+
+// declare evaluates local variables in order, each one sees the ones declared before it
+func (i *Interpreter) declare(names []string, values []ast.Expr, s *scope) *scope {
+	local := newScope(s)
+	for k, name := range names {
+		local.names[name] = i.eval(values[k], local)
+	}
+	return local
+}
+
+func (i *Interpreter) variable(global bool, name string, s *scope) Value {
+	if !global {
+		if owner := s.owner(name); owner != nil {
+			return owner.names[name]
+		}
+	}
+	value, ok := i.globals[name]
+	if !ok {
+		i.fail("Cannot find variable %", name)
+	}
+	return value
+}
+
+func (i *Interpreter) assign(global bool, name string, value Value, s *scope) {
+	if !global {
+		if owner := s.owner(name); owner != nil {
+			owner.names[name] = value
+			return
+		}
+	}
+	if _, ok := i.globals[name]; !ok {
+		i.fail("Cannot find variable %", name)
+	}
+	i.globals[name] = value
+}
+
+func (i *Interpreter) truth(expr ast.Expr, s *scope) bool {
+	value := i.evalAt(expr, s)
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	// App Inventor accepts the text forms too
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	i.fail("Expected a boolean but got % %", TypeName(value), ToText(value))
+	panic("unreachable")
+}
+
+func (i *Interpreter) forLoop(f *control.For, s *scope) {
+	from := i.number(i.evalAt(f.From, s))
+	to := i.number(i.evalAt(f.To, s))
+	by := i.number(i.evalAt(f.By, s))
+	if by == 0 {
+		i.fail("The step of a for loop cannot be 0")
+	}
+	for n := from; (by > 0 && n <= to) || (by < 0 && n >= to); n += by {
+		loopScope := newScope(s)
+		loopScope.names[f.IName] = n
+		if i.iterate(f.Body, loopScope) {
+			break
+		}
+	}
+}
+
+func (i *Interpreter) eachLoop(e *control.Each, s *scope) {
+	items := i.list(i.evalAt(e.Iterable, s))
+	// the body may grow or shrink the list while we go over it
+	for k := 0; k < len(items.Items); k++ {
+		loopScope := newScope(s)
+		loopScope.names[e.IName] = items.Items[k]
+		if i.iterate(e.Body, loopScope) {
+			break
+		}
+	}
+}
+
+func (i *Interpreter) eachPairLoop(e *control.EachPair, s *scope) {
+	dict := i.dict(i.evalAt(e.Iterable, s))
+	for _, key := range dict.Keys() {
+		value, ok := dict.Get(key)
+		if !ok {
+			// deleted by an earlier round
+			continue
+		}
+		loopScope := newScope(s)
+		loopScope.names[e.KeyName] = key
+		loopScope.names[e.ValueName] = value
+		if i.iterate(e.Body, loopScope) {
+			break
+		}
+	}
+}
+
+func (i *Interpreter) dictionary(d *fundamentals.Dictionary, s *scope) *Dict {
+	dict := NewDict()
+	for _, element := range d.Elements {
+		pair, ok := element.(*fundamentals.Pair)
+		if !ok {
+			i.fail("Expected a key: value pair in the dictionary but got %", element.String())
+		}
+		key := i.eval(pair.Key, s)
+		dict.Set(key, i.eval(pair.Value, s))
+	}
+	return dict
+}
+
+func (i *Interpreter) component(name string, componentType string) *Component {
+	component, ok := i.components[name]
+	if !ok {
+		component = &Component{Name: name, Type: componentType, Properties: map[string]Value{}}
+		i.components[name] = component
+		i.componentOrder = append(i.componentOrder, component)
+	}
+	return component
+}
+
+func (i *Interpreter) listGet(g *list.Get, s *scope) Value {
+	items := i.list(i.evalAt(g.List, s))
+	index := i.index(i.evalAt(g.Index, s), len(items.Items))
+	return items.Items[index]
+}
+
+func (i *Interpreter) listSet(l *list.Set, s *scope) {
+	items := i.list(i.evalAt(l.List, s))
+	index := i.index(i.evalAt(l.Index, s), len(items.Items))
+	items.Items[index] = i.eval(l.Value, s)
+}
+
+// index turns a 1-based position into a slice index, making sure it is within the bounds
+func (i *Interpreter) index(value Value, size int) int {
+	number := i.number(value)
+	if number != float64(int(number)) || number < 1 || int(number) > size {
+		i.fail("Index % is out of bounds for a list of size %", ToText(value), strconv.Itoa(size))
+	}
+	return int(number) - 1
+}
+
+// number reads a number, numeric strings are numbers too
+func (i *Interpreter) number(value Value) float64 {
+	number, ok := ToNumber(value)
+	if !ok {
+		i.fail("Expected a number but got % %", TypeName(value), strconv.Quote(ToText(value)))
+	}
+	return number
+}
+
+func (i *Interpreter) text(value Value) string {
+	switch value.(type) {
+	case string, float64, bool:
+		return ToText(value)
+	}
+	i.fail("Expected a text but got % %", TypeName(value), ToText(value))
+	panic("unreachable")
+}
+
+func (i *Interpreter) list(value Value) *List {
+	items, ok := value.(*List)
+	if !ok {
+		i.fail("Expected a list but got % %", TypeName(value), ToText(value))
+	}
+	return items
+}
+
+func (i *Interpreter) dict(value Value) *Dict {
+	dict, ok := value.(*Dict)
+	if !ok {
+		i.fail("Expected a dictionary but got % %", TypeName(value), ToText(value))
+	}
+	return dict
+}
+
+func (i *Interpreter) numberLiteral(content string) float64 {
+	number, err := strconv.ParseFloat(content, 64)
+	if err != nil {
+		i.fail("Malformed number %", content)
+	}
+	return number
+}
@@ -0,0 +1,94 @@
+package interp
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/context"
+	"Falcon/code/parsers/mistparser/misttest"
+	"errors"
+	"io"
+	"testing"
+)
+
+// parse gives a fresh interpreter located on the source along with the program
+func parse(t *testing.T, sourceCode string) (*Interpreter, []ast.Expr) {
+	t.Helper()
+	langParser, expressions := misttest.Parse(t, sourceCode)
+	interpreter := New().Locate(langParser.Located)
+	interpreter.Output = io.Discard
+	return interpreter, expressions
+}
+
+// load runs the program on a fresh interpreter
+func load(t *testing.T, sourceCode string) *Interpreter {
+	t.Helper()
+	interpreter, expressions := parse(t, sourceCode)
+	if err := interpreter.Load(expressions); err != nil {
+		t.Fatal(err)
+	}
+	return interpreter
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"7 / 2", "3.5"},
+		{"2 ^ 10", "1024"},
+		{"10 % 4", "2"},
+		{"mod(-7, 3)", "2"},
+		{"quot(7, 2)", "3"},
+		{"formatDecimal(3.14159, 2)", "3.14"},
+		{`"a" _ 1 _ true`, "a1true"},
+		{`"hello".uppercase()`, "HELLO"},
+		{"[1, 2, 3][2]", "2"},
+		{"[1, 2] == [1, 2]", "true"},
+		{"[1, 2, 3].map { x -> x * 2 }", "(2 4 6)"},
+		{"[3, 1, 2].sort { a, b -> a < b }", "(1 2 3)"},
+		{"[1, 2, 3, 4].filter { x -> x ? even }", "(2 4)"},
+		{"[1, 2, 3].reduce(0) { x, sum -> x + sum }", "6"},
+		{`{"a": 1, "b": 2}`, `{"a":1,"b":2}`},
+		{"!true || 1 < 2", "true"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			interpreter := load(t, "global result = "+test.expression+"\n")
+			result, _ := interpreter.Global("result")
+			if text := ToText(result); text != test.expected {
+				t.Errorf("expected %s but got %s", test.expected, text)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrorsPointAtTheValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		column int
+	}{
+		{"fractional index", "println([1, 2][1.5])", 1, 16},
+		{"index of a set", "local l = [1]\nl[0] = 2", 2, 3},
+		{"number operand of a logic operator", "println(true && 1)", 1, 17},
+		{"number condition", "if (3) {\n  println(1)\n}", 1, 5},
+		{"text condition of a loop", "while (\"x\") {\n  break\n}", 1, 8},
+		{"number iterated", "for (x in 5) {\n  println(x)\n}", 1, 11},
+		{"text bound of a loop", "for (i: 1 .. \"a\" step 1) {\n  println(i)\n}", 1, 14},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter, expressions := parse(t, test.source)
+			err := interpreter.Load(expressions)
+			var diagnostic *context.Diagnostic
+			if !errors.As(err, &diagnostic) {
+				t.Fatalf("expected a runtime error but got %v", err)
+			}
+			if diagnostic.Line != test.line || diagnostic.Column != test.column {
+				t.Errorf("expected the error at %d:%d but got %d:%d: %s",
+					test.line, test.column, diagnostic.Line, diagnostic.Column, diagnostic.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package interp
+
+import (
+	"Falcon/code/ast/method"
+	"encoding/csv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func (i *Interpreter) methodCall(c *method.Call, s *scope) Value {
+	on := i.eval(c.On, s)
+	args := i.evalAll(c.Args, s)
+	i.where = c.Where
+	errorMessage, signature := method.TestSignature(c.Name, len(args))
+	if signature == nil {
+		i.fail(errorMessage)
+	}
+	switch signature.Module {
+	case "text":
+		return i.textMethod(c.Name, i.text(on), args)
+	case "list":
+		return i.listMethod(c.Name, i.list(on), args)
+	default:
+		return i.dictMethod(c.Name, i.dict(on), args)
+	}
+}
+
+func (i *Interpreter) textMethod(name string, text string, args []Value) Value {
+	switch name {
+	case "textLen":
+		return float64(utf8.RuneCountInString(text))
+	case "trim":
+		return strings.TrimSpace(text)
+	case "uppercase":
+		return strings.ToUpper(text)
+	case "lowercase":
+		return strings.ToLower(text)
+	case "startsWith":
+		// the 1-based position of the piece, 0 when it is absent
+		index := strings.Index(text, i.text(args[0]))
+		if index < 0 {
+			return 0.0
+		}
+		return float64(utf8.RuneCountInString(text[:index]) + 1)
+	case "contains":
+		return strings.Contains(text, i.text(args[0]))
+	case "containsAny":
+		for _, piece := range i.texts(args[0]) {
+			if strings.Contains(text, piece) {
+				return true
+			}
+		}
+		return false
+	case "containsAll":
+		for _, piece := range i.texts(args[0]) {
+			if !strings.Contains(text, piece) {
+				return false
+			}
+		}
+		return true
+	case "split":
+		return textList(strings.Split(text, i.text(args[0])))
+	case "splitAtFirst":
+		return textList(strings.SplitN(text, i.text(args[0]), 2))
+	case "splitAtAny":
+		return textList(i.separators(args[0]).Split(text, -1))
+	case "splitAtFirstOfAny":
+		return textList(i.separators(args[0]).Split(text, 2))
+	case "splitAtSpaces":
+		return textList(strings.Fields(text))
+	case "reverse":
+		runes := []rune(text)
+		for x, y := 0, len(runes)-1; x < y; x, y = x+1, y-1 {
+			runes[x], runes[y] = runes[y], runes[x]
+		}
+		return string(runes)
+	case "csvRowToList":
+		table := i.readCsv(text)
+		if len(table.Items) == 0 {
+			return NewList()
+		}
+		return table.Items[0]
+	case "csvTableToList":
+		return i.readCsv(text)
+	case "segment":
+		runes := []rune(text)
+		start := i.integer(args[0])
+		length := i.integer(args[1])
+		if start < 1 || length < 0 || start-1+length > int64(len(runes)) {
+			i.fail("Segment from % of length % is out of bounds for a text of length %",
+				ToText(args[0]), ToText(args[1]), strconv.Itoa(len(runes)))
+		}
+		return string(runes[start-1 : start-1+length])
+	case "replace":
+		return strings.ReplaceAll(text, i.text(args[0]), i.text(args[1]))
+	case "replaceFrom":
+		return i.replaceMappings(text, i.dict(args[0]), false)
+	case "replaceFromLongestFirst":
+		return i.replaceMappings(text, i.dict(args[0]), true)
+	}
+	i.fail("Cannot find method .%()", name)
+	panic("unreachable")
+}
+
+func textList(texts []string) *List {
+	items := make([]Value, len(texts))
+	for k, text := range texts {
+		items[k] = text
+	}
+	return NewList(items...)
+}
+
+func (i *Interpreter) texts(value Value) []string {
+	items := i.list(value).Items
+	texts := make([]string, len(items))
+	for k, item := range items {
+		texts[k] = i.text(item)
+	}
+	return texts
+}
+
+// separators matches any of the texts of the list
+func (i *Interpreter) separators(value Value) *regexp.Regexp {
+	pieces := i.texts(value)
+	if len(pieces) == 0 {
+		i.fail("Expected at least one text to split at")
+	}
+	for k, piece := range pieces {
+		pieces[k] = regexp.QuoteMeta(piece)
+	}
+	return regexp.MustCompile(strings.Join(pieces, "|"))
+}
+
+// replaceMappings replaces the keys of the dictionary with their values in a single pass,
+// a replacement is never replaced again
+func (i *Interpreter) replaceMappings(text string, mappings *Dict, longestFirst bool) string {
+	keys := make([]string, 0, mappings.Len())
+	for _, key := range mappings.Keys() {
+		keys = append(keys, i.text(key))
+	}
+	if longestFirst {
+		sort.SliceStable(keys, func(x, y int) bool { return len(keys[x]) > len(keys[y]) })
+	}
+	var builder strings.Builder
+	for position := 0; position < len(text); {
+		replaced := false
+		for _, key := range keys {
+			if key != "" && strings.HasPrefix(text[position:], key) {
+				value, _ := mappings.Get(key)
+				builder.WriteString(i.text(value))
+				position += len(key)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			_, size := utf8.DecodeRuneInString(text[position:])
+			builder.WriteString(text[position : position+size])
+			position += size
+		}
+	}
+	return builder.String()
+}
+
+func (i *Interpreter) readCsv(text string) *List {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		i.fail("Malformed CSV: %", err.Error())
+	}
+	table := NewList()
+	for _, record := range records {
+		table.Items = append(table.Items, textList(record))
+	}
+	return table
+}
+
+// csvRow quotes every field, as App Inventor does
+func csvRow(items *List) string {
+	fields := make([]string, len(items.Items))
+	for k, item := range items.Items {
+		fields[k] = `"` + strings.ReplaceAll(ToText(item), `"`, `""`) + `"`
+	}
+	return strings.Join(fields, ",")
+}
+
+func (i *Interpreter) listMethod(name string, items *List, args []Value) Value {
+	size := len(items.Items)
+	switch name {
+	case "listLen":
+		return float64(size)
+	case "add":
+		items.Items = append(items.Items, args...)
+		return nil
+	case "containsItem":
+		return indexOf(items, args[0]) > 0
+	case "indexOf":
+		return float64(indexOf(items, args[0]))
+	case "insert":
+		index := i.index(args[0], size+1)
+		items.Items = append(items.Items[:index], append([]Value{args[1]}, items.Items[index:]...)...)
+		return nil
+	case "remove":
+		index := i.index(args[0], size)
+		items.Items = append(items.Items[:index], items.Items[index+1:]...)
+		return nil
+	case "appendList":
+		items.Items = append(items.Items, i.list(args[0]).Items...)
+		return nil
+	case "lookupInPairs":
+		for _, item := range items.Items {
+			pair, ok := item.(*List)
+			if !ok || len(pair.Items) != 2 {
+				i.fail("Expected a list of pairs but found %", ToText(item))
+			}
+			if Equal(pair.Items[0], args[0]) {
+				return pair.Items[1]
+			}
+		}
+		return args[1]
+	case "join":
+		separator := i.text(args[0])
+		texts := make([]string, size)
+		for k, item := range items.Items {
+			texts[k] = ToText(item)
+		}
+		return strings.Join(texts, separator)
+	case "slice":
+		// from the first index up to, but not including, the second
+		from := i.index(args[0], size+1)
+		to := i.index(args[1], size+1)
+		if from > to {
+			i.fail("Cannot slice from % to %", ToText(args[0]), ToText(args[1]))
+		}
+		return NewList(append([]Value{}, items.Items[from:to]...)...)
+	case "random":
+		if size == 0 {
+			i.fail("Cannot pick a random item from an empty list")
+		}
+		return items.Items[i.random.Intn(size)]
+	case "reverseList":
+		reversed := make([]Value, size)
+		for k, item := range items.Items {
+			reversed[size-1-k] = item
+		}
+		return NewList(reversed...)
+	case "toCsvRow":
+		return csvRow(items)
+	case "toCsvTable":
+		rows := make([]string, size)
+		for k, item := range items.Items {
+			rows[k] = csvRow(i.list(item))
+		}
+		return strings.Join(rows, "\n")
+	case "sort":
+		sorted := NewList(append([]Value{}, items.Items...)...)
+		sortValues(sorted.Items)
+		return sorted
+	case "allButFirst", "allButLast":
+		if size == 0 {
+			i.fail("Cannot take %() of an empty list", name)
+		}
+		if name == "allButFirst" {
+			return NewList(append([]Value{}, items.Items[1:]...)...)
+		}
+		return NewList(append([]Value{}, items.Items[:size-1]...)...)
+	case "pairsToDict":
+		dict := NewDict()
+		for _, item := range items.Items {
+			pair, ok := item.(*List)
+			if !ok || len(pair.Items) != 2 {
+				i.fail("Expected a list of pairs but found %", ToText(item))
+			}
+			dict.Set(pair.Items[0], pair.Items[1])
+		}
+		return dict
+	}
+	i.fail("Cannot find method .%()", name)
+	panic("unreachable")
+}
+
+// indexOf returns the 1-based position of the item, 0 when it is absent
+func indexOf(items *List, item Value) int {
+	for k, existing := range items.Items {
+		if Equal(existing, item) {
+			return k + 1
+		}
+	}
+	return 0
+}
+
+func (i *Interpreter) dictMethod(name string, dict *Dict, args []Value) Value {
+	switch name {
+	case "dictLen":
+		return float64(dict.Len())
+	case "get":
+		if value, ok := dict.Get(args[0]); ok {
+			return value
+		}
+		return args[1]
+	case "set":
+		dict.Set(args[0], args[1])
+		return nil
+	case "delete":
+		dict.Delete(args[0])
+		return nil
+	case "getAtPath":
+		if value, ok := i.lookupPath(dict, i.list(args[0]).Items); ok {
+			return value
+		}
+		return args[1]
+	case "setAtPath":
+		i.setPath(dict, i.list(args[0]).Items, args[1])
+		return nil
+	case "containsKey":
+		_, ok := dict.Get(args[0])
+		return ok
+	case "mergeInto":
+		target := i.dict(args[0])
+		for _, key := range dict.Keys() {
+			value, _ := dict.Get(key)
+			target.Set(key, value)
+		}
+		return nil
+	case "walkTree":
+		return i.walkTree(dict, i.list(args[0]).Items)
+	case "keys":
+		return NewList(dict.Keys()...)
+	case "values":
+		return NewList(dict.Values()...)
+	case "toPairs":
+		pairs := NewList()
+		for _, key := range dict.Keys() {
+			value, _ := dict.Get(key)
+			pairs.Items = append(pairs.Items, NewList(key, value))
+		}
+		return pairs
+	}
+	i.fail("Cannot find method .%()", name)
+	panic("unreachable")
+}
+
+// child looks up a key of a dictionary or a 1-based position of a list
+func child(container Value, key Value) (Value, bool) {
+	switch c := container.(type) {
+	case *Dict:
+		return c.Get(key)
+	case *List:
+		number, ok := ToNumber(key)
+		index := int(number)
+		if !ok || float64(index) != number || index < 1 || index > len(c.Items) {
+			return nil, false
+		}
+		return c.Items[index-1], true
+	}
+	return nil, false
+}
+
+func (i *Interpreter) lookupPath(dict *Dict, path []Value) (Value, bool) {
+	var current Value = dict
+	for _, key := range path {
+		next, ok := child(current, key)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+func (i *Interpreter) setPath(dict *Dict, path []Value, value Value) {
+	if len(path) == 0 {
+		i.fail("Cannot set a value at an empty path")
+	}
+	container, ok := i.lookupPath(dict, path[:len(path)-1])
+	if !ok {
+		i.fail("Cannot find the path %", ToText(NewList(path...)))
+	}
+	key := path[len(path)-1]
+	switch c := container.(type) {
+	case *Dict:
+		c.Set(key, value)
+	case *List:
+		c.Items[i.index(key, len(c.Items))] = value
+	default:
+		i.fail("Cannot set a value inside % %", TypeName(container), ToText(container))
+	}
+}
+
+// walkTree collects every value at the path, walkAll stands for all the children of a node
+func (i *Interpreter) walkTree(dict *Dict, path []Value) Value {
+	nodes := []Value{dict}
+	for _, key := range path {
+		var next []Value
+		for _, node := range nodes {
+			if _, all := key.(walkAll); all {
+				switch n := node.(type) {
+				case *Dict:
+					next = append(next, n.Values()...)
+				case *List:
+					next = append(next, n.Items...)
+				}
+			} else if value, ok := child(node, key); ok {
+				next = append(next, value)
+			}
+		}
+		nodes = next
+	}
+	return NewList(nodes...)
+}
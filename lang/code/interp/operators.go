@@ -0,0 +1,176 @@
+package interp
+
+import (
+	"Falcon/code/ast/common"
+	"Falcon/code/lex"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (i *Interpreter) binary(b *common.BinaryExpr, s *scope) Value {
+	// logic operators short circuit
+	switch b.Operator {
+	case lex.LogicAnd:
+		for _, operand := range b.Operands {
+			if !i.truth(operand, s) {
+				return false
+			}
+		}
+		return true
+	case lex.LogicOr:
+		for _, operand := range b.Operands {
+			if i.truth(operand, s) {
+				return true
+			}
+		}
+		return false
+	}
+	operands := i.evalAll(b.Operands, s)
+	i.where = b.Where
+	switch b.Operator {
+	case lex.Plus:
+		sum := 0.0
+		for _, operand := range operands {
+			sum += i.number(operand)
+		}
+		return sum
+	case lex.Times:
+		product := 1.0
+		for _, operand := range operands {
+			product *= i.number(operand)
+		}
+		return product
+	case lex.Dash:
+		return i.number(operands[0]) - i.number(operands[1])
+	case lex.Slash:
+		divisor := i.number(operands[1])
+		if divisor == 0 {
+			i.fail("Division by zero")
+		}
+		return i.number(operands[0]) / divisor
+	case lex.Power:
+		return math.Pow(i.number(operands[0]), i.number(operands[1]))
+	case lex.BitwiseAnd, lex.BitwiseOr, lex.BitwiseXor:
+		return i.bitwise(b.Operator, operands)
+	case lex.Equals:
+		return Equal(operands[0], operands[1])
+	case lex.NotEquals:
+		return !Equal(operands[0], operands[1])
+	case lex.Underscore:
+		var builder strings.Builder
+		for _, operand := range operands {
+			builder.WriteString(ToText(operand))
+		}
+		return builder.String()
+	case lex.LessThan:
+		return i.number(operands[0]) < i.number(operands[1])
+	case lex.LessThanEqual:
+		return i.number(operands[0]) <= i.number(operands[1])
+	case lex.GreatThan:
+		return i.number(operands[0]) > i.number(operands[1])
+	case lex.GreaterThanEqual:
+		return i.number(operands[0]) >= i.number(operands[1])
+	case lex.TextEquals:
+		return i.text(operands[0]) == i.text(operands[1])
+	case lex.TextNotEquals:
+		return i.text(operands[0]) != i.text(operands[1])
+	case lex.TextLessThan:
+		return i.text(operands[0]) < i.text(operands[1])
+	case lex.TextGreaterThan:
+		return i.text(operands[0]) > i.text(operands[1])
+	}
+	i.fail("Unknown binary operator %", b.Operator.String())
+	panic("unreachable")
+}
+
+func (i *Interpreter) bitwise(operator lex.Type, operands []Value) Value {
+	result := i.integer(operands[0])
+	for _, operand := range operands[1:] {
+		switch operator {
+		case lex.BitwiseAnd:
+			result &= i.integer(operand)
+		case lex.BitwiseOr:
+			result |= i.integer(operand)
+		default:
+			result ^= i.integer(operand)
+		}
+	}
+	return float64(result)
+}
+
+func (i *Interpreter) integer(value Value) int64 {
+	number := i.number(value)
+	if number != math.Trunc(number) {
+		i.fail("Expected a whole number but got %", ToText(value))
+	}
+	return int64(number)
+}
+
+var (
+	base10Pattern = regexp.MustCompile(`^[0-9]+$`)
+	hexaPattern   = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	binPattern    = regexp.MustCompile(`^[01]+$`)
+)
+
+func (i *Interpreter) question(q *common.Question, s *scope) Value {
+	value := i.eval(q.On, s)
+	i.where = q.Where
+	switch q.Question {
+	case "number":
+		_, ok := ToNumber(value)
+		return ok
+	case "base10":
+		return base10Pattern.MatchString(ToText(value))
+	case "hexa":
+		return hexaPattern.MatchString(ToText(value))
+	case "bin":
+		return binPattern.MatchString(ToText(value))
+	case "text":
+		_, ok := value.(string)
+		return ok
+	case "list":
+		_, ok := value.(*List)
+		return ok
+	case "dict":
+		_, ok := value.(*Dict)
+		return ok
+	case "emptyText":
+		return i.text(value) == ""
+	case "emptyList":
+		return len(i.list(value).Items) == 0
+	case "even", "odd":
+		remainder := math.Mod(float64(i.integer(value)), 2)
+		return (remainder == 0) == (q.Question == "even")
+	}
+	i.fail("Unknown question ? %", q.Question)
+	panic("unreachable")
+}
+
+// hexColor reads #RRGGBB or #RRGGBBAA into the signed ARGB number App Inventor uses for colors
+func (i *Interpreter) hexColor(hex string) Value {
+	digits := strings.TrimPrefix(hex, "#")
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+	rgba, err := strconv.ParseUint(digits, 16, 32)
+	if len(digits) != 8 || err != nil {
+		i.fail("Malformed color %", hex)
+	}
+	return packColor(int64(rgba>>24), int64(rgba>>16&0xff), int64(rgba>>8&0xff), int64(rgba&0xff))
+}
+
+func packColor(red int64, green int64, blue int64, alpha int64) float64 {
+	return float64(int32(uint32(alpha&0xff)<<24 | uint32(red&0xff)<<16 | uint32(green&0xff)<<8 | uint32(blue&0xff)))
+}
+
+func unpackColor(color int64) *List {
+	argb := uint32(color)
+	return NewList(
+		float64(argb>>16&0xff),
+		float64(argb>>8&0xff),
+		float64(argb&0xff),
+		float64(argb>>24),
+	)
+}
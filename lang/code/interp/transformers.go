@@ -0,0 +1,86 @@
+package interp
+
+import (
+	"Falcon/code/ast/list"
+	"sort"
+)
+
+func (i *Interpreter) transform(t *list.Transformer, s *scope) Value {
+	items := i.list(i.eval(t.List, s))
+	args := i.evalAll(t.Args, s)
+	i.where = t.Where
+	errorMessage, signature := list.TestSignature(t.Name, len(args), len(t.Names))
+	if signature == nil {
+		i.fail(errorMessage)
+	}
+	// apply runs the lambda with its names bound to the values
+	apply := func(values ...Value) Value {
+		lambdaScope := newScope(s)
+		for k, name := range t.Names {
+			lambdaScope.names[name] = values[k]
+		}
+		return i.eval(t.Transformer, lambdaScope)
+	}
+	test := func(values ...Value) bool {
+		result := apply(values...)
+		b, ok := result.(bool)
+		if !ok {
+			i.where = t.Where
+			i.fail("Expected the .% { } lambda to give a boolean but got % %", t.Name, TypeName(result), ToText(result))
+		}
+		return b
+	}
+	switch t.Name {
+	case "map":
+		mapped := make([]Value, len(items.Items))
+		for k, item := range items.Items {
+			mapped[k] = apply(item)
+		}
+		return NewList(mapped...)
+	case "filter":
+		filtered := NewList()
+		for _, item := range items.Items {
+			if test(item) {
+				filtered.Items = append(filtered.Items, item)
+			}
+		}
+		return filtered
+	case "reduce":
+		answer := args[0]
+		for _, item := range items.Items {
+			answer = apply(item, answer)
+		}
+		return answer
+	case "sort":
+		sorted := append([]Value{}, items.Items...)
+		sort.SliceStable(sorted, func(x, y int) bool { return test(sorted[x], sorted[y]) })
+		return NewList(sorted...)
+	case "sortByKey":
+		keys := make([]Value, len(items.Items))
+		order := make([]int, len(items.Items))
+		for k, item := range items.Items {
+			keys[k] = apply(item)
+			order[k] = k
+		}
+		sort.SliceStable(order, func(x, y int) bool { return compareValues(keys[order[x]], keys[order[y]]) < 0 })
+		sorted := make([]Value, len(order))
+		for k, index := range order {
+			sorted[k] = items.Items[index]
+		}
+		return NewList(sorted...)
+	case "min", "max":
+		// the lambda tells if the first item precedes the second
+		if len(items.Items) == 0 {
+			i.fail("Cannot take the .% { } of an empty list", t.Name)
+		}
+		best := items.Items[0]
+		for _, item := range items.Items[1:] {
+			if (t.Name == "min" && test(item, best)) || (t.Name == "max" && test(best, item)) {
+				best = item
+			}
+		}
+		return best
+	}
+	i.fail("Unknown list lambda! .% { }", t.Name)
+	panic("unreachable")
+}
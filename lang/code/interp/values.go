@@ -0,0 +1,225 @@
+package interp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is anything a Falcon expression evaluates to:
+// float64, string, bool, *List, *Dict or nil for statements.
+type Value any
+
+// List is shared by reference, like the lists of App Inventor
+type List struct {
+	Items []Value
+}
+
+func NewList(items ...Value) *List {
+	return &List{Items: items}
+}
+
+// Dict is shared by reference and remembers the insertion order of its keys
+type Dict struct {
+	keys   []Value
+	values map[string]Value
+}
+
+func NewDict() *Dict {
+	return &Dict{values: map[string]Value{}}
+}
+
+// keyOf lets numeric keys match their text forms, "1" and 1 are the same key
+func keyOf(key Value) string {
+	return ToText(key)
+}
+
+func (d *Dict) Get(key Value) (Value, bool) {
+	value, ok := d.values[keyOf(key)]
+	return value, ok
+}
+
+func (d *Dict) Set(key Value, value Value) {
+	k := keyOf(key)
+	if _, ok := d.values[k]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[k] = value
+}
+
+func (d *Dict) Delete(key Value) {
+	k := keyOf(key)
+	if _, ok := d.values[k]; !ok {
+		return
+	}
+	delete(d.values, k)
+	for i, existing := range d.keys {
+		if keyOf(existing) == k {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+// Keys returns the keys in their insertion order
+func (d *Dict) Keys() []Value {
+	return append([]Value{}, d.keys...)
+}
+
+func (d *Dict) Values() []Value {
+	values := make([]Value, len(d.keys))
+	for i, key := range d.keys {
+		values[i] = d.values[keyOf(key)]
+	}
+	return values
+}
+
+// walkAll is the path element that matches every item of a list or a dictionary
+type walkAll struct{}
+
+// FormatNumber prints numbers the way App Inventor shows them, integers without a decimal point
+func FormatNumber(number float64) string {
+	if number == math.Trunc(number) && math.Abs(number) < 1e15 {
+		if number == 0 {
+			// no negative zero
+			return "0"
+		}
+		return strconv.FormatFloat(number, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// ToText converts a value to its textual form, lists look like (1 2 3)
+func ToText(value Value) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return FormatNumber(v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case *List:
+		items := make([]string, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = ToText(item)
+		}
+		return "(" + strings.Join(items, " ") + ")"
+	case *Dict:
+		pairs := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			pairs[i] = strconv.Quote(ToText(key)) + ":" + jsonText(v.values[keyOf(key)])
+		}
+		return "{" + strings.Join(pairs, ",") + "}"
+	case walkAll:
+		return "ALL"
+	case *Component:
+		return v.Name
+	}
+	return ""
+}
+
+func jsonText(value Value) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return ToText(value)
+}
+
+// ToNumber reads a number or a numeric string
+func ToNumber(value Value) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		return parseNumber(strings.TrimSpace(v))
+	}
+	return 0, false
+}
+
+func parseNumber(text string) (float64, bool) {
+	if text == "" {
+		return 0, false
+	}
+	// ParseFloat also takes Inf, NaN and hex floats, which are not numbers in App Inventor
+	for _, c := range text {
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' && c != 'e' && c != 'E' {
+			return 0, false
+		}
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	return number, err == nil
+}
+
+// Equal compares like App Inventor's = block, numeric strings equal their numbers
+func Equal(a Value, b Value) bool {
+	if x, ok := ToNumber(a); ok {
+		if y, ok := ToNumber(b); ok {
+			return x == y
+		}
+	}
+	switch x := a.(type) {
+	case *List:
+		y, ok := b.(*List)
+		if !ok || len(x.Items) != len(y.Items) {
+			return false
+		}
+		for i := range x.Items {
+			if !Equal(x.Items[i], y.Items[i]) {
+				return false
+			}
+		}
+		return true
+	case *Dict:
+		y, ok := b.(*Dict)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for _, key := range x.keys {
+			other, found := y.Get(key)
+			if !found || !Equal(x.values[keyOf(key)], other) {
+				return false
+			}
+		}
+		return true
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case *Component:
+		y, ok := b.(*Component)
+		return ok && x == y
+	}
+	return a == b
+}
+
+// TypeName names the type of the value for error messages
+func TypeName(value Value) string {
+	switch value.(type) {
+	case nil:
+		return "nothing"
+	case float64:
+		return "number"
+	case string:
+		return "text"
+	case bool:
+		return "boolean"
+	case *List:
+		return "list"
+	case *Dict:
+		return "dictionary"
+	case *Component:
+		return "component"
+	}
+	return "value"
+}
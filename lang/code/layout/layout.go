@@ -0,0 +1,209 @@
+package layout
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/parsers/blocklytomist"
+	"encoding/xml"
+	"regexp"
+	"strings"
+)
+
+// estimated sizes of the rendered blocks, in workspace pixels
+const (
+	rowHeight    = 28 // a block with a single row of fields and inputs
+	armHeight    = 16 // the bottom arm of a block holding statements
+	indentWidth  = 16 // the left arm of a block holding statements
+	charWidth    = 8
+	minWidth     = 60
+	blockSpacing = 30 // between two blocks of a column
+	columnGap    = 60
+	// ColumnHeight is the height after which a column is full
+	ColumnHeight = 1200
+)
+
+// Height estimates the rendered height of a block, along with the blocks plugged and attached to it.
+// Every value input takes a row of its own, as tall as the block plugged into it.
+func Height(block *ast.Block) int {
+	if block.Collapsed {
+		return rowHeight
+	}
+	height := 0
+	for i := range block.Values {
+		height += max(rowHeight, Height(&block.Values[i].Block))
+	}
+	height = max(rowHeight, height)
+	for _, statement := range block.Statements {
+		height += max(rowHeight, chainHeight(statement.Block)) + armHeight
+	}
+	return height
+}
+
+// chainHeight is the height of a block and of the blocks that follow it
+func chainHeight(block *ast.Block) int {
+	height := 0
+	for block != nil {
+		height += Height(block)
+		if block.Next == nil {
+			break
+		}
+		block = block.Next.Block
+	}
+	return height
+}
+
+// Width estimates the rendered width of a block from its type, its fields and its inputs
+func Width(block *ast.Block) int {
+	label := len(block.Type)
+	for _, field := range block.Fields {
+		label += len(field.Value) + 2
+	}
+	width := max(minWidth, label*charWidth/2)
+	widest := 0
+	for i := range block.Values {
+		widest = max(widest, Width(&block.Values[i].Block))
+	}
+	for _, statement := range block.Statements {
+		for next := statement.Block; next != nil; {
+			widest = max(widest, Width(next)+indentWidth)
+			if next.Next == nil {
+				break
+			}
+			next = next.Next.Block
+		}
+	}
+	return width + widest
+}
+
+// Arrange places the root blocks that have no position yet in columns, from left to right.
+// The blocks of the previous workspace that are found unchanged keep their positions, then the
+// edited declarations stay where they were, and the new blocks go to the right of them all.
+func Arrange(blocks []ast.Block, previous *Positions) {
+	left := 0
+	if previous != nil {
+		for i := range blocks {
+			if _, _, placed := blocks[i].Position(); !placed {
+				previous.restore(&blocks[i])
+			}
+		}
+		for i := range blocks {
+			if _, _, placed := blocks[i].Position(); !placed {
+				previous.restoreDeclaration(&blocks[i])
+			}
+		}
+	}
+	for i := range blocks {
+		if x, _, placed := blocks[i].Position(); placed {
+			left = max(left, x+Width(&blocks[i])+columnGap)
+		}
+	}
+	x, y, columnWidth := left, 0, 0
+	for i := range blocks {
+		block := &blocks[i]
+		if _, _, placed := block.Position(); placed {
+			continue
+		}
+		height := Height(block)
+		if y > 0 && y+height > ColumnHeight {
+			x, y, columnWidth = x+columnWidth+columnGap, 0, 0
+		}
+		block.SetPosition(x, y)
+		y += height + blockSpacing
+		columnWidth = max(columnWidth, Width(block))
+	}
+}
+
+// Positions remembers where the root blocks of a workspace were, by their content and
+// by the declaration they make
+type Positions struct {
+	byContent     map[string][]position
+	byDeclaration map[string]position
+}
+
+type position struct {
+	x, y int
+}
+
+// ReadPositions reads the positions of the root blocks of a Blockly XML workspace
+func ReadPositions(xmlContent string) (*Positions, error) {
+	var root ast.XmlRoot
+	decoder := xml.NewDecoder(strings.NewReader(xmlContent))
+	decoder.Strict = false
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	positions := &Positions{byContent: map[string][]position{}, byDeclaration: map[string]position{}}
+	for i := range root.Blocks {
+		block := &root.Blocks[i]
+		x, y, placed := block.Position()
+		if !placed {
+			continue
+		}
+		at := position{x, y}
+		if content, ok := canonical(block); ok {
+			positions.byContent[content] = append(positions.byContent[content], at)
+		}
+		if declaration := block.Declaration(); declaration != "" {
+			positions.byDeclaration[declaration] = at
+		}
+	}
+	return positions, nil
+}
+
+func (p *Positions) restore(block *ast.Block) {
+	content, ok := contentOf(block)
+	if !ok {
+		return
+	}
+	if found := p.byContent[content]; len(found) > 0 {
+		block.SetPosition(found[0].x, found[0].y)
+		p.byContent[content] = found[1:]
+		// the declaration is not free to be taken by another block anymore
+		delete(p.byDeclaration, block.Declaration())
+	}
+}
+
+func (p *Positions) restoreDeclaration(block *ast.Block) {
+	declaration := block.Declaration()
+	if at, ok := p.byDeclaration[declaration]; ok && declaration != "" {
+		block.SetPosition(at.x, at.y)
+		delete(p.byDeclaration, declaration)
+	}
+}
+
+// canonical brings a block of a workspace to the form Falcon generates it in, so that it compares
+// with the generated blocks whatever Blockly wrote, ids and attributes alike
+func canonical(block *ast.Block) (content string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			content, ok = "", false
+		}
+	}()
+	unplaced := *block
+	unplaced.X, unplaced.Y = nil, nil
+	source, err := xml.Marshal(ast.XmlRoot{Blocks: []ast.Block{unplaced}})
+	if err != nil {
+		return "", false
+	}
+	parser := blocklytomist.NewParser(string(source))
+	parser.KeepIds = true
+	expressions := parser.GenerateAST()
+	if len(expressions) != 1 {
+		return "", false
+	}
+	generated := ast.RootBlock(expressions[0])
+	return contentOf(&generated)
+}
+
+// the id attributes of the blocks, the quotes of the values are escaped
+var idAttribute = regexp.MustCompile(` id="[^"]*"`)
+
+// contentOf is the XML of a block, without its position and the ids of the blocks
+func contentOf(block *ast.Block) (string, bool) {
+	unplaced := *block
+	unplaced.X, unplaced.Y = nil, nil
+	content, err := xml.Marshal(unplaced)
+	if err != nil {
+		return "", false
+	}
+	return idAttribute.ReplaceAllString(string(content), ""), true
+}
@@ -0,0 +1,97 @@
+package layout
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/parsers/mistparser/misttest"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// positionsOf reads back the positions of blocks arranged before, as Blockly exports them
+func positionsOf(t *testing.T, blocks []ast.Block) *Positions {
+	t.Helper()
+	content, err := xml.Marshal(ast.XmlRoot{Blocks: blocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := ReadPositions(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return positions
+}
+
+func placement(t *testing.T, block *ast.Block) [2]int {
+	t.Helper()
+	x, y, placed := block.Position()
+	if !placed {
+		t.Fatalf("the block %s wasn't placed", block.Type)
+	}
+	return [2]int{x, y}
+}
+
+func TestArrange(t *testing.T) {
+	blocks := misttest.Blocks(t, misttest.Program)
+	Arrange(blocks, nil)
+	y := 0
+	for i := range blocks {
+		if at := placement(t, &blocks[i]); at != [2]int{0, y} {
+			t.Errorf("expected block %d at 0,%d but got %v", i, y, at)
+		}
+		y += Height(&blocks[i]) + blockSpacing
+	}
+
+	// a column holds the blocks up to its height, the others go to the next columns
+	height := Height(&misttest.Blocks(t, "println(1)\n")[0])
+	fitting := (ColumnHeight-height)/(height+blockSpacing) + 1
+	many := misttest.Blocks(t, strings.Repeat("println(1)\n", fitting+1))
+	Arrange(many, nil)
+	if x := placement(t, &many[len(many)-2])[0]; x != 0 {
+		t.Errorf("expected the block to fit the first column but it's at x %d", x)
+	}
+	if at := placement(t, &many[len(many)-1]); at[0] <= 0 || at[1] != 0 {
+		t.Errorf("expected the last block at the top of a second column but got %v", at)
+	}
+}
+
+// after marks a block expected to the right of the blocks placed before
+var after = [2]int{-1, -1}
+
+func TestArrangeKeepsPreviousPositions(t *testing.T) {
+	previous := misttest.Blocks(t, misttest.Program)
+	for i := range previous {
+		previous[i].SetPosition(100*i, 50)
+	}
+	right := 0
+	for i := range previous {
+		right = max(right, 100*i+Width(&previous[i])+columnGap)
+	}
+	tests := []struct {
+		name     string
+		source   string
+		expected [][2]int
+	}{
+		{"unchanged", misttest.Program, [][2]int{{0, 50}, {100, 50}, {200, 50}}},
+		{"reordered", "func double(x) = { x * 2 }\n\nglobal n = 1\n", [][2]int{{100, 50}, {0, 50}}},
+		{"declaration edited", strings.Replace(misttest.Program, `"hi"`, `"hello"`, 1), [][2]int{{0, 50}, {100, 50}, {200, 50}}},
+		{"declaration added", misttest.Program + "\nfunc triple(x) = { x * 3 }\n", [][2]int{{0, 50}, {100, 50}, {200, 50}, after}},
+		{"statement added", misttest.Program + "\nprintln(1)\n", [][2]int{{0, 50}, {100, 50}, {200, 50}, after}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := misttest.Blocks(t, test.source)
+			Arrange(blocks, positionsOf(t, previous))
+			for i, expected := range test.expected {
+				at := placement(t, &blocks[i])
+				if expected == after {
+					if at[0] < right {
+						t.Errorf("expected the new block %d to the right of the others but got %v", i, at)
+					}
+				} else if at != expected {
+					t.Errorf("expected block %d at %v but got %v", i, expected, at)
+				}
+			}
+		})
+	}
+}
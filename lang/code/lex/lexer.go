@@ -2,19 +2,24 @@ package lex
 
 import (
 	"Falcon/code/context"
-	"Falcon/code/sugar"
 	"strconv"
 	"strings"
 )
 
 type Lexer struct {
-	ctx        *context.CodeContext
-	source     string
-	sourceLen  int
-	currIndex  int
-	currColumn int
-	currRow    int
-	Tokens     []*Token
+	ctx         *context.CodeContext
+	source      string
+	sourceLen   int
+	currIndex   int
+	currColumn  int
+	currRow     int
+	Tokens      []*Token
+	Diagnostics []*context.Diagnostic
+
+	comments  []string // own line comments waiting for the next token
+	spaced    bool
+	lastLine  int // line of the last token or comment
+	lastToken *Token
 }
 
 func NewLexer(ctx *context.CodeContext) *Lexer {
@@ -29,26 +34,37 @@ func NewLexer(ctx *context.CodeContext) *Lexer {
 	}
 }
 
-func (l *Lexer) Lex() []*Token {
+// Lex tokenizes the whole source. Lexical errors do not stop the lexer, the offending
+// characters are skipped and reported through the returned diagnostics.
+func (l *Lexer) Lex() ([]*Token, []*context.Diagnostic) {
 	for l.notEOF() {
-		l.parse()
+		l.safeParse()
+	}
+	if len(l.comments) > 0 && l.lastToken != nil {
+		l.lastToken.After = l.comments
+		l.comments = nil
 	}
-	return l.Tokens
+	return l.Tokens, l.Diagnostics
+}
+
+func (l *Lexer) safeParse() {
+	defer func() {
+		if r := recover(); r != nil {
+			diagnostic, ok := r.(*context.Diagnostic)
+			if !ok {
+				panic(r)
+			}
+			l.Diagnostics = append(l.Diagnostics, diagnostic)
+		}
+	}()
+	l.parse()
 }
 
 func (l *Lexer) parse() {
 	c := l.next()
 
 	if c == '/' && l.consume('/') {
-		// comment, skip the current line
-		for l.notEOF() {
-			n := l.next()
-			if n == '\n' {
-				l.currColumn++
-				l.currRow = 0
-				break
-			}
-		}
+		l.comment()
 		return
 	}
 	if c == '\n' {
@@ -168,11 +184,32 @@ func (l *Lexer) parse() {
 		} else if l.isDigit() {
 			l.numeric()
 		} else {
+			l.skip()
 			l.error("Unexpected character '%'", string(c))
 		}
 	}
 }
 
+// comment reads a comment till the end of the line, it becomes trivia of the token
+// before it if on the same line, otherwise of the token after it
+func (l *Lexer) comment() {
+	line := l.currColumn
+	start := l.currIndex
+	for l.notEOF() && l.peek() != '\n' {
+		l.skip()
+	}
+	text := strings.TrimSpace(strings.TrimRight(l.source[start:l.currIndex], "\r"))
+	if l.lastToken != nil && l.lastToken.Column == line && l.lastToken.Trailing == "" {
+		l.lastToken.Trailing = text
+		return
+	}
+	if len(l.comments) == 0 {
+		l.spaced = l.lastLine > 0 && line-l.lastLine > 1
+	}
+	l.comments = append(l.comments, text)
+	l.lastLine = line
+}
+
 func (l *Lexer) createOp(op string) {
 	sToken, ok := Symbols[op]
 	if !ok {
@@ -211,13 +248,23 @@ func (l *Lexer) colorCode() {
 }
 
 func (l *Lexer) text() {
+	// an unterminated text is reported at its opening quote rather than at the end of the file
+	line, column := l.currColumn, l.currRow
 	var writer strings.Builder
 	for {
+		if l.isEOF() {
+			l.errorAt(line, column, "Unterminated text literal")
+		}
 		c := l.next()
 		if c == '"' {
 			break
 		}
-		if c == '\\' {
+		if c == '\n' {
+			// the tokens after a text written over several lines are on the lines that follow
+			l.currColumn++
+			l.currRow = 0
+		}
+		if c == '\\' && l.notEOF() {
 			// Only handle escaping of (")
 			e := l.peek()
 			if e == '"' || e == '\\' {
@@ -227,7 +274,6 @@ func (l *Lexer) text() {
 		}
 		writer.WriteByte(c)
 	}
-	println(len(writer.String()) == 0)
 	content := writer.String()
 	l.appendToken(&Token{
 		Context: l.ctx,
@@ -288,7 +334,16 @@ func (l *Lexer) numeric() {
 }
 
 func (l *Lexer) appendToken(token *Token) {
-	println(token.Debug())
+	if len(l.comments) > 0 {
+		token.Comments = l.comments
+		token.Spaced = l.spaced
+		token.Detached = token.Column-l.lastLine > 1
+		l.comments = nil
+	} else {
+		token.Spaced = l.lastLine > 0 && token.Column-l.lastLine > 1
+	}
+	l.lastLine = token.Column
+	l.lastToken = token
 	l.Tokens = append(l.Tokens, token)
 }
 
@@ -322,7 +377,13 @@ func (l *Lexer) eat(expect uint8) {
 }
 
 func (l *Lexer) error(message string, args ...string) {
-	panic("[line " + strconv.Itoa(l.currColumn) + "] " + sugar.Format(message, args...))
+	l.errorAt(l.currColumn, l.currRow, message, args...)
+}
+
+func (l *Lexer) errorAt(line int, column int, message string, args ...string) {
+	column = max(column, 1)
+	span := context.Span{Line: line, Column: column, EndColumn: column + 1}
+	panic(l.ctx.NewDiagnostic(context.SeverityError, context.CodeLexical, span, message, args...))
 }
 
 func (l *Lexer) consume(expect uint8) bool {
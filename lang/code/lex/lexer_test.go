@@ -0,0 +1,65 @@
+package lex
+
+import (
+	"Falcon/code/context"
+	"testing"
+)
+
+func lex(sourceCode string) ([]*Token, []*context.Diagnostic) {
+	return NewLexer(&context.CodeContext{SourceCode: &sourceCode, FileName: "test.mist"}).Lex()
+}
+
+func TestLexerDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		span    context.Span
+		message string
+	}{
+		{"unexpected character", "global x = 1 $ 2", context.Span{File: "test.mist", Line: 1, Column: 14, EndColumn: 15},
+			"Unexpected character '$'"},
+		{"unterminated text", "println(1)\nprintln(\"abc)\n", context.Span{File: "test.mist", Line: 2, Column: 9, EndColumn: 10},
+			"Unterminated text literal"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, diagnostics := lex(test.source)
+			if len(diagnostics) != 1 {
+				t.Fatalf("expected a diagnostic but got %v", diagnostics)
+			}
+			diagnostic := diagnostics[0]
+			if diagnostic.Span != test.span || diagnostic.Message != test.message {
+				t.Errorf("expected %q at %+v but got %q at %+v", test.message, test.span, diagnostic.Message, diagnostic.Span)
+			}
+			if diagnostic.Severity != context.SeverityError || diagnostic.Code != context.CodeLexical {
+				t.Errorf("expected a lexical error but got the %s %s", diagnostic.Code, diagnostic.Severity)
+			}
+		})
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	tokens, diagnostics := lex("global s = \"a\nbc\"\nprintln(1)")
+	if len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics %v", diagnostics)
+	}
+	expected := map[string]context.Span{
+		"a\nbc":   {File: "test.mist", Line: 2, Column: 1, EndColumn: 4},
+		"println": {File: "test.mist", Line: 3, Column: 1, EndColumn: 8},
+		"1":       {File: "test.mist", Line: 3, Column: 9, EndColumn: 10},
+	}
+	for _, token := range tokens {
+		if token.Content == nil {
+			continue
+		}
+		if span, ok := expected[*token.Content]; ok {
+			if token.Span() != span {
+				t.Errorf("expected %q at %+v but got %+v", *token.Content, span, token.Span())
+			}
+			delete(expected, *token.Content)
+		}
+	}
+	for content := range expected {
+		t.Errorf("no token %q", content)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"Falcon/code/context"
 	"Falcon/code/sugar"
 	"strconv"
+	"strings"
 )
 
 type Token struct {
@@ -14,6 +15,13 @@ type Token struct {
 	Type    Type
 	Flags   []Flag
 	Content *string
+
+	// comments around the token, without the leading //
+	Comments []string // on the lines above the token
+	Trailing string   // after the token on the same line
+	After    []string // at the end of the file, only set on the last token
+	Spaced   bool     // an empty line precedes the token or its comments
+	Detached bool     // an empty line separates the comments above from the token
 }
 
 func (t *Token) String() string {
@@ -34,21 +42,50 @@ func (t *Token) HasFlag(flag Flag) bool {
 }
 
 func (t *Token) Error(message string, args ...string) {
-	if t.Context != nil {
-		(*t.Context).ReportError(t.Column, t.Row, len(*t.Content), message, args...)
-	} else {
-		panic(sugar.Format(message, args...))
-	}
+	panic(t.Diagnostic(context.CodeSyntax, message, args...))
 }
 
 func (t *Token) BuildError(decorate bool, message string, args ...string) string {
 	if t.Context != nil {
-		return (*t.Context).BuildError(decorate, t.Column, t.Row, len(*t.Content), message, args...)
+		return (*t.Context).BuildError(decorate, t.Column, t.Row, t.width(), message, args...)
 	} else {
 		return sugar.Format(message, args...)
 	}
 }
 
+// Diagnostic creates an error diagnostic pointing at this token
+func (t *Token) Diagnostic(code string, message string, args ...string) *context.Diagnostic {
+	return t.Context.NewDiagnostic(context.SeverityError, code, t.Span(), message, args...)
+}
+
+// Span returns the source range covered by the token, fake tokens have an empty span
+func (t *Token) Span() context.Span {
+	if t.Column < 0 {
+		return context.Span{}
+	}
+	width := t.width()
+	span := context.Span{Line: t.Column, Column: t.Row - width + 1, EndColumn: t.Row + 1}
+	if t.Context != nil {
+		span.File = t.Context.FileName
+	}
+	return span
+}
+
+func (t *Token) width() int {
+	if t.Content == nil {
+		return 1
+	}
+	if t.Type == Text {
+		// a text written over several lines is measured on its last line, up to the closing quote
+		if last := strings.LastIndexByte(*t.Content, '\n'); last >= 0 {
+			return len(*t.Content) - last
+		}
+		// account for the surrounding quotes
+		return len(*t.Content) + 2
+	}
+	return len(*t.Content)
+}
+
 type StaticToken struct {
 	Type  Type
 	Flags []Flag
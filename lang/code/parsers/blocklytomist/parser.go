@@ -50,6 +50,9 @@ func (s *StatementMap) get(name string) []ast.Expr {
 
 type Parser struct {
 	xmlContent string
+	// KeepIds keeps the block ids as @id annotations, Blockly gives every block one
+	KeepIds bool
+	blocks  []ast.Block
 }
 
 func NewParser(xmlContent string) *Parser {
@@ -57,7 +60,13 @@ func NewParser(xmlContent string) *Parser {
 }
 
 func (p *Parser) GenerateAST() []ast.Expr {
-	return p.parseAllBlocks(p.decodeXML())
+	p.blocks = p.decodeXML()
+	return p.parseAllBlocks(p.blocks)
+}
+
+// Blocks returns the root blocks of the workspace, as decoded by GenerateAST
+func (p *Parser) Blocks() []ast.Block {
+	return p.blocks
 }
 
 func (p *Parser) decodeXML() []ast.Block {
@@ -75,16 +84,57 @@ func (p *Parser) decodeXML() []ast.Block {
 func (p *Parser) parseAllBlocks(allBlocks []ast.Block) []ast.Expr {
 	var parsedBlocks []ast.Expr
 	for i := range allBlocks {
-		parsedBlocks = append(parsedBlocks, p.parseBlock(allBlocks[i]))
+		parsedBlocks = append(parsedBlocks, p.parseStatement(allBlocks[i]))
 	}
 	return parsedBlocks
 }
 
+// parseStatement parses a statement block, the comments of the block and of the
+// blocks plugged into it are placed above the statement along with the state of the block.
+// The type annotations kept in the comment of a declaration and the comments of the end of its
+// lines are restored.
+func (p *Parser) parseStatement(block ast.Block) ast.Expr {
+	expr := p.parseBlock(block)
+	if block.Comment != nil {
+		block.Comment.Text = ast.RestoreTrailing(expr, ast.RestoreTypes(expr, block.Comment.Text))
+	}
+	if meta := ast.MetaOf(expr); meta != nil {
+		for _, text := range collectComments(block) {
+			meta.SetCommentText(text)
+		}
+		meta.State = ast.StateOf(block, p.KeepIds)
+	}
+	return expr
+}
+
+// parseValue parses a block plugged into an input along with its state, written as annotations
+// in front of the value
+func (p *Parser) parseValue(block ast.Block) ast.Expr {
+	expr := p.parseBlock(block)
+	if state := ast.StateOf(block, p.KeepIds); state.Annotations() != "" {
+		if meta := ast.MetaOf(expr); meta != nil {
+			meta.State = state
+		}
+	}
+	return expr
+}
+
+func collectComments(block ast.Block) []string {
+	var comments []string
+	if block.Comment != nil {
+		comments = append(comments, block.Comment.Text)
+	}
+	for _, value := range block.Values {
+		comments = append(comments, collectComments(value.Block)...)
+	}
+	return comments
+}
+
 func (p *Parser) singleExpr(block ast.Block) ast.Expr {
 	if len(block.Values) == 0 {
 		return &common.EmptySocket{}
 	}
-	return p.parseBlock(block.Values[0].Block)
+	return p.parseValue(block.Values[0].Block)
 }
 
 func (p *Parser) parseBlock(block ast.Block) ast.Expr {
@@ -622,7 +672,7 @@ func (p *Parser) variableSet(block ast.Block) ast.Expr {
 	if isGlobal {
 		varName = varName[len("global "):]
 	}
-	return variables.Set{Global: isGlobal, Name: varName, Expr: p.singleExpr(block)}
+	return &variables.Set{Global: isGlobal, Name: varName, Expr: p.singleExpr(block)}
 }
 
 func (p *Parser) variableGet(block ast.Block) ast.Expr {
@@ -1129,7 +1179,7 @@ func (p *Parser) makeStatementMap(allStatements []ast.Statement) StatementMap {
 func (p *Parser) recursiveParse(currBlock ast.Block) []ast.Expr {
 	var pParsed []ast.Expr
 	for {
-		pParsed = append(pParsed, p.parseBlock(currBlock))
+		pParsed = append(pParsed, p.parseStatement(currBlock))
 		if currBlock.Next == nil {
 			break
 		}
@@ -1149,7 +1199,7 @@ func (p *Parser) makeFieldMap(allFields []ast.Field) map[string]string {
 func (p *Parser) makeValueMap(allValues []ast.Value) ValueMap {
 	valueMap := make(map[string]ast.Expr, len(allValues))
 	for _, val := range allValues {
-		valueMap[val.Name] = p.parseBlock(val.Block)
+		valueMap[val.Name] = p.parseValue(val.Block)
 	}
 	return ValueMap{valueMap: valueMap}
 }
@@ -1157,7 +1207,7 @@ func (p *Parser) makeValueMap(allValues []ast.Value) ValueMap {
 func (p *Parser) fromVals(allValues []ast.Value) []ast.Expr {
 	arrBlocks := make([]ast.Expr, len(allValues))
 	for i := range allValues {
-		arrBlocks[i] = p.parseBlock(allValues[i].Block)
+		arrBlocks[i] = p.parseValue(allValues[i].Block)
 	}
 	return arrBlocks
 }
@@ -1166,7 +1216,7 @@ func (p *Parser) fromMinVals(allValues []ast.Value, minCount int) []ast.Expr {
 	size := max(minCount, len(allValues))
 	arrExprs := make([]ast.Expr, size)
 	for i := range allValues {
-		arrExprs[i] = p.parseBlock(allValues[i].Block)
+		arrExprs[i] = p.parseValue(allValues[i].Block)
 	}
 	for i := len(allValues); i < size; i++ {
 		arrExprs[i] = &common.EmptySocket{}
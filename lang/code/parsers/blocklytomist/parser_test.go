@@ -0,0 +1,90 @@
+package blocklytomist
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/parsers/mistparser/misttest"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const joinWorkspace = `<xml xmlns="https://developers.google.com/blockly/xml">
+  <block type="global_declaration" id="g1" collapsed="true" x="0" y="0">
+    <field name="NAME">x</field>
+    <value name="VALUE">
+      <block type="lists_join_with_separator" id="j1"%s>
+        <value name="LIST">
+          <block type="text" id="t1"><field name="TEXT">a</field></block>
+        </value>
+        <value name="SEPARATOR">
+          <block type="text" id="t2"><field name="TEXT">b</field></block>
+        </value>
+      </block>
+    </value>
+  </block>
+</xml>`
+
+func TestPluggedBlockState(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes string
+		source     string
+	}{
+		{"default state", "", `global x = "a".join("b")`},
+		{"external inputs", ` inline="false"`, `global x = @external ("a".join("b"))`},
+		{"collapsed and disabled", ` collapsed="true" disabled="true"`, `global x = @collapsed @disabled ("a".join("b"))`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workspace := strings.Replace(joinWorkspace, "%s", test.attributes, 1)
+			exprs := NewParser(workspace).GenerateAST()
+			if len(exprs) != 1 || !ast.MetaOf(exprs[0]).State.Collapsed {
+				t.Fatalf("the state of the statement wasn't kept")
+			}
+			source := ast.FormatStatement(exprs[0])
+			if source != "@collapsed\n"+test.source {
+				t.Errorf("expected the state in the source:\n%s\nbut got:\n%s", test.source, source)
+			}
+			// the blocks compiled from the source are in the state they were decompiled from
+			var expected ast.XmlRoot
+			if err := xml.Unmarshal([]byte(workspace), &expected); err != nil {
+				t.Fatal(err)
+			}
+			plugged := expected.Blocks[0].Values[0].Block
+			back := misttest.Blocks(t, source)[0].Values[0].Block
+			expectedState, backState := ast.StateOf(plugged, false), ast.StateOf(back, false)
+			if backState.Annotations() != expectedState.Annotations() {
+				t.Errorf("expected the plugged block in the state %q but got %q",
+					expectedState.Annotations(), backState.Annotations())
+			}
+		})
+	}
+}
+
+func TestCommentsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"comments above", "// the count\n// of the clicks\nglobal count = 0"},
+		{"comment at the end of the line", "global count = 0 // of the clicks"},
+		{"comments in a body", "func f() {\n  // first\n  println(1) // one\n  println(2) // two\n}"},
+		{"comments of locals", "func f() {\n  local x = 1 // x\n  local y = 2\n  local z = 3 // z\n  println(x + y + z)\n}"},
+		{"comment of a plugged block", "// total\nglobal total = 1 + 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, err := xml.Marshal(ast.XmlRoot{Blocks: misttest.Blocks(t, test.source)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			exprs := NewParser(string(content)).GenerateAST()
+			if len(exprs) != 1 {
+				t.Fatalf("expected a statement back but got %d", len(exprs))
+			}
+			if back := ast.FormatStatement(exprs[0]); back != test.source {
+				t.Errorf("expected the comments back:\n%s\nbut got:\n%s", test.source, back)
+			}
+		})
+	}
+}
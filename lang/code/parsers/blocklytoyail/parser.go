@@ -1,9 +1,1191 @@
 package blocklytoyail
 
+import (
+	"Falcon/code/ast"
+	"Falcon/components/registry"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// prefixes App Inventor gives to the names of the generated code
+const (
+	localTag     = "$"
+	globalTag    = "g$"
+	procedureTag = "p$"
+)
+
+const (
+	yailFalse = "#f"
+	yailTrue  = "#t"
+	emptyList = "(call-yail-primitive make-yail-list (*list-for-runtime* ) '() \"make a list\")"
+)
+
+type ValueMap struct {
+	valueMap map[string]string
+}
+
+func (v *ValueMap) getUnsafe(name string) (string, bool) {
+	value, ok := v.valueMap[name]
+	return value, ok
+}
+
+func (v *ValueMap) get(name string) string {
+	if value, ok := v.valueMap[name]; ok {
+		return value
+	}
+	return yailFalse
+}
+
+func (v *ValueMap) orElse(name string, otherwise string) string {
+	if value, ok := v.valueMap[name]; ok {
+		return value
+	}
+	return otherwise
+}
+
+type StatementMap struct {
+	statementMap map[string][]string
+}
+
+func (s *StatementMap) getUnsafe(name string) ([]string, bool) {
+	value, ok := s.statementMap[name]
+	return value, ok
+}
+
+func (s *StatementMap) get(name string) []string {
+	return s.statementMap[name]
+}
+
+// Parser generates YAIL, the Scheme code App Inventor compiles apps from, out of Blockly XML
 type Parser struct {
 	xmlContent string
+	// Components types the properties and the method arguments the runtime coerces, the default
+	// descriptors when nil
+	Components *registry.Registry
 }
 
 func NewParser(xmlContent string) *Parser {
 	return &Parser{xmlContent: xmlContent}
 }
+
+// GenerateYail returns the code of the top level blocks, separated by a blank line
+func (p *Parser) GenerateYail() string {
+	var builder strings.Builder
+	for i, code := range p.parseAllBlocks(p.decodeXML()) {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(code)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func (p *Parser) decodeXML() []ast.Block {
+	decoder := xml.NewDecoder(strings.NewReader(p.xmlContent))
+	decoder.Strict = false
+	decoder.DefaultSpace = ""
+
+	var root ast.XmlRoot
+	if err := decoder.Decode(&root); err != nil {
+		panic(err)
+	}
+	return root.Blocks
+}
+
+func (p *Parser) parseAllBlocks(allBlocks []ast.Block) []string {
+	var codes []string
+	for i := range allBlocks {
+		codes = append(codes, p.parseBlock(allBlocks[i]))
+	}
+	return codes
+}
+
+func (p *Parser) singleExpr(block ast.Block) string {
+	if len(block.Values) == 0 {
+		return yailFalse
+	}
+	return p.parseBlock(block.Values[0].Block)
+}
+
+func (p *Parser) parseBlock(block ast.Block) string {
+	switch block.Type {
+	case "controls_if":
+		return p.ctrlIf(block)
+	case "controls_forRange":
+		return p.ctrlForRange(block)
+	case "controls_forEach":
+		return "(foreach " + localTag + block.SingleField() + " " +
+			begin(p.optSingleBody(block)) + " " + p.singleExprOr(block, emptyList) + ")"
+	case "controls_for_each_dict":
+		return p.ctrlForEachDict(block)
+	case "controls_while":
+		return "(while " + p.singleExpr(block) + " " + begin(p.optSingleBody(block)) + ")"
+	case "controls_choose":
+		return p.ctrlChoose(block)
+	case "controls_do_then_return":
+		return "(begin" + indent(append(p.optSingleBody(block), p.singleExpr(block))) + ")"
+	case "controls_eval_but_ignore":
+		return "(begin " + p.singleExpr(block) + " \"ignored\")"
+	case "controls_openAnotherScreen":
+		return primitive("open-another-screen", "text", "open another screen", p.singleExpr(block))
+	case "controls_openAnotherScreenWithStartValue":
+		pVals := p.makeValueMap(block.Values)
+		return primitive("open-another-screen-with-start-value", "text any", "open another screen with start value",
+			pVals.get("SCREENNAME"), pVals.get("STARTVALUE"))
+	case "controls_getStartValue":
+		return primitive("get-start-value", "", "get start value")
+	case "controls_closeScreen":
+		return primitive("close-screen", "", "close screen")
+	case "controls_closeScreenWithValue":
+		return primitive("close-screen-with-value", "any", "close screen with value", p.singleExpr(block))
+	case "controls_closeApplication":
+		return primitive("close-application", "", "close application")
+	case "controls_getPlainStartText":
+		return primitive("get-plain-start-text", "", "get plain start text")
+	case "controls_closeScreenWithPlainText":
+		return primitive("close-screen-with-plain-text", "text", "close screen with plain text", p.singleExpr(block))
+	case "controls_break":
+		return "(*yail-break* #f)"
+
+	case "logic_boolean", "logic_true", "logic_false":
+		if block.SingleField() == "TRUE" {
+			return yailTrue
+		}
+		return yailFalse
+	case "logic_negate":
+		return primitive("yail-not", "boolean", "not", p.singleExpr(block))
+	case "logic_compare", "logic_operation", "logic_or":
+		return p.logicExpr(block)
+
+	case "text":
+		return quote(block.SingleField())
+	case "text_join":
+		args := p.fromMinVals(block.Values, 1)
+		return primitive("string-append", repeatType("text", len(args)), "join", args...)
+	case "text_length":
+		return primitive("string-length", "text", "length", p.singleExpr(block))
+	case "text_isEmpty":
+		return primitive("is-string-empty?", "text", "is text empty?", p.singleExpr(block))
+	case "text_trim":
+		return primitive("string-trim", "text", "trim", p.singleExpr(block))
+	case "text_reverse":
+		return primitive("string-reverse", "text", "reverse", p.singleExpr(block))
+	case "text_split_at_spaces":
+		return primitive("string-split-at-spaces", "text", "split at spaces", p.singleExpr(block))
+	case "text_compare":
+		return p.textCompare(block)
+	case "text_changeCase":
+		return p.textChangeCase(block)
+	case "text_starts_at":
+		return p.textStartsWith(block)
+	case "text_contains":
+		return p.textContains(block)
+	case "text_split":
+		return p.textSplit(block)
+	case "text_segment":
+		return p.textSegment(block)
+	case "text_replace_all":
+		return p.textReplace(block)
+	case "obfuscated_text":
+		// the text is generated in the clear
+		return quote(block.SingleField())
+	case "text_replace_mappings":
+		return p.textReplaceMap(block)
+	case "text_is_string":
+		return primitive("string?", "any", "is a string?", p.singleExpr(block))
+
+	case "math_number":
+		return block.SingleField()
+	case "math_compare", "math_bitwise":
+		return p.mathExpr(block)
+	case "math_add":
+		args := p.fromMinVals(block.Values, 2)
+		return primitive("+", repeatType("number", len(args)), "+", args...)
+	case "math_subtract":
+		return primitive("-", "number number", "-", p.fromMinVals(block.Values, 2)...)
+	case "math_multiply":
+		args := p.fromMinVals(block.Values, 2)
+		return primitive("*", repeatType("number", len(args)), "*", args...)
+	case "math_division":
+		return primitive("yail-divide", "number number", "/", p.fromMinVals(block.Values, 2)...)
+	case "math_power":
+		return primitive("expt", "number number", "^", p.fromMinVals(block.Values, 2)...)
+	case "math_random_int":
+		return p.mathRandom(block)
+	case "math_random_float":
+		return primitive("random-fraction", "", "random fraction")
+	case "math_random_set_seed":
+		return primitive("random-set-seed", "number", "random set seed", p.singleExpr(block))
+	case "math_number_radix":
+		return p.mathRadix(block)
+	case "math_on_list": // min() and max()
+		args := p.fromMinVals(block.Values, 1)
+		name := strings.ToLower(block.SingleField())
+		return primitive(name, repeatType("number", len(args)), name, args...)
+	case "math_on_list2":
+		return p.mathOnList2(block)
+	case "math_mode_of_list":
+		return primitive("mode", "list", "mode", p.singleExpr(block))
+	case "math_trig", "math_sin", "math_cos", "math_tan":
+		name := strings.ToLower(block.SingleField())
+		return primitive(name+"-degrees", "number", name, p.singleExpr(block))
+	case "math_single":
+		return p.mathSingle(block)
+	case "math_atan2":
+		pVals := p.makeValueMap(block.Values)
+		return primitive("atan2-degrees", "number number", "atan2", pVals.get("Y"), pVals.get("X"))
+	case "math_format_as_decimal":
+		pVals := p.makeValueMap(block.Values)
+		return primitive("format-as-decimal", "number number", "format as decimal",
+			pVals.get("NUM"), pVals.get("PLACES"))
+	case "math_divide":
+		return p.mathDivide(block)
+	case "math_is_a_number":
+		return p.mathIsNumber(block)
+	case "math_convert_number":
+		return p.mathConvertNumber(block)
+	case "math_convert_angles":
+		return p.mathConvertAngles(block)
+
+	case "lists_create_with":
+		args := p.fromMinVals(block.Values, 0)
+		return primitive("make-yail-list", repeatType("any", len(args)), "make a list", args...)
+	case "lists_add_items":
+		return p.listAddItem(block)
+	case "lists_is_in":
+		return p.listContainsItem(block)
+	case "lists_length":
+		return primitive("yail-list-length", "list", "length of list", p.singleExpr(block))
+	case "lists_is_empty":
+		return primitive("yail-list-empty?", "list", "is list empty?", p.singleExpr(block))
+	case "lists_pick_random_item":
+		return primitive("yail-list-pick-random", "list", "pick random item", p.singleExpr(block))
+	case "lists_position_in":
+		return p.listIndexOf(block)
+	case "lists_select_item":
+		return p.listSelectItem(block)
+	case "lists_insert_item":
+		return p.listInsertItem(block)
+	case "lists_replace_item":
+		return p.listReplaceItem(block)
+	case "lists_remove_item":
+		return p.listRemoveItem(block)
+	case "lists_copy":
+		return primitive("yail-list-copy", "list", "copy list", p.singleExpr(block))
+	case "lists_reverse":
+		return primitive("yail-list-reverse", "list", "reverse list", p.singleExpr(block))
+	case "lists_to_csv_row":
+		return primitive("yail-list-to-csv-row", "list", "list to csv row", p.singleExpr(block))
+	case "lists_to_csv_table":
+		return primitive("yail-list-to-csv-table", "list", "list to csv table", p.singleExpr(block))
+	case "lists_sort":
+		return primitive("yail-list-sort", "list", "sort", p.singleExpr(block))
+	case "lists_is_list":
+		return primitive("yail-list?", "any", "is a list?", p.singleExpr(block))
+	case "lists_from_csv_row":
+		return primitive("yail-list-from-csv-row", "text", "list from csv row", p.singleExpr(block))
+	case "lists_from_csv_table":
+		return primitive("yail-list-from-csv-table", "text", "list from csv table", p.singleExpr(block))
+	case "lists_but_first":
+		return primitive("yail-list-but-first", "list", "butFirst", p.singleExpr(block))
+	case "lists_but_last":
+		return primitive("yail-list-but-last", "list", "butLast", p.singleExpr(block))
+	case "lists_lookup_in_pairs":
+		return p.listLookupPairs(block)
+	case "lists_join_with_separator":
+		return p.listJoin(block)
+	case "lists_slice":
+		return p.listSlice(block)
+	case "lists_map":
+		return p.listMap(block)
+	case "lists_filter":
+		return p.listFilter(block)
+	case "lists_reduce":
+		return p.listReduce(block)
+	case "lists_sort_comparator":
+		return p.listSortComparator(block)
+	case "lists_sort_key":
+		return p.listSortKeyComparator(block)
+	case "lists_minimum_value":
+		return p.listTransCompare("mincomparator-nondest", block)
+	case "lists_maximum_value":
+		return p.listTransCompare("maxcomparator-nondest", block)
+	case "lists_append_list":
+		return p.listAppend(block)
+
+	case "pair":
+		return p.dictPair(block)
+	case "dictionaries_create_with":
+		args := p.fromMinVals(block.Values, 0)
+		return primitive("make-yail-dictionary", repeatType("pair", len(args)), "make a dictionary", args...)
+	case "dictionaries_lookup":
+		return p.dictLookup(block)
+	case "dictionaries_set_pair":
+		return p.dictSet(block)
+	case "dictionaries_delete_pair":
+		return p.dictRemove(block)
+	case "dictionaries_recursive_lookup":
+		return p.dictLookupPath(block)
+	case "dictionaries_recursive_set":
+		return p.dictSetPath(block)
+	case "dictionaries_getters":
+		return p.dictGetters(block)
+	case "dictionaries_is_key_in":
+		return p.dictHasKey(block)
+	case "dictionaries_length":
+		return primitive("yail-dictionary-length", "dictionary", "get a dictionary's length", p.singleExpr(block))
+	case "dictionaries_alist_to_dict":
+		return primitive("yail-dictionary-alist-to-dict", "list", "convert an alist to a dictionary", p.singleExpr(block))
+	case "dictionaries_dict_to_alist":
+		return primitive("yail-dictionary-dict-to-alist", "dictionary", "convert a dictionary to an alist", p.singleExpr(block))
+	case "dictionaries_copy":
+		return primitive("yail-dictionary-copy", "dictionary", "get a shallow copy of a dict", p.singleExpr(block))
+	case "dictionaries_combine_dicts":
+		return p.dictCombine(block)
+	case "dictionaries_walk_tree":
+		return p.dictWalkTree(block)
+	case "dictionaries_walk_all":
+		return "(static-field com.google.appinventor.components.runtime.util.YailDictionary 'ALL)"
+	case "dictionaries_is_dict":
+		return primitive("yail-dictionary?", "any", "check if something is a dictionary", p.singleExpr(block))
+
+	case "color_make_color":
+		return primitive("make-color", "list", "make a color", p.singleExpr(block))
+	case "color_split_color":
+		return primitive("split-color", "number", "split a color", p.singleExpr(block))
+
+	case "global_declaration":
+		return "(def " + globalTag + block.SingleField() + " " + p.singleExpr(block) + ")"
+	case "lexical_variable_get":
+		return p.variableGet(block)
+	case "lexical_variable_set":
+		return p.variableSet(block)
+	case "local_declaration_statement", "local_declaration_expression":
+		return p.variableSmts(block)
+
+	case "procedures_defnoreturn":
+		return p.voidProcedure(block)
+	case "procedures_defreturn":
+		return p.returnProcedure(block)
+	case "procedures_callnoreturn", "procedures_callreturn":
+		return p.procedureCall(block)
+
+	case "helpers_assets":
+		return quote(block.SingleField())
+	case "helpers_dropdown":
+		return "(static-field com.google.appinventor.components.common." + block.Mutation.Key + " " +
+			quote(block.SingleField()) + ")"
+
+	case "component_component_block":
+		return "(get-component " + block.SingleField() + ")"
+	case "component_set_get":
+		return p.componentProp(block)
+	case "component_event":
+		return p.componentEvent(block)
+	case "component_method":
+		return p.componentMethod(block)
+	case "component_all_component_block":
+		return "(get-all-components " + block.Mutation.ComponentType + ")"
+	default:
+		if strings.HasPrefix(block.Type, "color_") && len(block.Fields) > 0 {
+			return p.makeColor(block)
+		}
+		if strings.HasPrefix(block.Type, "helpers_") {
+			return quote(block.SingleField())
+		}
+		panic("Unsupported block type: " + block.Type)
+	}
+}
+
+func (p *Parser) componentMethod(block ast.Block) string {
+	if block.Mutation.IsGeneric {
+		pVals := p.makeValueMap(block.Values)
+		var callArgs []string
+		for i := 0; ; i++ {
+			aArg, ok := pVals.getUnsafe("ARG" + strconv.Itoa(i))
+			if !ok {
+				break
+			}
+			callArgs = append(callArgs, aArg)
+		}
+		return "(call-component-type-method " + pVals.get("COMPONENT") +
+			" '" + block.Mutation.ComponentType +
+			" '" + block.Mutation.MethodName +
+			" " + argList(callArgs) +
+			" '(" + p.parameterTypes(block.Mutation.ComponentType, block.Mutation.MethodName, len(callArgs)) + "))"
+	}
+	callArgs := p.fromVals(block.Values)
+	return "(call-component-method '" + block.Mutation.InstanceName +
+		" '" + block.Mutation.MethodName +
+		" " + argList(callArgs) +
+		" '(" + p.parameterTypes(block.Mutation.ComponentType, block.Mutation.MethodName, len(callArgs)) + "))"
+}
+
+func (p *Parser) componentEvent(block ast.Block) string {
+	var mutArgsNames []ast.Arg
+	if block.Mutation != nil {
+		mutArgsNames = block.Mutation.Args
+	}
+	paramNames := make([]string, len(mutArgsNames))
+	for i := range mutArgsNames {
+		paramNames[i] = localTag + mutArgsNames[i].Name
+	}
+	if block.Mutation.IsGeneric {
+		// generic handlers are also told which component fired and if a specific handler ran, the
+		// block may name them already
+		if len(paramNames) < 2 || paramNames[0] != localTag+"component" {
+			paramNames = append([]string{localTag + "component", localTag + "notAlreadyHandled"}, paramNames...)
+		}
+		return "(define-generic-event " + block.Mutation.ComponentType + " " + block.Mutation.EventName +
+			" (" + strings.Join(paramNames, " ") + ")" + indent(p.optSingleBody(block)) + ")"
+	}
+	return "(define-event " + block.Mutation.InstanceName + " " + block.Mutation.EventName +
+		"(" + strings.Join(paramNames, " ") + ")(set-this-form)" + indent(p.optSingleBody(block)) + ")"
+}
+
+func (p *Parser) componentProp(block ast.Block) string {
+	pFields := p.makeFieldMap(block.Fields)
+	property := pFields["PROP"]
+	isSet := block.Mutation.SetOrGet == "set"
+
+	if block.Mutation.IsGeneric {
+		pVals := p.makeValueMap(block.Values)
+		if isSet {
+			return "(set-and-coerce-property-and-check! " + pVals.get("COMPONENT") +
+				" '" + block.Mutation.ComponentType + " '" + property + " " + pVals.get("VALUE") +
+				" '" + p.propertyType(block.Mutation.ComponentType, property) + ")"
+		}
+		return "(get-property-and-check " + pVals.get("COMPONENT") +
+			" '" + block.Mutation.ComponentType + " '" + property + ")"
+	}
+
+	if isSet {
+		return "(set-and-coerce-property! '" + pFields["COMPONENT_SELECTOR"] + " '" + property +
+			" " + p.singleExpr(block) + " '" + p.propertyType(block.Mutation.ComponentType, property) + ")"
+	}
+	return "(get-property '" + pFields["COMPONENT_SELECTOR"] + " '" + property + ")"
+}
+
+func (p *Parser) descriptors() *registry.Registry {
+	if p.Components == nil {
+		return registry.Default()
+	}
+	return p.Components
+}
+
+// propertyType is the type the runtime coerces the value of a property to
+func (p *Parser) propertyType(componentType string, property string) string {
+	if component, found := p.descriptors().Component(componentType); found {
+		if blockProperty, found := component.Property(property); found {
+			return coercion(blockProperty.Type)
+		}
+	}
+	return "any"
+}
+
+// parameterTypes are the types the runtime coerces the arguments of a method to, any for the
+// methods the descriptors don't know
+func (p *Parser) parameterTypes(componentType string, method string, count int) string {
+	types := make([]string, count)
+	for i := range types {
+		types[i] = "any"
+	}
+	if component, found := p.descriptors().Component(componentType); found {
+		if aMethod, found := component.Method(method); found && len(aMethod.Params) == count {
+			for i, param := range aMethod.Params {
+				types[i] = coercion(param.Type)
+			}
+		}
+	}
+	return strings.Join(types, " ")
+}
+
+// coercion names a type of the descriptors the way the runtime does, the types it doesn't check are any
+func coercion(typeName string) string {
+	switch typeName {
+	case "text", "number", "boolean", "list", "dictionary", "component", "InstantInTime":
+		return typeName
+	}
+	return "any"
+}
+
+func (p *Parser) ctrlChoose(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return "(if " + pVals.get("TEST") + " " + pVals.get("THENRETURN") + " " + pVals.get("ELSERETURN") + ")"
+}
+
+func (p *Parser) ctrlForEachDict(block ast.Block) string {
+	pFields := p.makeFieldMap(block.Fields)
+	pair := localTag + "pair"
+	// the dictionary is walked as a list of key value pairs
+	bindings := "(let ((" + localTag + pFields["KEY"] + " " +
+		primitive("yail-list-get-item", "list number", "select list item", pair, "1") + ") (" +
+		localTag + pFields["VALUE"] + " " +
+		primitive("yail-list-get-item", "list number", "select list item", pair, "2") + "))" +
+		indent(p.optSingleBody(block)) + ")"
+	pairs := primitive("yail-dictionary-dict-to-alist", "dictionary", "convert a dictionary to an alist",
+		p.singleExpr(block))
+	return "(foreach " + pair + indent([]string{bindings}) + " " + pairs + ")"
+}
+
+func (p *Parser) ctrlForRange(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return "(forrange " + localTag + block.SingleField() + " " + begin(p.optSingleBody(block)) +
+		" " + pVals.get("START") + " " + pVals.get("END") + " " + pVals.get("STEP") + ")"
+}
+
+func (p *Parser) ctrlIf(block ast.Block) string {
+	conditions := p.fromVals(block.Values)
+	statementMap := p.makeStatementMap(block.Statements)
+
+	// the else-ifs are nested in the else branch of the previous condition
+	code := ""
+	if elseBody, ok := statementMap.getUnsafe("ELSE"); ok {
+		code = " " + begin(elseBody)
+	}
+	for i := len(conditions) - 1; i >= 0; i-- {
+		code = "(if " + conditions[i] + " " + begin(statementMap.get("DO"+strconv.Itoa(i))) + code + ")"
+		if i > 0 {
+			code = " " + code
+		}
+	}
+	return code
+}
+
+func (p *Parser) logicExpr(block ast.Block) string {
+	args := p.fromMinVals(block.Values, 2)
+	switch block.SingleField() {
+	case "EQ":
+		return primitive("yail-equal?", "any any", "=", args...)
+	case "NEQ":
+		return primitive("yail-not-equal?", "any any", "≠", args...)
+	case "AND":
+		return "(and-delayed " + strings.Join(args, " ") + ")"
+	case "OR":
+		return "(or-delayed " + strings.Join(args, " ") + ")"
+	default:
+		panic("Unknown Logic Compare operation: " + block.SingleField())
+	}
+}
+
+func (p *Parser) procedureCall(block ast.Block) string {
+	procedureName := block.SingleField()
+	args := p.fromVals(block.Values)
+	return "((get-var " + procedureTag + procedureName + ")" + prefixed(" ", args) + ")"
+}
+
+func (p *Parser) returnProcedure(block ast.Block) string {
+	procedureName := p.makeFieldMap(block.Fields)["NAME"]
+	return "(def (" + procedureTag + procedureName + p.parameters(block) + ") " + p.singleExpr(block) + ")"
+}
+
+func (p *Parser) voidProcedure(block ast.Block) string {
+	procedureName := p.makeFieldMap(block.Fields)["NAME"]
+	return "(def (" + procedureTag + procedureName + p.parameters(block) + ")" +
+		indent(p.optSingleBody(block)) + ")"
+}
+
+func (p *Parser) parameters(block ast.Block) string {
+	var mutArgs []ast.Arg
+	if block.Mutation != nil {
+		mutArgs = block.Mutation.Args
+	}
+	code := ""
+	for i := range mutArgs {
+		code += " " + localTag + mutArgs[i].Name
+	}
+	return code
+}
+
+func (p *Parser) variableSmts(block ast.Block) string {
+	numOfVars := len(block.Mutation.LocalNames)
+	fieldMap := p.makeFieldMap(block.Fields)
+	valueMap := p.makeValueMap(block.Values)
+
+	bindings := make([]string, numOfVars)
+	for i := 0; i < numOfVars; i++ {
+		bindings[i] = "(" + localTag + fieldMap["VAR"+strconv.Itoa(i)] + " " +
+			valueMap.get("DECL"+strconv.Itoa(i)) + ")"
+	}
+	code := "(let (" + strings.Join(bindings, " ") + ")"
+	if block.GetType() == "local_declaration_statement" {
+		return code + indent(p.optSingleBody(block)) + ")"
+	}
+	return code + " " + valueMap.get("RETURN") + ")"
+}
+
+func (p *Parser) variableSet(block ast.Block) string {
+	varName := block.SingleField()
+	if name, isGlobal := strings.CutPrefix(varName, "global "); isGlobal {
+		return "(set-var! " + globalTag + name + " " + p.singleExpr(block) + ")"
+	}
+	return "(set-lexical! " + localTag + varName + " " + p.singleExpr(block) + ")"
+}
+
+func (p *Parser) variableGet(block ast.Block) string {
+	varName := block.Fields[0].Name
+	if varName == "VAR" {
+		varName = block.SingleField()
+	}
+	if name, isGlobal := strings.CutPrefix(varName, "global "); isGlobal {
+		return "(get-var " + globalTag + name + ")"
+	}
+	return "(lexical-value " + localTag + varName + ")"
+}
+
+func (p *Parser) dictWalkTree(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-walk", "list any", "list by walking key path",
+		pVals.get("PATH"), pVals.get("DICT"))
+}
+
+func (p *Parser) dictCombine(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-combine-dicts", "dictionary dictionary", "combine 2 dictionaries",
+		pVals.get("DICT1"), pVals.get("DICT2"))
+}
+
+func (p *Parser) dictHasKey(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-is-key-in", "key dictionary", "is key in dict?",
+		pVals.get("KEY"), pVals.get("DICT"))
+}
+
+func (p *Parser) dictGetters(block ast.Block) string {
+	switch block.SingleField() {
+	case "KEYS":
+		return primitive("yail-dictionary-get-keys", "dictionary", "get a dictionary's keys", p.singleExpr(block))
+	case "VALUES":
+		return primitive("yail-dictionary-get-values", "dictionary", "get a dictionary's values", p.singleExpr(block))
+	default:
+		panic("Unknown DictGetters operation: " + block.SingleField())
+	}
+}
+
+func (p *Parser) dictSetPath(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-recursive-set", "list dictionary any", "set value for key path",
+		pVals.get("KEYS"), pVals.get("DICT"), pVals.get("VALUE"))
+}
+
+func (p *Parser) dictLookupPath(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-recursive-lookup", "dictionary list any", "dictionary lookup",
+		pVals.get("DICT"), pVals.get("KEYS"), pVals.get("NOTFOUND"))
+}
+
+func (p *Parser) dictRemove(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-delete-pair", "dictionary key", "delete dictionary pair",
+		pVals.get("DICT"), pVals.get("KEY"))
+}
+
+func (p *Parser) dictSet(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-set-pair", "key dictionary any", "set value for key in dict to value",
+		pVals.get("KEY"), pVals.get("DICT"), pVals.get("VALUE"))
+}
+
+func (p *Parser) dictLookup(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-dictionary-lookup", "key any any", "dictionary lookup",
+		pVals.get("KEY"), pVals.get("DICT"), pVals.get("NOTFOUND"))
+}
+
+func (p *Parser) dictPair(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("make-dictionary-pair", "key any", "make a pair", pVals.get("KEY"), pVals.get("VALUE"))
+}
+
+func (p *Parser) listAppend(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-append!", "list list", "append to list", pVals.get("LIST0"), pVals.get("LIST1"))
+}
+
+// listTransCompare generates the min and max transformers, that compare the items two by two
+func (p *Parser) listTransCompare(name string, block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	pFields := p.makeFieldMap(block.Fields)
+	return "(" + name + " " + localTag + pFields["VAR1"] + " " + localTag + pFields["VAR2"] +
+		" " + pVals.get("COMPARE") + " " + pVals.orElse("LIST", emptyList) + ")"
+}
+
+func (p *Parser) listSortKeyComparator(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return "(sortkey_nondest " + localTag + block.SingleField() + " " + pVals.get("KEY") +
+		" " + pVals.orElse("LIST", emptyList) + ")"
+}
+
+func (p *Parser) listSortComparator(block ast.Block) string {
+	return p.listTransCompare("sortcomparator_nondest", block)
+}
+
+func (p *Parser) listReduce(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	pFields := p.makeFieldMap(block.Fields)
+	return "(reduceovereach " + pVals.get("INITANSWER") + " " + localTag + pFields["VAR1"] +
+		" " + localTag + pFields["VAR2"] + " " + pVals.get("COMBINE") + " " + pVals.orElse("LIST", emptyList) + ")"
+}
+
+func (p *Parser) listFilter(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return "(filter_nondest " + localTag + block.SingleField() + " " + pVals.get("TEST") +
+		" " + pVals.orElse("LIST", emptyList) + ")"
+}
+
+func (p *Parser) listMap(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return "(map_nondest " + localTag + block.SingleField() + " " + pVals.get("TO") +
+		" " + pVals.orElse("LIST", emptyList) + ")"
+}
+
+func (p *Parser) listSlice(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-slices", "list number number", "slice list",
+		pVals.get("LIST"), pVals.get("INDEX1"), pVals.get("INDEX2"))
+}
+
+func (p *Parser) listJoin(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-join-with-separator", "list text", "join with separator",
+		pVals.get("LIST"), pVals.get("SEPARATOR"))
+}
+
+func (p *Parser) listLookupPairs(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-alist-lookup", "any list any", "lookup in pairs",
+		pVals.get("KEY"), pVals.get("LIST"), pVals.get("NOTFOUND"))
+}
+
+func (p *Parser) listRemoveItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-remove-item!", "list number", "remove list item",
+		pVals.get("LIST"), pVals.get("INDEX"))
+}
+
+func (p *Parser) listReplaceItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-set-item!", "list number any", "replace list item",
+		pVals.get("LIST"), pVals.get("NUM"), pVals.get("ITEM"))
+}
+
+func (p *Parser) listInsertItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-insert-item!", "list number any", "insert list item",
+		pVals.get("LIST"), pVals.get("INDEX"), pVals.get("ITEM"))
+}
+
+func (p *Parser) listSelectItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-get-item", "list number", "select list item", pVals.get("LIST"), pVals.get("NUM"))
+}
+
+func (p *Parser) listIndexOf(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-index", "any list", "index in list", pVals.get("ITEM"), pVals.get("LIST"))
+}
+
+func (p *Parser) listContainsItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("yail-list-member?", "any list", "is in list?", pVals.get("ITEM"), pVals.get("LIST"))
+}
+
+func (p *Parser) listAddItem(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	numElements := block.Mutation.ItemCount
+	args := []string{pVals.get("LIST")}
+	for i := 0; i < numElements; i++ {
+		args = append(args, pVals.get("ITEM"+strconv.Itoa(i)))
+	}
+	return primitive("yail-list-add-to-list!", "list "+repeatType("any", numElements), "add items to list", args...)
+}
+
+func (p *Parser) textReplaceMap(block ast.Block) string {
+	var name string
+	switch block.SingleField() {
+	case "LONGEST_STRING_FIRST":
+		name = "string-replace-mappings-longest-string"
+	case "DICTIONARY_ORDER":
+		name = "string-replace-mappings-dictionary"
+	default:
+		panic("Unknown Text Replace Map operation: " + block.SingleField())
+	}
+	pVals := p.makeValueMap(block.Values)
+	return primitive(name, "text dictionary", "replace with mappings", pVals.get("TEXT"), pVals.get("MAPPINGS"))
+}
+
+func (p *Parser) textSegment(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("string-substring", "text number number", "segment",
+		pVals.get("TEXT"), pVals.get("START"), pVals.get("LENGTH"))
+}
+
+func (p *Parser) textReplace(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("string-replace-all", "text text text", "replace all",
+		pVals.get("TEXT"), pVals.get("SEGMENT"), pVals.get("REPLACEMENT"))
+}
+
+func (p *Parser) textSplit(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	switch block.SingleField() {
+	case "SPLIT":
+		return primitive("string-split", "text text", "split", pVals.get("TEXT"), pVals.get("AT"))
+	case "SPLITATFIRST":
+		return primitive("string-split-at-first", "text text", "split at first", pVals.get("TEXT"), pVals.get("AT"))
+	case "SPLITATANY":
+		return primitive("string-split-at-any", "text list", "split at any", pVals.get("TEXT"), pVals.get("AT"))
+	case "SPLITATFIRSTOFANY":
+		return primitive("string-split-at-first-of-any", "text list", "split at first of any",
+			pVals.get("TEXT"), pVals.get("AT"))
+	default:
+		panic("Unsupported Text Split operation: " + block.SingleField())
+	}
+}
+
+func (p *Parser) textContains(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	operation := "CONTAINS"
+	if len(block.Fields) > 0 {
+		operation = block.SingleField()
+	}
+	switch operation {
+	case "CONTAINS":
+		return primitive("string-contains", "text text", "contains", pVals.get("TEXT"), pVals.get("PIECE"))
+	case "CONTAINS_ANY":
+		return primitive("string-contains-any", "text list", "contains any", pVals.get("TEXT"), pVals.get("PIECE"))
+	case "CONTAINS_ALL":
+		return primitive("string-contains-all", "text list", "contains all", pVals.get("TEXT"), pVals.get("PIECE"))
+	default:
+		panic("Unsupported Text Contains operation: " + block.SingleField())
+	}
+}
+
+func (p *Parser) textStartsWith(block ast.Block) string {
+	pVals := p.makeValueMap(block.Values)
+	return primitive("string-starts-at", "text text", "starts at", pVals.get("TEXT"), pVals.get("PIECE"))
+}
+
+func (p *Parser) textChangeCase(block ast.Block) string {
+	switch block.SingleField() {
+	case "UPCASE":
+		return primitive("string-to-upper-case", "text", "upcase", p.singleExpr(block))
+	case "DOWNCASE":
+		return primitive("string-to-lower-case", "text", "downcase", p.singleExpr(block))
+	default:
+		panic("Unsupported Text Change Case operation type: " + block.SingleField())
+	}
+}
+
+func (p *Parser) textCompare(block ast.Block) string {
+	var name string
+	switch block.SingleField() {
+	case "EQUAL":
+		name = "string=?"
+	case "NEQ":
+		name = "string-not-equal?"
+	case "LT":
+		name = "string<?"
+	case "GT":
+		name = "string>?"
+	default:
+		panic("Unknown Text Compare operation: " + block.SingleField())
+	}
+	return primitive(name, "text text", "compare texts", p.fromMinVals(block.Values, 2)...)
+}
+
+func (p *Parser) mathConvertAngles(block ast.Block) string {
+	switch block.SingleField() {
+	case "RADIANS_TO_DEGREES":
+		return primitive("radians->degrees", "number", "convert radians to degrees", p.singleExpr(block))
+	case "DEGREES_TO_RADIANS":
+		return primitive("degrees->radians", "number", "convert degrees to radians", p.singleExpr(block))
+	default:
+		panic("Unknown MathConvertAngles type: " + block.SingleField())
+	}
+}
+
+func (p *Parser) mathConvertNumber(block ast.Block) string {
+	switch block.SingleField() {
+	case "DEC_TO_HEX":
+		return primitive("math-convert-dec-hex", "number", "convert Dec to Hex", p.singleExpr(block))
+	case "DEC_TO_BIN":
+		return primitive("math-convert-dec-bin", "number", "convert Dec to Bin", p.singleExpr(block))
+	case "HEX_TO_DEC":
+		return primitive("math-convert-hex-dec", "text", "convert Hex to Dec", p.singleExpr(block))
+	case "BIN_TO_DEC":
+		return primitive("math-convert-bin-dec", "text", "convert Bin to Dec", p.singleExpr(block))
+	default:
+		panic("Unknown MathConvertNumber type: " + block.SingleField())
+	}
+}
+
+func (p *Parser) mathIsNumber(block ast.Block) string {
+	switch block.SingleField() {
+	case "NUMBER":
+		return primitive("is-number?", "any", "is a number?", p.singleExpr(block))
+	case "BINARY":
+		return primitive("is-binary?", "text", "is binary?", p.singleExpr(block))
+	case "HEXADECIMAL":
+		return primitive("is-hexadecimal?", "text", "is hexadecimal?", p.singleExpr(block))
+	case "BASE10":
+		return primitive("is-base10?", "text", "is Base 10?", p.singleExpr(block))
+	default:
+		panic("Unknown MathIsNumber type: " + block.SingleField())
+	}
+}
+
+func (p *Parser) mathDivide(block ast.Block) string {
+	var name string
+	switch block.SingleField() {
+	case "MODULO":
+		name = "modulo"
+	case "REMAINDER":
+		name = "remainder"
+	case "QUOTIENT":
+		name = "quotient"
+	default:
+		panic("Unsupported math divide type: " + block.SingleField())
+	}
+	return primitive(name, "number number", name, p.fromMinVals(block.Values, 2)...)
+}
+
+func (p *Parser) mathSingle(block ast.Block) string {
+	var name, display string
+	switch block.SingleField() {
+	case "ROOT":
+		name, display = "sqrt", "sqrt"
+	case "ABS":
+		name, display = "abs", "absolute"
+	case "NEG":
+		name, display = "-", "neg"
+	case "LN":
+		name, display = "log", "log"
+	case "EXP":
+		name, display = "exp", "exp"
+	case "ROUND":
+		name, display = "yail-round", "round"
+	case "CEILING":
+		name, display = "yail-ceiling", "ceiling"
+	case "FLOOR":
+		name, display = "yail-floor", "floor"
+	default:
+		panic("Unsupported math single operation: " + block.SingleField())
+	}
+	return primitive(name, "number", display, p.singleExpr(block))
+}
+
+func (p *Parser) mathOnList2(block ast.Block) string {
+	var name string
+	switch block.SingleField() {
+	case "AVG":
+		name = "avg"
+	case "MIN":
+		name = "minl"
+	case "MAX":
+		name = "maxl"
+	case "GM":
+		name = "gm"
+	case "SD":
+		name = "std-dev"
+	case "SE":
+		name = "std-err"
+	default:
+		panic("Unsupported math on list operation: " + block.SingleField())
+	}
+	return primitive(name, "list", name, p.singleExpr(block))
+}
+
+// mathRadix generates the decimal value of the number, it is converted at compile time
+func (p *Parser) mathRadix(block ast.Block) string {
+	pFields := p.makeFieldMap(block.Fields)
+	var base int
+	switch pFields["OP"] {
+	case "DEC":
+		base = 10
+	case "BIN":
+		base = 2
+	case "HEX":
+		base = 16
+	case "OCT":
+		base = 8
+	default:
+		panic("Unknown Math Radix Type: " + pFields["OP"])
+	}
+	number, err := strconv.ParseInt(pFields["NUM"], base, 64)
+	if err != nil {
+		panic("Invalid number " + pFields["NUM"] + " of base " + strconv.Itoa(base))
+	}
+	return strconv.FormatInt(number, 10)
+}
+
+func (p *Parser) mathRandom(block ast.Block) string {
+	valMap := p.makeValueMap(block.Values)
+	return primitive("random-integer", "number number", "random integer", valMap.get("FROM"), valMap.get("TO"))
+}
+
+func (p *Parser) mathExpr(block ast.Block) string {
+	var name, types, display string
+	switch block.SingleField() {
+	case "EQ":
+		name, types, display = "yail-equal?", "any any", "="
+	case "NEQ":
+		name, types, display = "yail-not-equal?", "any any", "≠"
+	case "LT":
+		name, types, display = "<", "number number", "<"
+	case "LTE":
+		name, types, display = "<=", "number number", "≤"
+	case "GT":
+		name, types, display = ">", "number number", ">"
+	case "GTE":
+		name, types, display = ">=", "number number", "≥"
+	case "BITAND":
+		name, display = "bitwise-and", "bitwise and"
+	case "BITIOR":
+		name, display = "bitwise-ior", "bitwise or"
+	case "BITXOR":
+		name, display = "bitwise-xor", "bitwise xor"
+	default:
+		panic("Unsupported math expression operation: " + block.SingleField())
+	}
+	args := p.fromMinVals(block.Values, 2)
+	if types == "" {
+		types = repeatType("number", len(args))
+	}
+	return primitive(name, types, display, args...)
+}
+
+// makeColor generates the signed integer of the color, #RRGGBB is opaque and #RRGGBBAA has an alpha
+func (p *Parser) makeColor(block ast.Block) string {
+	hex := strings.TrimPrefix(block.SingleField(), "#")
+	if len(hex) == 8 {
+		hex = hex[6:] + hex[:6]
+	} else {
+		hex = "FF" + hex
+	}
+	argb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		panic("Invalid color " + block.SingleField())
+	}
+	return strconv.FormatInt(int64(int32(uint32(argb))), 10)
+}
+
+func (p *Parser) singleExprOr(block ast.Block, otherwise string) string {
+	if len(block.Values) == 0 {
+		return otherwise
+	}
+	return p.parseBlock(block.Values[0].Block)
+}
+
+func (p *Parser) optSingleBody(block ast.Block) []string {
+	if len(block.Statements) > 0 {
+		return p.recursiveParse(*block.SingleStatement().Block)
+	}
+	return []string{}
+}
+
+func (p *Parser) makeStatementMap(allStatements []ast.Statement) StatementMap {
+	statementMap := make(map[string][]string, len(allStatements))
+	for _, stmt := range allStatements {
+		statementMap[stmt.Name] = p.recursiveParse(*stmt.Block)
+	}
+	return StatementMap{statementMap: statementMap}
+}
+
+func (p *Parser) recursiveParse(currBlock ast.Block) []string {
+	var codes []string
+	for {
+		codes = append(codes, p.parseBlock(currBlock))
+		if currBlock.Next == nil {
+			break
+		}
+		currBlock = *currBlock.Next.Block
+	}
+	return codes
+}
+
+func (p *Parser) makeFieldMap(allFields []ast.Field) map[string]string {
+	fieldMap := make(map[string]string, len(allFields))
+	for _, fil := range allFields {
+		fieldMap[fil.Name] = fil.Value
+	}
+	return fieldMap
+}
+
+func (p *Parser) makeValueMap(allValues []ast.Value) ValueMap {
+	valueMap := make(map[string]string, len(allValues))
+	for _, val := range allValues {
+		valueMap[val.Name] = p.parseBlock(val.Block)
+	}
+	return ValueMap{valueMap: valueMap}
+}
+
+func (p *Parser) fromVals(allValues []ast.Value) []string {
+	codes := make([]string, len(allValues))
+	for i := range allValues {
+		codes[i] = p.parseBlock(allValues[i].Block)
+	}
+	return codes
+}
+
+func (p *Parser) fromMinVals(allValues []ast.Value, minCount int) []string {
+	size := max(minCount, len(allValues))
+	codes := make([]string, size)
+	for i := range allValues {
+		codes[i] = p.parseBlock(allValues[i].Block)
+	}
+	for i := len(allValues); i < size; i++ {
+		codes[i] = yailFalse
+	}
+	return codes
+}
+
+// primitive calls a function of the runtime, the types coerce the arguments and the
+// display name is shown in the runtime errors
+func primitive(name string, types string, display string, args ...string) string {
+	return "(call-yail-primitive " + name + " " + argList(args) + " '(" + types + ") " + quote(display) + ")"
+}
+
+func argList(args []string) string {
+	return "(*list-for-runtime*" + prefixed(" ", args) + ")"
+}
+
+func prefixed(prefix string, codes []string) string {
+	code := ""
+	for _, c := range codes {
+		code += prefix + c
+	}
+	return code
+}
+
+func repeatType(name string, count int) string {
+	return strings.TrimSuffix(strings.Repeat(name+" ", count), " ")
+}
+
+func begin(statements []string) string {
+	return "(begin" + indent(statements) + ")"
+}
+
+// indent places each statement on a line of its own, an empty body does nothing
+func indent(statements []string) string {
+	if len(statements) == 0 {
+		statements = []string{yailFalse}
+	}
+	var builder strings.Builder
+	for _, statement := range statements {
+		// texts escape their line breaks, so every line belongs to the code
+		for _, line := range strings.Split(statement, "\n") {
+			builder.WriteString("\n  ")
+			builder.WriteString(line)
+		}
+	}
+	return builder.String()
+}
+
+// quote writes a Scheme string literal
+func quote(text string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t")
+	return "\"" + replacer.Replace(text) + "\""
+}
@@ -0,0 +1,79 @@
+package blocklytoyail
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the YAIL of the testdata screens instead of comparing it")
+
+// the blocks of each screen were compiled from the .mist program next to them, their YAIL was
+// reviewed by hand. Run go test -update to write it again.
+func TestGoldenScreens(t *testing.T) {
+	screens, err := filepath.Glob(filepath.Join("testdata", "*.bky"))
+	if err != nil || len(screens) == 0 {
+		t.Fatalf("no screen in testdata: %v", err)
+	}
+	for _, screen := range screens {
+		name := strings.TrimSuffix(filepath.Base(screen), ".bky")
+		t.Run(name, func(t *testing.T) {
+			blocks, err := os.ReadFile(screen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			yail := NewParser(string(blocks)).GenerateYail()
+			golden := strings.TrimSuffix(screen, ".bky") + ".yail"
+			if *update {
+				if err := os.WriteFile(golden, []byte(yail), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if yail != string(expected) {
+				t.Errorf("the YAIL of %s changed, expected:\n%s\nbut got:\n%s", name, expected, yail)
+			}
+		})
+	}
+}
+
+func TestCoercionsAreTyped(t *testing.T) {
+	blocks, err := os.ReadFile(filepath.Join("testdata", "HelloPurr.bky"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yail := NewParser(string(blocks)).GenerateYail()
+	for _, expected := range []string{
+		`(set-and-coerce-property! 'Button1 'Text "Pet the Kitty" 'text)`,
+		`(set-and-coerce-property! 'Player1 'Loop #f 'boolean)`,
+		`(set-and-coerce-property! 'Sound1 'MinimumInterval 500 'number)`,
+		`(call-component-method 'Sound1 'Vibrate (*list-for-runtime* 500) '(number))`,
+	} {
+		if !strings.Contains(yail, expected) {
+			t.Errorf("expected %s in:\n%s", expected, yail)
+		}
+	}
+}
+
+func TestUnknownPropertiesAreCoercedToAny(t *testing.T) {
+	blocks := `<xml xmlns="https://developers.google.com/blockly/xml">
+  <block type="component_set_get" id="s1">
+    <mutation component_type="ColorPicker" set_or_get="set" property_name="PickedColor" instance_name="ColorPicker1"></mutation>
+    <field name="COMPONENT_SELECTOR">ColorPicker1</field>
+    <field name="PROP">PickedColor</field>
+    <value name="VALUE">
+      <block type="text" id="t1"><field name="TEXT">red</field></block>
+    </value>
+  </block>
+</xml>`
+	expected := `(set-and-coerce-property! 'ColorPicker1 'PickedColor "red" 'any)`
+	if yail := strings.TrimSpace(NewParser(blocks).GenerateYail()); yail != expected {
+		t.Errorf("expected %s but got %s", expected, yail)
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"Falcon/code/ast/method"
 	"Falcon/code/ast/procedures"
 	"Falcon/code/ast/variables"
+	"Falcon/code/context"
 	"Falcon/code/sugar"
-	"strconv"
+	"Falcon/components/registry"
+	"Falcon/design"
+	"slices"
+	"sort"
 	"strings"
 
 	l "Falcon/code/lex"
@@ -28,12 +32,40 @@ type LangParser struct {
 	Resolver    *NameResolver
 	ScopeCursor *ScopeCursor
 	aggregator  *ErrorAggregator
+	diagnostics []*context.Diagnostic
+
+	// Located holds every expression parsed along with its source tokens, used by editor tooling
+	Located []LocatedExpr
+	// DefinitionComments holds the comments found around the component definitions
+	DefinitionComments []string
+	headerDetached     bool    // an empty line separates the first of the comments from the definitions
+	claimed            []uint8 // the comments of the tokens that are attached to a statement
+
+	// designed holds the components declared by the screen design rather than by an @ header
+	designed map[string]bool
+	// voidCalls holds the method calls giving nothing back in the statements being parsed, they can
+	// only be a statement of their own
+	voidCalls []voidCall
+}
+
+type voidCall struct {
+	where *l.Token
+	call  ast.Expr
+}
+
+type LocatedExpr struct {
+	Start *l.Token
+	End   *l.Token
+	Expr  ast.Expr
+	// Statement is set for a whole statement, along with its block annotations
+	Statement bool
 }
 
 func NewLangParser(strict bool, tokens []*l.Token) *LangParser {
 	return &LangParser{
 		Tokens:         tokens,
 		tokenSize:      len(tokens),
+		claimed:        make([]uint8, len(tokens)),
 		currIndex:      0,
 		currCheckpoint: 0,
 		strict:         strict,
@@ -41,9 +73,12 @@ func NewLangParser(strict bool, tokens []*l.Token) *LangParser {
 			Procedures:        map[string]*Procedure{},
 			ComponentTypesMap: map[string]string{},
 			ComponentNameMap:  map[string][]string{},
+			Globals:           map[string]*l.Token{},
+			Components:        registry.Default(),
 		},
 		ScopeCursor: MakeScopeCursor(),
 		aggregator:  &ErrorAggregator{Errors: map[*l.Token]ParseError{}},
+		designed:    map[string]bool{},
 	}
 }
 
@@ -52,37 +87,223 @@ func (p *LangParser) SetComponentDefinitions(definitions map[string][]string, re
 	p.Resolver.ComponentTypesMap = reverseDefinitions
 }
 
+// SetDesign declares the components of the screen design, the screen included, so that the
+// source needs no @ headers for them. It is called before parsing.
+func (p *LangParser) SetDesign(screen *design.Component) {
+	if screen.Id != "" {
+		p.declareComponent(screen.ComponentType(), screen.Id)
+		p.designed[screen.Id] = true
+	}
+	for k := range screen.Children {
+		p.SetDesign(&screen.Children[k])
+	}
+}
+
+func (p *LangParser) declareComponent(compType string, name string) {
+	p.Resolver.ComponentTypesMap[name] = compType
+	if !slices.Contains(p.Resolver.ComponentNameMap[compType], name) {
+		p.Resolver.ComponentNameMap[compType] = append(p.Resolver.ComponentNameMap[compType], name)
+	}
+}
+
+// Include makes the procedures, components and globals known to another parser visible to this one,
+// such as the ones of the source file exercised by a test file. It is called before parsing.
+func (p *LangParser) Include(other *LangParser) {
+	p.IncludeComponents(other)
+	for name, procedure := range other.Resolver.Procedures {
+		p.Resolver.Procedures[name] = procedure
+	}
+	for name, where := range other.Resolver.Globals {
+		p.Resolver.Globals[name] = where
+		signature, _ := other.ScopeCursor.ResolveVariable(name)
+		p.ScopeCursor.DefineVariable(name, signature)
+	}
+}
+
+// IncludeComponents makes the components known to another parser visible to this one, along with
+// their descriptors. It is called before parsing.
+func (p *LangParser) IncludeComponents(other *LangParser) {
+	p.Resolver.Components = other.Resolver.Components
+	for componentType, names := range other.Resolver.ComponentNameMap {
+		p.Resolver.ComponentNameMap[componentType] = names
+	}
+	for name, componentType := range other.Resolver.ComponentTypesMap {
+		p.Resolver.ComponentTypesMap[name] = componentType
+	}
+}
+
+// Declares tells whether the @ headers of the source declare the component, rather than the design
+func (p *LangParser) Declares(name string) bool {
+	_, known := p.Resolver.ComponentTypesMap[name]
+	return known && !p.designed[name]
+}
+
 func (p *LangParser) GetComponentDefinitionsCode() string {
 	// convert the AST back to syntax
 	var definitions strings.Builder
-	for key, value := range p.Resolver.ComponentNameMap {
-		definitions.WriteString(sugar.Format("@% { % }\n", key, strings.Join(value, ", ")))
+	for _, comment := range p.DefinitionComments {
+		definitions.WriteString(strings.TrimSpace("// " + comment))
+		definitions.WriteString("\n")
+	}
+	if p.headerDetached {
+		definitions.WriteString("\n")
+	}
+	componentTypes := make([]string, 0, len(p.Resolver.ComponentNameMap))
+	for componentType := range p.Resolver.ComponentNameMap {
+		componentTypes = append(componentTypes, componentType)
+	}
+	sort.Strings(componentTypes)
+	for _, componentType := range componentTypes {
+		var names []string
+		for _, name := range p.Resolver.ComponentNameMap[componentType] {
+			// the design already declares them
+			if !p.designed[name] {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		definitions.WriteString(sugar.Format("@% { % }\n", componentType, strings.Join(names, ", ")))
 	}
 	return definitions.String()
 }
 
-func (p *LangParser) ParseAll() []ast.Expr {
+// ParseAll parses every statement in the token stream. A syntax error does not stop the parser,
+// it skips to the next statement and carries on, so that all the problems in the file are
+// returned as diagnostics in a single pass.
+func (p *LangParser) ParseAll() ([]ast.Expr, []*context.Diagnostic) {
 	var expressions []ast.Expr
-	if p.notEOF() {
-		p.defineStatements()
-	}
+	p.defineStatements()
 	for p.notEOF() {
-		e := p.parse()
-		expressions = append(expressions, e)
+		start := p.currIndex
+		var expression ast.Expr
+		failure := p.attempt(func() { expression = p.statement() })
+		if failure == nil {
+			expressions = append(expressions, expression)
+			continue
+		}
+		p.forgetSymbols(start)
+		p.ScopeCursor.Unwind(1)
+		if _, toRoot := failure.(syncToRoot); !toRoot {
+			p.report(p.toDiagnostic(failure))
+			p.synchronize(start, true)
+		}
+	}
+	if count := len(expressions); count > 0 && p.tokenSize > 0 {
+		if meta := ast.MetaOf(expressions[count-1]); meta != nil {
+			meta.After = append(meta.After, p.Tokens[p.tokenSize-1].After...)
+		}
 	}
 	if p.strict {
 		p.checkPendingSymbols()
 	}
-	return expressions
+	return expressions, p.diagnostics
+}
+
+// syncToRoot unwinds the parsing of a body whose recovery ran into a declaration
+// that can only be present at the root (func, when or global)
+type syncToRoot struct{}
+
+// attempt runs fn and returns the value it panicked with, if any
+func (p *LangParser) attempt(fn func()) (failure any) {
+	defer func() {
+		failure = recover()
+	}()
+	fn()
+	return nil
+}
+
+// synchronize skips the rest of a statement that failed to parse. It stops at the start of the next
+// statement, which is a new line, a closing curly brace of the enclosing body, or a root declaration.
+// Returns true when a root declaration was reached while not parsing at the root.
+func (p *LangParser) synchronize(start int, atRoot bool) bool {
+	// re-examine the offending token, it may already be the start of the next statement
+	p.currIndex = max(p.currIndex-1, start+1)
+	depth := 0
+	for p.notEOF() {
+		token := p.peek()
+		switch token.Type {
+		case l.Func, l.When, l.Global:
+			return !atRoot
+		case l.OpenCurly:
+			depth++
+		case l.CloseCurly:
+			if depth == 0 && !atRoot {
+				return false
+			}
+			depth = max(depth-1, 0)
+		default:
+			if depth == 0 && p.startsLine(p.currIndex) {
+				return false
+			}
+		}
+		p.skip()
+	}
+	return false
+}
+
+// forgetSymbols drops the unresolved symbols of a statement that failed to parse,
+// the syntax error is what needs to be fixed first
+func (p *LangParser) forgetSymbols(start int) {
+	for _, token := range p.Tokens[start:min(p.currIndex, p.tokenSize)] {
+		p.aggregator.MarkResolved(token)
+	}
+}
+
+func (p *LangParser) startsLine(index int) bool {
+	return index == 0 || p.Tokens[index].Column > p.Tokens[index-1].Column
+}
+
+// report records the diagnostic, unless it repeats the last one, which happens when
+// an early end of the file is seen by several enclosing bodies
+func (p *LangParser) report(diagnostic *context.Diagnostic) {
+	if count := len(p.diagnostics); count > 0 {
+		last := p.diagnostics[count-1]
+		if last.Span == diagnostic.Span && last.Message == diagnostic.Message {
+			return
+		}
+	}
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+func (p *LangParser) toDiagnostic(r any) *context.Diagnostic {
+	switch err := r.(type) {
+	case *context.Diagnostic:
+		return err
+	case string:
+		// raised by an AST node while it was being constructed
+		return p.currentToken().Diagnostic(context.CodeSyntax, strings.TrimSpace(err))
+	default:
+		panic(r)
+	}
+}
+
+// currentToken returns the token being parsed, or the last one at the end of the file
+func (p *LangParser) currentToken() *l.Token {
+	if p.tokenSize == 0 {
+		return l.MakeFakeToken(l.Undefined)
+	}
+	return p.Tokens[min(max(p.currIndex-1, 0), p.tokenSize-1)]
 }
 
 func (p *LangParser) checkPendingSymbols() {
-	var errorMessages []string
-	for token, parseError := range p.aggregator.Errors {
+	var pendingTokens []*l.Token
+	for token := range p.aggregator.Errors {
+		pendingTokens = append(pendingTokens, token)
+	}
+	// report in the order of appearance
+	sort.Slice(pendingTokens, func(i, j int) bool {
+		if pendingTokens[i].Column != pendingTokens[j].Column {
+			return pendingTokens[i].Column < pendingTokens[j].Column
+		}
+		return pendingTokens[i].Row < pendingTokens[j].Row
+	})
+	for _, token := range pendingTokens {
+		parseError := p.aggregator.Errors[token]
 		// try resolve global variables again
 		if get, ok := parseError.Owner.(*variables.Get); ok && get.Global {
 			signatures, resolved := p.ScopeCursor.ResolveVariable(get.Name)
-			println("Failed to resolve: " + get.String())
 			if resolved {
 				get.ValueSignature = signatures
 				continue
@@ -97,36 +318,174 @@ func (p *LangParser) checkPendingSymbols() {
 			}
 			parseError.ErrorMessage = procedureErrorMessage
 		}
-		errorMessages = append(errorMessages, token.BuildError(false, parseError.ErrorMessage))
-	}
-	if len(errorMessages) > 0 {
-		var errorWriter strings.Builder
-		errorWriter.WriteString(sugar.Format("compile failed with % syntax errors", strconv.Itoa(len(errorMessages))))
-		errorWriter.WriteString(strings.Join(errorMessages, ""))
-		panic(errorWriter.String())
+		diagnostic := token.Diagnostic(context.CodeUnresolved, parseError.ErrorMessage)
+		if procCall, ok := parseError.Owner.(*procedures.Call); ok {
+			if procedure, exists := p.Resolver.Procedures[procCall.Name]; exists {
+				diagnostic.WithRelated(procedure.Where.Span(), "procedure "+procedure.Name+"() is defined here")
+			}
+		}
+		p.diagnostics = append(p.diagnostics, diagnostic)
 	}
 }
 
 func (p *LangParser) defineStatements() {
-	for p.notEOF() && p.consume(l.At) {
-		compType := p.name()
-		p.expect(l.OpenCurly)
-		if !p.consume(l.CloseCurly) {
-			var componentNames []string
-			for {
-				name := p.name()
-				componentNames = append(componentNames, name)
-				p.Resolver.ComponentTypesMap[name] = compType
-				if !p.consume(l.Comma) {
-					break
-				}
+	for p.isNext(l.At) && !p.isBlockAnnotation() {
+		start := p.currIndex
+		if failure := p.attempt(p.defineStatement); failure != nil {
+			p.report(p.toDiagnostic(failure))
+			p.synchronize(start, true)
+		}
+	}
+}
+
+func (p *LangParser) defineStatement() {
+	start := p.currIndex
+	defer func() {
+		for i := start; i < min(p.currIndex, p.tokenSize); i++ {
+			comments := p.claimComments(i)
+			if len(p.DefinitionComments) == 0 && len(comments) > 0 {
+				p.headerDetached = p.Tokens[i].Detached
+			}
+			p.DefinitionComments = append(p.DefinitionComments, comments...)
+			p.DefinitionComments = append(p.DefinitionComments, p.claimTrailing(i)...)
+			if i == p.tokenSize-1 {
+				p.DefinitionComments = append(p.DefinitionComments, p.Tokens[i].After...)
+			}
+		}
+	}()
+	p.expect(l.At)
+	compType := p.name()
+	p.expect(l.OpenCurly)
+	if !p.consume(l.CloseCurly) {
+		for {
+			name := p.name()
+			p.declareComponent(compType, name)
+			delete(p.designed, name)
+			if !p.consume(l.Comma) {
+				break
 			}
-			p.Resolver.ComponentNameMap[compType] = componentNames
-			p.expect(l.CloseCurly)
 		}
+		p.expect(l.CloseCurly)
 	}
 }
 
+// statement parses a statement of a body or of the root and attaches the comments and the
+// block annotations around it
+func (p *LangParser) statement() ast.Expr {
+	start := p.currIndex
+	calls := len(p.voidCalls)
+	defer func() {
+		p.voidCalls = p.voidCalls[:calls]
+	}()
+	state, annotated := p.blockState()
+	expression := p.parse()
+	for _, void := range p.voidCalls[calls:] {
+		if void.call != expression {
+			p.report(void.where.Diagnostic(context.CodeSyntax, "Expected a consumable but got a statement"))
+		}
+	}
+	if start < p.currIndex {
+		p.Located = append(p.Located, LocatedExpr{
+			Start: p.Tokens[start], End: p.Tokens[p.currIndex-1], Expr: expression, Statement: true})
+	}
+	meta := ast.MetaOf(expression)
+	end := p.currIndex - 1
+	if annotated && meta == nil {
+		p.Tokens[start].Error("The block of this statement cannot be annotated")
+	}
+	if meta == nil || end < start {
+		return expression
+	}
+	meta.State = state
+	var comments []string
+	if start > 0 && p.Tokens[start-1].Type == l.OpenCurly {
+		// a comment following the opening of the body
+		comments = append(comments, p.claimTrailing(start-1)...)
+	}
+	meta.Spaced = p.Tokens[start].Spaced
+	meta.Detached = p.Tokens[start].Detached
+	// comments in the middle of the statement are moved above it
+	for i := start; i <= end; i++ {
+		comments = append(comments, p.claimComments(i)...)
+		if i < end {
+			comments = append(comments, p.claimTrailing(i)...)
+		}
+	}
+	meta.Comments = append(meta.Comments, comments...)
+	if trailing := p.claimTrailing(end); len(trailing) > 0 {
+		meta.Trailing = trailing[0]
+	}
+	return expression
+}
+
+// isBlockAnnotation tells if the @ that follows annotates a block rather than defines components
+func (p *LangParser) isBlockAnnotation() bool {
+	if p.currIndex+1 >= p.tokenSize || p.Tokens[p.currIndex+1].Type != l.Name {
+		return false
+	}
+	// a component definition has its names between curly braces, @Button { Button1 }
+	return slices.Contains(ast.BlockAnnotations, *p.Tokens[p.currIndex+1].Content) ||
+		p.currIndex+2 < p.tokenSize && p.Tokens[p.currIndex+2].Type != l.OpenCurly
+}
+
+// startsAnnotation tells if the @ that follows annotates the block of the next statement rather than
+// accesses a helper of the expression before it, e.g. @collapsed on the line after a global
+func (p *LangParser) startsAnnotation() bool {
+	if p.currIndex > 0 && p.Tokens[p.currIndex-1].Column != p.Tokens[p.currIndex].Column {
+		return true
+	}
+	next := p.currIndex + 1
+	return next < p.tokenSize && p.Tokens[next].Type == l.Name &&
+		slices.Contains(ast.BlockAnnotations, *p.Tokens[next].Content)
+}
+
+// blockState parses the annotations of the block of a statement, e.g. @collapsed @id("a1")
+func (p *LangParser) blockState() (state ast.BlockState, annotated bool) {
+	for p.isNext(l.At) {
+		p.expect(l.At)
+		annotated = true
+		nameToken := p.expect(l.Name)
+		switch *nameToken.Content {
+		case "collapsed":
+			state.Collapsed = true
+		case "disabled":
+			state.Disabled = true
+		case "inline", "external":
+			inline := *nameToken.Content == "inline"
+			state.Inline = &inline
+		case "id":
+			p.expect(l.OpenCurve)
+			state.ID = *p.expect(l.Text).Content
+			p.expect(l.CloseCurve)
+		default:
+			nameToken.Error("Unknown block annotation @%, expected one of %",
+				*nameToken.Content, strings.Join(ast.BlockAnnotations, ", "))
+		}
+	}
+	return state, annotated
+}
+
+const (
+	claimedComments = 1 << iota
+	claimedTrailing
+)
+
+func (p *LangParser) claimComments(index int) []string {
+	if p.claimed[index]&claimedComments != 0 {
+		return nil
+	}
+	p.claimed[index] |= claimedComments
+	return p.Tokens[index].Comments
+}
+
+func (p *LangParser) claimTrailing(index int) []string {
+	if p.claimed[index]&claimedTrailing != 0 || p.Tokens[index].Trailing == "" {
+		return nil
+	}
+	p.claimed[index] |= claimedTrailing
+	return []string{p.Tokens[index].Trailing}
+}
+
 func (p *LangParser) parse() ast.Expr {
 	switch p.peek().Type {
 	case l.If:
@@ -150,20 +509,43 @@ func (p *LangParser) parse() ast.Expr {
 			return p.genericEvent()
 		}
 		return p.event()
+	case l.Name:
+		if p.isTest() {
+			return p.testSmt()
+		}
+		return p.expr(0)
 	default:
 		// It cannot be consumable
 		return p.expr(0)
 	}
 }
 
+// isTest tells if a test declaration is next, test is only a keyword when a text follows it
+func (p *LangParser) isTest() bool {
+	return *p.peek().Content == "test" && p.currIndex+1 < p.tokenSize && p.Tokens[p.currIndex+1].Type == l.Text
+}
+
+func (p *LangParser) testSmt() ast.Expr {
+	testToken := p.next()
+	name := *p.expect(l.Text).Content
+	where := p.expect(l.OpenCurly)
+	p.ScopeCursor.Enter(where, ScopeTest)
+	body := p.bodyUntilCurly()
+	p.ScopeCursor.Exit(ScopeTest)
+	p.expect(l.CloseCurly)
+	return &procedures.Test{Where: testToken, Name: name, Body: body}
+}
+
 func (p *LangParser) genericEvent() ast.Expr {
 	componentType := p.componentType()
 	p.expect(l.Dot)
-	eventName := p.name()
+	eventToken := p.expect(l.Name)
+	eventName := *eventToken.Content
 	var parameters []string
 	if p.isNext(l.OpenCurve) {
 		parameters = p.parameters()
 	}
+	p.checkComponent(eventToken, p.Resolver.ResolveEvent(componentType, eventName, parameters, true))
 	where := p.expect(l.OpenCurly)
 	p.ScopeCursor.Enter(where, ScopeEvent)
 	for _, param := range parameters {
@@ -178,11 +560,13 @@ func (p *LangParser) genericEvent() ast.Expr {
 func (p *LangParser) event() ast.Expr {
 	component := p.component()
 	p.expect(l.Dot)
-	eventName := p.name()
+	eventToken := p.expect(l.Name)
+	eventName := *eventToken.Content
 	var parameters []string
 	if p.isNext(l.OpenCurve) {
 		parameters = p.parameters()
 	}
+	p.checkComponent(eventToken, p.Resolver.ResolveEvent(component.Type, eventName, parameters, false))
 	where := p.expect(l.OpenCurly)
 	p.ScopeCursor.Enter(where, ScopeEvent)
 	for _, param := range parameters {
@@ -203,15 +587,23 @@ func (p *LangParser) event() ast.Expr {
 
 func (p *LangParser) funcSmt() ast.Expr {
 	where := p.next()
-	name := p.name()
-	var parameters = p.parameters()
+	nameToken := p.expect(l.Name)
+	name := *nameToken.Content
+	parameters, parameterTypes := p.typedParameters()
+	var returnType string
+	if p.consume(l.Colon) {
+		returnType = p.typeName()
+		if !p.isNext(l.Assign) {
+			p.currentToken().Error("Only a func that returns a value (func %(..): % = ..) can have a return type",
+				name, returnType)
+		}
+	}
 	returning := p.consume(l.Assign)
-	p.Resolver.Procedures[name] = &Procedure{Name: name, Parameters: parameters, Returning: returning}
+	p.Resolver.Procedures[name] = &Procedure{Where: nameToken, Name: name, Parameters: parameters,
+		ParameterTypes: parameterTypes, Returning: returning, ReturnType: returnType}
 	if returning {
 		p.ScopeCursor.Enter(where, ScopeSmartBody)
-		for _, parameter := range parameters {
-			p.ScopeCursor.DefineVariable(parameter, []ast.Signature{ast.SignAny})
-		}
+		p.defineParameters(parameters, parameterTypes)
 		var result ast.Expr
 		if p.isNext(l.OpenCurly) {
 			result = p.smartBody()
@@ -219,17 +611,23 @@ func (p *LangParser) funcSmt() ast.Expr {
 			result = p.parse()
 		}
 		p.ScopeCursor.Exit(ScopeSmartBody)
-		return &procedures.RetProcedure{Name: name, Parameters: parameters, Result: result}
+		return &procedures.RetProcedure{Name: name, Parameters: parameters, Result: result,
+			ParameterTypes: parameterTypes, ReturnType: returnType}
 	} else {
 		where := p.expect(l.OpenCurly)
 		p.ScopeCursor.Enter(where, ScopeProc)
-		for _, parameter := range parameters {
-			p.ScopeCursor.DefineVariable(parameter, []ast.Signature{ast.SignAny})
-		}
+		p.defineParameters(parameters, parameterTypes)
 		body := p.bodyUntilCurly()
 		p.ScopeCursor.Exit(ScopeProc)
 		p.expect(l.CloseCurly)
-		return &procedures.VoidProcedure{Name: name, Parameters: parameters, Body: body}
+		return &procedures.VoidProcedure{Name: name, Parameters: parameters, Body: body, ParameterTypes: parameterTypes}
+	}
+}
+
+func (p *LangParser) defineParameters(parameters []string, parameterTypes []string) {
+	for i, parameter := range parameters {
+		signature, _ := ast.ParseType(parameterTypes[i])
+		p.ScopeCursor.DefineVariable(parameter, []ast.Signature{signature})
 	}
 }
 
@@ -238,17 +636,29 @@ func (p *LangParser) globVar() ast.Expr {
 	if !p.ScopeCursor.AtRoot() {
 		where.Error("Global variables can only be defined at the root.")
 	}
-	name := p.name()
+	nameToken := p.expect(l.Name)
+	name := *nameToken.Content
+	var typeName string
+	if p.consume(l.Colon) {
+		typeName = p.typeName()
+	}
 	p.expect(l.Assign)
 	value := p.parse()
-	p.ScopeCursor.DefineVariable(name, value.Signature())
-	return &variables.Global{Name: name, Value: value}
+	signatures := value.Signature()
+	if typeName != "" {
+		signature, _ := ast.ParseType(typeName)
+		signatures = []ast.Signature{signature}
+	}
+	p.ScopeCursor.DefineVariable(name, signatures)
+	p.Resolver.Globals[name] = nameToken
+	return &variables.Global{Name: name, Value: value, Type: typeName}
 }
 
 func (p *LangParser) varExpr() ast.Expr {
 	// a clean full scope variable
 	var names []string
 	var values []ast.Expr
+	var trailings []string
 	for {
 		p.createCheckpoint()
 		if !p.consume(l.Local) {
@@ -267,10 +677,16 @@ func (p *LangParser) varExpr() ast.Expr {
 
 		names = append(names, name)
 		values = append(values, value)
+		// the comment ending the line of the local, it would otherwise be moved above the statement
+		trailing := ""
+		if comments := p.claimTrailing(p.currIndex - 1); len(comments) > 0 {
+			trailing = comments[0]
+		}
+		trailings = append(trailings, trailing)
 		p.ScopeCursor.DefineVariable(name, value.Signature())
 	}
 	// we have to parse rest of the body here
-	return &variables.Var{Names: names, Values: values, Body: p.bodyUntilCurly()}
+	return &variables.Var{Names: names, Values: values, Trailings: trailings, Body: p.bodyUntilCurly()}
 }
 
 func (p *LangParser) whileExpr() *control.While {
@@ -390,13 +806,36 @@ func (p *LangParser) bodyUntilCurly() []ast.Expr {
 		return expressions
 	}
 	for p.notEOF() && !p.isNext(l.CloseCurly) {
-		expressions = append(expressions, p.parse())
+		start := p.currIndex
+		depth := p.ScopeCursor.Depth()
+		var expression ast.Expr
+		failure := p.attempt(func() { expression = p.statement() })
+		if failure != nil {
+			if _, toRoot := failure.(syncToRoot); toRoot {
+				panic(failure)
+			}
+			p.report(p.toDiagnostic(failure))
+			p.forgetSymbols(start)
+			p.ScopeCursor.Unwind(depth)
+			if p.synchronize(start, false) {
+				panic(syncToRoot{})
+			}
+			continue
+		}
+		expressions = append(expressions, expression)
 		p.consume(l.Comma)
 	}
+	if count := len(expressions); count > 0 && p.isNext(l.CloseCurly) {
+		// comments before the closing of the body
+		if meta := ast.MetaOf(expressions[count-1]); meta != nil {
+			meta.After = append(meta.After, p.claimComments(p.currIndex)...)
+		}
+	}
 	return expressions
 }
 
 func (p *LangParser) expr(minPrecedence int) ast.Expr {
+	start := p.currIndex
 	left := p.element()
 	for p.notEOF() {
 		opToken := p.peek()
@@ -420,11 +859,11 @@ func (p *LangParser) expr(minPrecedence int) ast.Expr {
 		} else {
 			right = p.expr(precedence)
 		}
-		if rBinExpr, ok := right.(*common.BinaryExpr); ok && rBinExpr.CanRepeat(opToken.Type) {
+		if rBinExpr, ok := right.(*common.BinaryExpr); ok && rBinExpr.CanRepeat(opToken.Type) && !hasState(right) {
 			// for NoPreserveOrder: merge binary expr with same operator (towards right)
 			rBinExpr.Operands = append([]ast.Expr{left}, rBinExpr.Operands...)
 			left = rBinExpr
-		} else if lBinExpr, ok := left.(*common.BinaryExpr); ok && lBinExpr.CanRepeat(opToken.Type) {
+		} else if lBinExpr, ok := left.(*common.BinaryExpr); ok && lBinExpr.CanRepeat(opToken.Type) && !hasState(left) {
 			// for PreserveOder: merge binary expr with same operator (towards left)
 			lBinExpr.Operands = append(lBinExpr.Operands, right)
 		} else {
@@ -432,7 +871,21 @@ func (p *LangParser) expr(minPrecedence int) ast.Expr {
 			left = p.makeBinary(opToken, left, right)
 		}
 	}
-	return left
+	return p.locate(start, left)
+}
+
+// hasState tells if the block of the value is annotated, it's then a block of its own
+func hasState(expr ast.Expr) bool {
+	meta := ast.MetaOf(expr)
+	return meta != nil && meta.State.Annotations() != ""
+}
+
+// locate records the tokens from start up to the current index as the source of the expression
+func (p *LangParser) locate(start int, expr ast.Expr) ast.Expr {
+	if start < p.currIndex && p.currIndex <= p.tokenSize {
+		p.Located = append(p.Located, LocatedExpr{Start: p.Tokens[start], End: p.Tokens[p.currIndex-1], Expr: expr})
+	}
+	return expr
 }
 
 func (p *LangParser) compoundOperator(opToken *l.Token, left ast.Expr) ast.Expr {
@@ -470,7 +923,7 @@ func (p *LangParser) makeBinary(opToken *l.Token, left ast.Expr, right ast.Expr)
 func (p *LangParser) assignSmt(left ast.Expr, right ast.Expr) (ast.Expr, bool) {
 	if nameExpr, ok := left.(*variables.Get); ok {
 		p.aggregator.MarkResolved(nameExpr.Where)
-		return &variables.Set{Global: nameExpr.Global, Name: nameExpr.Name, Expr: right}, true
+		return &variables.Set{Where: nameExpr.Where, Global: nameExpr.Global, Name: nameExpr.Name, Expr: right}, true
 	} else if listGet, ok := left.(*list.Get); ok {
 		return &list.Set{List: listGet.List, Index: listGet.Index, Value: right}, true
 	}
@@ -478,6 +931,7 @@ func (p *LangParser) assignSmt(left ast.Expr, right ast.Expr) (ast.Expr, bool) {
 }
 
 func (p *LangParser) element() ast.Expr {
+	start := p.currIndex
 	left := p.term()
 	for p.notEOF() {
 		pe := p.peek()
@@ -492,6 +946,9 @@ func (p *LangParser) element() ast.Expr {
 
 		switch pe.Type {
 		case l.At:
+			if p.startsAnnotation() {
+				break
+			}
 			left = p.helperDropdown(left)
 		case l.Dot:
 			left = p.objectCall(left)
@@ -511,20 +968,35 @@ func (p *LangParser) element() ast.Expr {
 		}
 		break
 	}
-	return left
+	return p.locate(start, left)
 }
 
 func (p *LangParser) componentCall(compName string, compType string) ast.Expr {
 	p.expect(l.Dot)
-	resource := p.name()
+	resourceToken := p.expect(l.Name)
+	resource := *resourceToken.Content
 	if p.isNext(l.OpenCurve) {
-		return &components.MethodCall{
+		args := p.arguments()
+		errorMessage, method := p.Resolver.ResolveMethod(compType, resource, len(args))
+		p.checkComponent(resourceToken, errorMessage)
+		var signatures []ast.Signature
+		if method != nil {
+			signatures = []ast.Signature{registry.Signature(method.ReturnType)}
+		}
+		call := &components.MethodCall{
 			ComponentName: compName,
 			ComponentType: compType,
 			Method:        resource,
-			Args:          p.arguments(),
+			Args:          args,
+			Signatures:    signatures,
+		}
+		if method != nil && !method.Returns() {
+			p.voidCalls = append(p.voidCalls, voidCall{where: resourceToken, call: call})
 		}
+		return call
 	} else if p.consume(l.Assign) {
+		errorMessage, _ := p.Resolver.ResolveProperty(compType, resource, true)
+		p.checkComponent(resourceToken, errorMessage)
 		assignment := p.expr(0)
 		return &components.PropertySet{
 			ComponentName: compName,
@@ -533,7 +1005,20 @@ func (p *LangParser) componentCall(compName string, compType string) ast.Expr {
 			Value:         assignment,
 		}
 	}
-	return &components.PropertyGet{ComponentName: compName, ComponentType: compType, Property: resource}
+	errorMessage, property := p.Resolver.ResolveProperty(compType, resource, false)
+	p.checkComponent(resourceToken, errorMessage)
+	var signatures []ast.Signature
+	if property != nil {
+		signatures = []ast.Signature{registry.Signature(property.Type)}
+	}
+	return &components.PropertyGet{ComponentName: compName, ComponentType: compType, Property: resource, Signatures: signatures}
+}
+
+// checkComponent reports a member unknown to the component registry, the parser carries on
+func (p *LangParser) checkComponent(where *l.Token, errorMessage string) {
+	if errorMessage != "" {
+		p.report(where.Diagnostic(context.CodeComponent, errorMessage))
+	}
 }
 
 func (p *LangParser) helperDropdown(keyExpr ast.Expr) ast.Expr {
@@ -612,6 +1097,9 @@ func (p *LangParser) term() ast.Expr {
 		e := p.parse()
 		p.expect(l.CloseCurve)
 		return e
+	case l.At:
+		p.back()
+		return p.annotatedValue()
 	case l.Not:
 		return &fundamentals.Not{Expr: p.element()}
 	case l.Dash:
@@ -632,6 +1120,22 @@ func (p *LangParser) term() ast.Expr {
 	}
 }
 
+// annotatedValue parses a value between parentheses following the annotations of its block,
+// e.g. @external (a + b)
+func (p *LangParser) annotatedValue() ast.Expr {
+	start := p.peek()
+	state, _ := p.blockState()
+	p.expect(l.OpenCurve)
+	e := p.parse()
+	p.expect(l.CloseCurve)
+	meta := ast.MetaOf(e)
+	if meta == nil {
+		start.Error("The block of this value cannot be annotated")
+	}
+	meta.State = state
+	return e
+}
+
 func (p *LangParser) smartBody() ast.Expr {
 	body := p.body(ScopeSmartBody)
 	k := 0
@@ -744,6 +1248,42 @@ func (p *LangParser) parameters() []string {
 	return parameters
 }
 
+// typedParameters parses the parameters of a func, each can be annotated with a type
+func (p *LangParser) typedParameters() ([]string, []string) {
+	p.expect(l.OpenCurve)
+	var parameters, parameterTypes []string
+	if !p.consume(l.CloseCurve) {
+		for p.notEOF() && !p.isNext(l.CloseCurve) {
+			parameters = append(parameters, p.name())
+			var typeName string
+			if p.consume(l.Colon) {
+				typeName = p.typeName()
+			}
+			parameterTypes = append(parameterTypes, typeName)
+			if !p.consume(l.Comma) {
+				break
+			}
+		}
+		p.expect(l.CloseCurve)
+	}
+	return parameters, parameterTypes
+}
+
+const typeNames = "number, text, boolean, list, dict, component or any"
+
+// typeName parses the name of a type annotation
+func (p *LangParser) typeName() string {
+	token := p.next()
+	if token.Type != l.Name && token.Type != l.Any {
+		token.Error("Expected a type (%) but got %", typeNames, token.String())
+	}
+	name := *token.Content
+	if _, ok := ast.ParseType(name); !ok {
+		token.Error("Unknown type '%', expected %", name, typeNames)
+	}
+	return name
+}
+
 func (p *LangParser) arguments() []ast.Expr {
 	p.expect(l.OpenCurve)
 	var args []ast.Expr
@@ -831,7 +1371,7 @@ func (p *LangParser) consume(t l.Type) bool {
 
 func (p *LangParser) expect(t l.Type) *l.Token {
 	if p.isEOF() {
-		panic("Early EOF! Was expecting type " + t.String())
+		p.currentToken().Error("Unexpected end of file, was expecting type %", t.String())
 	}
 	got := p.next()
 	if got.Type != t {
@@ -855,14 +1395,14 @@ func (p *LangParser) isNext(checkTypes ...l.Type) bool {
 
 func (p *LangParser) peek() *l.Token {
 	if p.isEOF() {
-		panic("Early EOF!")
+		p.currentToken().Error("Unexpected end of file")
 	}
 	return p.Tokens[p.currIndex]
 }
 
 func (p *LangParser) next() *l.Token {
 	if p.isEOF() {
-		panic("Early EOF!")
+		p.currentToken().Error("Unexpected end of file")
 	}
 	token := p.Tokens[p.currIndex]
 	p.currIndex++
@@ -874,6 +1414,10 @@ func (p *LangParser) createCheckpoint() {
 }
 
 func (p *LangParser) backToPast() {
+	// the statements parsed since are discarded, so are their claims on comments
+	for i := p.currCheckpoint; i < min(p.currIndex, p.tokenSize); i++ {
+		p.claimed[i] = 0
+	}
 	p.currIndex = p.currCheckpoint
 }
 
@@ -0,0 +1,112 @@
+package mistparser
+
+import (
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"strings"
+	"testing"
+)
+
+// a diagnostic expected at a line and a column
+type expected struct {
+	line, column int
+	code         string
+	message      string
+}
+
+func parse(sourceCode string) []*context.Diagnostic {
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: "test.mist"}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	_, parseDiagnostics := NewLangParser(true, tokens).ParseAll()
+	return append(diagnostics, parseDiagnostics...)
+}
+
+func TestParserRecovers(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []expected
+	}{
+		{"valid", "global n = 1\nfunc f(x) = { x * this.n }\n", nil},
+		{"an error per statement", "func f() {\n  println(1 +)\n}\nfunc g() {\n  local x = \n}\nprintln(unknown)\n", []expected{
+			{2, 14, context.CodeSyntax, "Unexpected! (CloseCurve )"},
+			{6, 1, context.CodeSyntax, "Unexpected! (CloseCurly }"},
+			{7, 9, context.CodeUnresolved, "Cannot find symbol 'unknown'"},
+		}},
+		{"unexpected character", "global x = 1 $ 2\n", []expected{
+			{1, 14, context.CodeLexical, "Unexpected character '$'"},
+		}},
+		{"unterminated text", "println(\"abc)\nprintln(1)\n", []expected{
+			{1, 9, context.CodeLexical, "Unterminated text literal"},
+			{1, 8, context.CodeSyntax, "Unexpected end of file, was expecting type CloseCurve"},
+		}},
+		{"text over several lines", "println(\"a\nb\")\nprintln(x)\n", []expected{
+			{3, 9, context.CodeUnresolved, "Cannot find symbol 'x'"},
+		}},
+		{"unclosed body", "if (true) {\n  println(1)\n\nprintln(2)\n", []expected{
+			{4, 10, context.CodeSyntax, "Unexpected end of file, was expecting type CloseCurly"},
+		}},
+		{"unclosed list", "global a = [1, 2\nglobal b = 3\n", []expected{
+			{2, 1, context.CodeSyntax, "Expected type CloseSquare but got (Global global)"},
+		}},
+		{"undefined component", "when Button1.Click {\n}\n", []expected{
+			{1, 6, context.CodeSyntax, "Undefined component Button1"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := parse(test.source)
+			if len(diagnostics) != len(test.expected) {
+				t.Fatalf("expected %d diagnostics but got %v", len(test.expected), diagnostics)
+			}
+			for i, diagnostic := range diagnostics {
+				expected := test.expected[i]
+				if diagnostic.Line != expected.line || diagnostic.Column != expected.column {
+					t.Errorf("expected %q at %d:%d but got %d:%d", expected.message, expected.line, expected.column,
+						diagnostic.Line, diagnostic.Column)
+				}
+				if diagnostic.Severity != context.SeverityError || diagnostic.Code != expected.code ||
+					!strings.Contains(diagnostic.Message, expected.message) {
+					t.Errorf("expected the %s error %q but got the %s %s %q", expected.code, expected.message,
+						diagnostic.Code, diagnostic.Severity, diagnostic.Message)
+				}
+				if diagnostic.File != "test.mist" {
+					t.Errorf("expected the diagnostic in test.mist but got %q", diagnostic.File)
+				}
+			}
+		})
+	}
+}
+
+func TestMethodsGivingNothingBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []expected
+	}{
+		{"statement", "@Notifier { Notifier1 }\nNotifier1.ShowAlert(\"a\")\n", nil},
+		{"statement of a body", "@Notifier { Notifier1 }\nif (true) {\n  Notifier1.ShowAlert(\"a\")\n}\n", nil},
+		{"value giving a result", "@Clock { Clock1 }\nprintln(Clock1.Now())\n", nil},
+		{"argument", "@Notifier { Notifier1 }\nprintln(Notifier1.ShowAlert(\"a\"))\n", []expected{
+			{2, 19, context.CodeSyntax, "Expected a consumable but got a statement"},
+		}},
+		{"value of a local", "@Notifier { Notifier1 }\nfunc f() {\n  local x = Notifier1.ShowAlert(\"a\")\n  println(x)\n}\n",
+			[]expected{{3, 23, context.CodeSyntax, "Expected a consumable but got a statement"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := parse(test.source)
+			if len(diagnostics) != len(test.expected) {
+				t.Fatalf("expected %d diagnostics but got %v", len(test.expected), diagnostics)
+			}
+			for i, diagnostic := range diagnostics {
+				expected := test.expected[i]
+				if diagnostic.Line != expected.line || diagnostic.Column != expected.column ||
+					diagnostic.Code != expected.code || diagnostic.Message != expected.message {
+					t.Errorf("expected %q at %d:%d but got %q at %d:%d", expected.message, expected.line, expected.column,
+						diagnostic.Message, diagnostic.Line, diagnostic.Column)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package misttest
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"testing"
+)
+
+// FileName is the name the sources are parsed under, it's the file of their diagnostics
+const FileName = "test.mist"
+
+// Program declares a global, a function giving a value and a procedure
+const Program = `global n = 1
+
+func double(x) = { x * 2 }
+
+func greet() {
+  println("hi" _ this.n)
+}
+`
+
+// Parse parses the source and fails the test on any diagnostic
+func Parse(t testing.TB, sourceCode string) (*mistparser.LangParser, []ast.Expr) {
+	t.Helper()
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: FileName}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	parser := mistparser.NewLangParser(true, tokens)
+	expressions, parseDiagnostics := parser.ParseAll()
+	if diagnostics = append(diagnostics, parseDiagnostics...); len(diagnostics) > 0 {
+		t.Fatalf("the source doesn't parse: %v", diagnostics)
+	}
+	return parser, expressions
+}
+
+// Blocks compiles the source into its root blocks, with no id or position yet
+func Blocks(t testing.TB, sourceCode string) []ast.Block {
+	t.Helper()
+	_, expressions := Parse(t, sourceCode)
+	blocks := make([]ast.Block, len(expressions))
+	for i, expression := range expressions {
+		blocks[i] = ast.RootBlock(expression)
+	}
+	return blocks
+}
@@ -1,20 +1,33 @@
 package mistparser
 
 import (
+	"Falcon/code/lex"
 	"Falcon/code/sugar"
+	"Falcon/components/registry"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 type NameResolver struct {
 	Procedures        map[string]*Procedure
 	ComponentTypesMap map[string]string // Button1 -> Button
 	ComponentNameMap  map[string][]string
+	Globals           map[string]*lex.Token // where global variables are declared
+
+	// Components describes the component types, the members of a type it doesn't know are not checked
+	Components *registry.Registry
 }
 
 type Procedure struct {
+	Where      *lex.Token
 	Name       string
 	Parameters []string
 	Returning  bool
+
+	// optional type annotations, an empty type is not annotated
+	ParameterTypes []string
+	ReturnType     string
 }
 
 func (n *NameResolver) ResolveProcedure(name string, argsCount int) (string, *Procedure) {
@@ -29,3 +42,68 @@ func (n *NameResolver) ResolveProcedure(name string, argsCount int) (string, *Pr
 	}
 	return sugar.Format("Did not find procedure %()", name), nil
 }
+
+// ResolveMethod checks the method is known to the component type and is given all its arguments
+func (n *NameResolver) ResolveMethod(componentType string, name string, argsCount int) (string, *registry.Method) {
+	component, known := n.Components.Component(componentType)
+	if !known {
+		return "", nil
+	}
+	method, found := component.Method(name)
+	if !found {
+		return sugar.Format("% has no method %()", componentType, name), nil
+	}
+	if len(method.Params) != argsCount {
+		return sugar.Format(
+			"Expected % args but got % for method %.%()",
+			strconv.Itoa(len(method.Params)), strconv.Itoa(argsCount), componentType, name), nil
+	}
+	return "", method
+}
+
+// ResolveProperty checks the property is known to the component type and can be set or read
+func (n *NameResolver) ResolveProperty(componentType string, name string, set bool) (string, *registry.BlockProperty) {
+	component, known := n.Components.Component(componentType)
+	if !known {
+		return "", nil
+	}
+	property, found := component.Property(name)
+	if !found || property.RW == "invisible" {
+		return sugar.Format("% has no property %", componentType, name), nil
+	}
+	if set && !property.Writable() {
+		return sugar.Format("Property %.% is read-only", componentType, name), nil
+	}
+	if !set && !property.Readable() {
+		return sugar.Format("Property %.% is write-only", componentType, name), nil
+	}
+	return "", property
+}
+
+// ResolveEvent checks the event is known to the component type, the parameters are optional but
+// when they are given, they must be named after the ones of the event. A generic event may also
+// name the component and notAlreadyHandled parameters first.
+func (n *NameResolver) ResolveEvent(componentType string, name string, parameters []string, generic bool) string {
+	component, known := n.Components.Component(componentType)
+	if !known {
+		return ""
+	}
+	event, found := component.Event(name)
+	if !found {
+		return sugar.Format("% has no event %", componentType, name)
+	}
+	if len(parameters) == 0 {
+		return ""
+	}
+	expected := event.ParameterNames()
+	if slices.Equal(parameters, expected) {
+		return ""
+	}
+	if generic {
+		withComponent := append([]string{"component", "notAlreadyHandled"}, expected...)
+		if slices.Equal(parameters, withComponent) {
+			return ""
+		}
+	}
+	return sugar.Format("Expected the parameters (%) for event %.%", strings.Join(expected, ", "), componentType, name)
+}
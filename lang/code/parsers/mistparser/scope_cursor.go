@@ -18,6 +18,7 @@ const (
 	ScopeIfBody
 	ScopeSmartBody
 	ScopeTypeTransform
+	ScopeTest
 )
 
 type ScopeCursor struct {
@@ -48,6 +49,10 @@ func (s *ScopeCursor) checkScope(where *lex.Token, t ScopeType) {
 		if depth != 1 {
 			where.Error("Events can only be defined at the root.")
 		}
+	} else if t == ScopeTest {
+		if depth != 1 {
+			where.Error("Tests can only be defined at the root.")
+		}
 	}
 }
 
@@ -64,6 +69,20 @@ func (s *ScopeCursor) Exit(t ScopeType) {
 	s.currScope = s.allScopes[len(s.allScopes)-1]
 }
 
+// Depth returns the number of scopes currently open, including the root scope
+func (s *ScopeCursor) Depth() int {
+	return len(s.allScopes)
+}
+
+// Unwind exits the scopes left open by a statement that failed to parse
+func (s *ScopeCursor) Unwind(depth int) {
+	if depth < 1 || depth > len(s.allScopes) {
+		return
+	}
+	s.allScopes = s.allScopes[:depth]
+	s.currScope = s.allScopes[depth-1]
+}
+
 func (s *ScopeCursor) DefineVariable(name string, signature []ast.Signature) {
 	s.currScope.DefineVariable(name, signature)
 }
@@ -0,0 +1,263 @@
+package sourcemap
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"hash/fnv"
+	"strconv"
+)
+
+// Range is the source of a block, from the start of its first token to the end of its last one
+type Range struct {
+	File        string `json:"file"`
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+}
+
+type Mapping struct {
+	BlockId string `json:"blockId"`
+	Range
+}
+
+// SourceMap maps the blocks of a workspace to the ranges of the Falcon source they stand for
+type SourceMap struct {
+	Version  int       `json:"version"`
+	Mappings []Mapping `json:"mappings"`
+}
+
+const version = 1
+
+// Range returns the source of the block of that id
+func (s *SourceMap) Range(blockId string) (Range, bool) {
+	for _, mapping := range s.Mappings {
+		if mapping.BlockId == blockId {
+			return mapping.Range, true
+		}
+	}
+	return Range{}, false
+}
+
+// Block returns the id of the innermost block whose source holds the position
+func (s *SourceMap) Block(line int, column int) (string, bool) {
+	best := -1
+	for i, mapping := range s.Mappings {
+		if !mapping.holds(line, column) {
+			continue
+		}
+		if best < 0 || s.Mappings[best].holdsRange(mapping.Range) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	return s.Mappings[best].BlockId, true
+}
+
+func (r Range) holds(line int, column int) bool {
+	if line < r.StartLine || line > r.EndLine {
+		return false
+	}
+	if line == r.StartLine && column < r.StartColumn {
+		return false
+	}
+	return line != r.EndLine || column < r.EndColumn
+}
+
+func (r Range) holdsRange(other Range) bool {
+	return r.holds(other.StartLine, other.StartColumn) &&
+		(other.EndLine < r.EndLine || other.EndLine == r.EndLine && other.EndColumn <= r.EndColumn)
+}
+
+// AssignIds gives an id to every block that has none. The id is derived from the declaration of
+// the root block and the inputs that lead to the block, so that it stays the same when the other
+// blocks are edited.
+func AssignIds(blocks []ast.Block) {
+	seen := map[string]int{}
+	for i := range blocks {
+		root := blocks[i].Declaration()
+		if root == "" {
+			root = "block"
+		}
+		seen[root]++
+		if seen[root] > 1 || root == "block" {
+			root += "#" + strconv.Itoa(seen[root])
+		}
+		assignIds(&blocks[i], root)
+	}
+}
+
+func assignIds(block *ast.Block, path string) {
+	if block.ID == "" {
+		block.ID = blockId(path)
+	}
+	for i := range block.Values {
+		assignIds(&block.Values[i].Block, path+"/"+block.Values[i].Name)
+	}
+	for _, statement := range block.Statements {
+		if statement.Block != nil {
+			assignIds(statement.Block, path+"/"+statement.Name)
+		}
+	}
+	if block.Next != nil && block.Next.Block != nil {
+		assignIds(block.Next.Block, path+"+")
+	}
+}
+
+func blockId(path string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(path))
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
+
+// FromSource maps the blocks generated from the source to the ranges of the expressions they
+// were generated from. The blocks must have their ids.
+func FromSource(blocks []ast.Block, located []mistparser.LocatedExpr) *SourceMap {
+	ranges := map[ast.Expr]Range{}
+	for _, locatedExpr := range located {
+		exprRange, ok := rangeOf(locatedExpr)
+		if !ok {
+			continue
+		}
+		// an expression is located again by the rules that enclose it, the widest range is kept
+		if known, found := ranges[locatedExpr.Expr]; !found || exprRange.holdsRange(known) {
+			ranges[locatedExpr.Expr] = exprRange
+		}
+	}
+	sourceMap := &SourceMap{Version: version, Mappings: []Mapping{}}
+	var visit func(block *ast.Block)
+	visit = func(block *ast.Block) {
+		if exprRange, ok := ranges[block.Expr]; ok && block.Expr != nil && block.ID != "" {
+			sourceMap.Mappings = append(sourceMap.Mappings, Mapping{BlockId: block.ID, Range: exprRange})
+		}
+		eachChild(block, visit)
+	}
+	for i := range blocks {
+		visit(&blocks[i])
+	}
+	return sourceMap
+}
+
+func rangeOf(located mistparser.LocatedExpr) (Range, bool) {
+	start, end := located.Start.Span(), located.End.Span()
+	if start.Line == 0 || end.Line == 0 {
+		// tokens made up by the parser
+		return Range{}, false
+	}
+	return Range{
+		File:        start.File,
+		StartLine:   start.Line,
+		StartColumn: start.Column,
+		EndLine:     end.Line,
+		EndColumn:   end.EndColumn,
+	}, true
+}
+
+func eachChild(block *ast.Block, fn func(child *ast.Block)) {
+	for i := range block.Values {
+		fn(&block.Values[i].Block)
+	}
+	for _, statement := range block.Statements {
+		if statement.Block != nil {
+			fn(statement.Block)
+		}
+	}
+	if block.Next != nil && block.Next.Block != nil {
+		fn(block.Next.Block)
+	}
+}
+
+// FromWorkspace maps the blocks of a workspace to the source decompiled from them. The source is
+// parsed again and the blocks it generates are paired with the ones of the workspace, as far as
+// they have the same shape.
+func FromWorkspace(workspace []ast.Block, fileName string, sourceCode string) *SourceMap {
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: fileName}
+	tokens, _ := lex.NewLexer(codeContext).Lex()
+	parser := mistparser.NewLangParser(false, tokens)
+	definitions, reverseDefinitions := componentsOf(workspace)
+	parser.SetComponentDefinitions(definitions, reverseDefinitions)
+	expressions, diagnostics := parser.ParseAll()
+	if context.HasErrors(diagnostics) || len(expressions) != len(workspace) {
+		// the blocks can't be paired with the ones of the workspace
+		return &SourceMap{Version: version, Mappings: []Mapping{}}
+	}
+	generated := make([]ast.Block, len(expressions))
+	for i, expression := range expressions {
+		generated[i] = ast.RootBlock(expression)
+	}
+	AssignIds(generated)
+	sourceMap := FromSource(generated, parser.Located)
+	byGenerated := map[string]Range{}
+	for _, mapping := range sourceMap.Mappings {
+		byGenerated[mapping.BlockId] = mapping.Range
+	}
+	sourceMap.Mappings = []Mapping{}
+	var pair func(original *ast.Block, generated *ast.Block)
+	pair = func(original *ast.Block, generated *ast.Block) {
+		if original.Type != generated.Type {
+			return
+		}
+		if exprRange, ok := byGenerated[generated.ID]; ok && original.ID != "" {
+			sourceMap.Mappings = append(sourceMap.Mappings, Mapping{BlockId: original.ID, Range: exprRange})
+		}
+		for _, value := range original.Values {
+			if other := valueNamed(generated, value.Name); other != nil {
+				pair(&value.Block, other)
+			}
+		}
+		for _, statement := range original.Statements {
+			if other := statementNamed(generated, statement.Name); other != nil && statement.Block != nil {
+				pair(statement.Block, other)
+			}
+		}
+		if original.Next != nil && original.Next.Block != nil && generated.Next != nil && generated.Next.Block != nil {
+			pair(original.Next.Block, generated.Next.Block)
+		}
+	}
+	for i := range workspace {
+		pair(&workspace[i], &generated[i])
+	}
+	return sourceMap
+}
+
+func valueNamed(block *ast.Block, name string) *ast.Block {
+	for i := range block.Values {
+		if block.Values[i].Name == name {
+			return &block.Values[i].Block
+		}
+	}
+	return nil
+}
+
+func statementNamed(block *ast.Block, name string) *ast.Block {
+	for _, statement := range block.Statements {
+		if statement.Name == name {
+			return statement.Block
+		}
+	}
+	return nil
+}
+
+// componentsOf collects the components the blocks refer to, the decompiled source declares none
+func componentsOf(blocks []ast.Block) (map[string][]string, map[string]string) {
+	definitions := map[string][]string{}
+	reverseDefinitions := map[string]string{}
+	var visit func(block *ast.Block)
+	visit = func(block *ast.Block) {
+		if mutation := block.Mutation; mutation != nil && mutation.InstanceName != "" && mutation.ComponentType != "" {
+			if _, known := reverseDefinitions[mutation.InstanceName]; !known {
+				reverseDefinitions[mutation.InstanceName] = mutation.ComponentType
+				definitions[mutation.ComponentType] = append(definitions[mutation.ComponentType], mutation.InstanceName)
+			}
+		}
+		eachChild(block, visit)
+	}
+	for i := range blocks {
+		visit(&blocks[i])
+	}
+	return definitions, reverseDefinitions
+}
@@ -0,0 +1,124 @@
+package sourcemap
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/code/parsers/mistparser/misttest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// compile generates the blocks of the source with their ids, along with the parser that located them
+func compile(t *testing.T, sourceCode string) ([]ast.Block, *mistparser.LangParser) {
+	t.Helper()
+	parser, expressions := misttest.Parse(t, sourceCode)
+	blocks := make([]ast.Block, len(expressions))
+	for i, expression := range expressions {
+		blocks[i] = ast.RootBlock(expression)
+	}
+	AssignIds(blocks)
+	return blocks, parser
+}
+
+func TestFromSource(t *testing.T) {
+	blocks, parser := compile(t, misttest.Program)
+	sourceMap := FromSource(blocks, parser.Located)
+	tests := []struct {
+		name      string
+		line      int
+		column    int
+		blockType string
+		expected  Range
+	}{
+		{"global", 1, 1, "global_declaration", Range{misttest.FileName, 1, 1, 1, 13}},
+		{"value of the global", 1, 12, "math_number", Range{misttest.FileName, 1, 12, 1, 13}},
+		{"procedure", 3, 1, "procedures_defreturn", Range{misttest.FileName, 3, 1, 3, 27}},
+		{"operand", 3, 20, "lexical_variable_get", Range{misttest.FileName, 3, 20, 3, 21}},
+		{"statement", 6, 3, "controls_eval_but_ignore", Range{misttest.FileName, 6, 3, 6, 25}},
+		{"text of a join", 6, 11, "text", Range{misttest.FileName, 6, 11, 6, 15}},
+	}
+	byId := map[string]*ast.Block{}
+	var visit func(block *ast.Block)
+	visit = func(block *ast.Block) {
+		byId[block.ID] = block
+		eachChild(block, visit)
+	}
+	for i := range blocks {
+		visit(&blocks[i])
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, found := sourceMap.Block(test.line, test.column)
+			if !found {
+				t.Fatalf("no block at %d:%d", test.line, test.column)
+			}
+			if block := byId[id]; block == nil || block.Type != test.blockType {
+				t.Fatalf("expected a %s block at %d:%d but got %v", test.blockType, test.line, test.column, block)
+			}
+			if got, _ := sourceMap.Range(id); got != test.expected {
+				t.Errorf("expected the range %+v but got %+v", test.expected, got)
+			}
+		})
+	}
+	if _, found := sourceMap.Block(2, 1); found {
+		t.Errorf("expected no block on an empty line")
+	}
+}
+
+func TestIdsStayWhenOtherBlocksChange(t *testing.T) {
+	before, _ := compile(t, misttest.Program)
+	// the ids follow the declarations, whatever their place and their content
+	after, _ := compile(t, strings.Replace(misttest.Program, "global n = 1", "global m = 3\n\nglobal n = 2", 1))
+	for i := range before {
+		if before[i].ID != after[i+1].ID {
+			t.Errorf("expected %s to keep the id %s but got %s", before[i].Declaration(), before[i].ID, after[i+1].ID)
+		}
+	}
+	if after[0].ID == before[0].ID {
+		t.Errorf("expected the new global to get an id of its own")
+	}
+	seen := map[string]bool{}
+	var visit func(block *ast.Block)
+	visit = func(block *ast.Block) {
+		if seen[block.ID] {
+			t.Errorf("the id %s is given twice", block.ID)
+		}
+		seen[block.ID] = true
+		eachChild(block, visit)
+	}
+	twice, _ := compile(t, "println(1)\nprintln(1)\n")
+	for i := range twice {
+		visit(&twice[i])
+	}
+}
+
+func TestFromWorkspace(t *testing.T) {
+	workspace, _ := compile(t, misttest.Program)
+	// Blockly gives the blocks ids of its own
+	var rename func(block *ast.Block, path string)
+	rename = func(block *ast.Block, path string) {
+		block.ID = path
+		for i := range block.Values {
+			rename(&block.Values[i].Block, path+"."+block.Values[i].Name)
+		}
+		for _, statement := range block.Statements {
+			if statement.Block != nil {
+				rename(statement.Block, path+"."+statement.Name)
+			}
+		}
+	}
+	for i := range workspace {
+		rename(&workspace[i], "b"+strconv.Itoa(i))
+	}
+	sourceMap := FromWorkspace(workspace, "decompiled.mist", misttest.Program)
+	if got, _ := sourceMap.Range("b2.STACK"); got != (Range{"decompiled.mist", 6, 3, 6, 25}) {
+		t.Errorf("expected the println of greet at 6:3 but got %+v", got)
+	}
+	if id, _ := sourceMap.Block(3, 20); id != "b1.RETURN.NUM0" {
+		t.Errorf("expected the operand of double at 3:20 but got %s", id)
+	}
+	if empty := FromWorkspace(workspace, "decompiled.mist", "global n = 1\n"); len(empty.Mappings) != 0 {
+		t.Errorf("expected no mapping for a source of other blocks but got %+v", empty.Mappings)
+	}
+}
@@ -0,0 +1,64 @@
+// Command generate writes the component registry from the simple_components.json of an App Inventor build.
+// The descriptions are left out, the registry only types the blocks and checks the designs.
+package main
+
+import (
+	"Falcon/components/registry"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	output := flag.String("o", "", "the file to write, the standard output when empty")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: generate [-o file] simple_components.json")
+		os.Exit(2)
+	}
+	if err := generate(flag.Arg(0), *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(input string, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	var components []*registry.Component
+	if err := json.Unmarshal(data, &components); err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+	if len(components) == 0 {
+		return fmt.Errorf("%s has no components", input)
+	}
+	for _, component := range components {
+		component.HelpString = ""
+		for i := range component.BlockProperties {
+			component.BlockProperties[i].Description = ""
+		}
+		for i := range component.Events {
+			component.Events[i].Description = ""
+		}
+		for i := range component.Methods {
+			component.Methods[i].Description = ""
+		}
+	}
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	// the colors of the designer start with &H
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(components); err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(content.Bytes())
+		return err
+	}
+	return os.WriteFile(output, content.Bytes(), 0644)
+}
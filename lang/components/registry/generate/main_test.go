@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateKeepsTheRegistry(t *testing.T) {
+	embedded := filepath.Join("..", "simple_components.json")
+	output := filepath.Join(t.TempDir(), "simple_components.json")
+	if err := generate(embedded, output); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile(embedded)
+	if got, _ := os.ReadFile(output); string(got) != string(want) {
+		t.Errorf("the registry changed when generated again, run go generate")
+	}
+}
@@ -0,0 +1,277 @@
+package registry
+
+import (
+	"Falcon/code/ast"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// the simple_components.json built by App Inventor, without the descriptions. Regenerate it from
+// the one of an App Inventor build with SIMPLE_COMPONENTS set to its path.
+//
+//go:generate go run ./generate -o simple_components.json $SIMPLE_COMPONENTS
+//go:embed simple_components.json
+var simpleComponents []byte
+
+// Component describes a component type the way App Inventor does in simple_components.json
+type Component struct {
+	Type            string             `json:"type"`
+	Name            string             `json:"name"`
+	External        string             `json:"external"`
+	Version         string             `json:"version"`
+	CategoryString  string             `json:"categoryString"`
+	HelpString      string             `json:"helpString"`
+	ShowOnPalette   string             `json:"showOnPalette"`
+	NonVisible      string             `json:"nonVisible"`
+	IconName        string             `json:"iconName"`
+	Properties      []DesignerProperty `json:"properties"`
+	BlockProperties []BlockProperty    `json:"blockProperties"`
+	Events          []Event            `json:"events"`
+	Methods         []Method           `json:"methods"`
+}
+
+// DesignerProperty is a property set in the designer, stored in the .scm file
+type DesignerProperty struct {
+	Name         string   `json:"name"`
+	EditorType   string   `json:"editorType"`
+	DefaultValue string   `json:"defaultValue"`
+	EditorArgs   []string `json:"editorArgs"`
+}
+
+// BlockProperty is a property the blocks can get or set
+type BlockProperty struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	RW          string `json:"rw"`
+	Deprecated  string `json:"deprecated"`
+}
+
+type Parameter struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Event struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Deprecated  string      `json:"deprecated"`
+	Params      []Parameter `json:"params"`
+}
+
+type Method struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Deprecated  string      `json:"deprecated"`
+	Params      []Parameter `json:"params"`
+	ReturnType  string      `json:"returnType,omitempty"`
+}
+
+// Registry knows the components by their name, such as Button
+type Registry struct {
+	components map[string]*Component
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns the registry of the embedded components, it must not be modified
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry = New()
+		if err := defaultRegistry.Add(simpleComponents); err != nil {
+			panic(err)
+		}
+	})
+	return defaultRegistry
+}
+
+func New() *Registry {
+	return &Registry{components: map[string]*Component{}}
+}
+
+// Load creates a registry from the content of a simple_components.json file
+func Load(data []byte) (*Registry, error) {
+	registry := New()
+	if err := registry.Add(data); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Add reads component descriptors, either a list of them like simple_components.json and the
+// components.json of an extension, or a single descriptor. A component of the same name is replaced.
+func (r *Registry) Add(data []byte) error {
+	var descriptors []*Component
+	if err := json.Unmarshal(data, &descriptors); err != nil {
+		var descriptor Component
+		if json.Unmarshal(data, &descriptor) != nil {
+			return err
+		}
+		descriptors = []*Component{&descriptor}
+	}
+	for _, descriptor := range descriptors {
+		if descriptor.Name == "" {
+			return errors.New("a component descriptor has no name")
+		}
+		r.components[descriptor.Name] = descriptor
+	}
+	return nil
+}
+
+// Extend returns a copy of the registry with the component descriptors added
+func (r *Registry) Extend(data []byte) (*Registry, error) {
+	extended := New()
+	for name, component := range r.components {
+		extended.components[name] = component
+	}
+	if err := extended.Add(data); err != nil {
+		return nil, err
+	}
+	return extended, nil
+}
+
+// Component returns the component type of that name, such as Button
+func (r *Registry) Component(name string) (*Component, bool) {
+	component, ok := r.components[name]
+	return component, ok
+}
+
+// Names returns the names of the component types, sorted
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.components))
+}
+
+// EditorType is the editor of the designer properties of that name, when the components that have one
+// agree on it, such as color for BackgroundColor
+func (r *Registry) EditorType(property string) (string, bool) {
+	editorType := ""
+	for _, component := range r.components {
+		designerProperty, found := component.DesignerProperty(property)
+		if !found {
+			continue
+		}
+		if editorType != "" && designerProperty.EditorType != editorType {
+			return "", false
+		}
+		editorType = designerProperty.EditorType
+	}
+	return editorType, editorType != ""
+}
+
+func (c *Component) IsExternal() bool {
+	return c.External == "true"
+}
+
+func (c *Component) IsNonVisible() bool {
+	return c.NonVisible == "true"
+}
+
+func (c *Component) Property(name string) (*BlockProperty, bool) {
+	for i := range c.BlockProperties {
+		if c.BlockProperties[i].Name == name {
+			return &c.BlockProperties[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Component) DesignerProperty(name string) (*DesignerProperty, bool) {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Component) Event(name string) (*Event, bool) {
+	for i := range c.Events {
+		if c.Events[i].Name == name {
+			return &c.Events[i], true
+		}
+	}
+	return nil, false
+}
+
+// the events most apps handle, by component type
+var primaryEvents = map[string][]string{
+	"Form":       {"Initialize"},
+	"Button":     {"Click"},
+	"CheckBox":   {"Changed"},
+	"ListPicker": {"AfterPicking"},
+	"Slider":     {"PositionChanged"},
+	"Notifier":   {"AfterChoosing"},
+	"Clock":      {"Timer"},
+	"Web":        {"GotText"},
+}
+
+// PrimaryEvents returns the events most apps handle for the component, such as the Click of a Button.
+// Other components and the extensions have none.
+func (c *Component) PrimaryEvents() []*Event {
+	var events []*Event
+	for _, name := range primaryEvents[c.Name] {
+		if event, found := c.Event(name); found {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (c *Component) Method(name string) (*Method, bool) {
+	for i := range c.Methods {
+		if c.Methods[i].Name == name {
+			return &c.Methods[i], true
+		}
+	}
+	return nil, false
+}
+
+func (p *BlockProperty) Readable() bool {
+	return p.RW != "write-only" && p.RW != "invisible"
+}
+
+func (p *BlockProperty) Writable() bool {
+	return p.RW != "read-only" && p.RW != "invisible"
+}
+
+// ParameterNames returns the names of the parameters of the event
+func (e *Event) ParameterNames() []string {
+	names := make([]string, len(e.Params))
+	for i, param := range e.Params {
+		names[i] = param.Name
+	}
+	return names
+}
+
+// Returns tells if the method gives back a value
+func (m *Method) Returns() bool {
+	return m.ReturnType != ""
+}
+
+// Signature converts a type of the descriptors, the instants and the helper types are any value
+func Signature(typeName string) ast.Signature {
+	switch typeName {
+	case "number":
+		return ast.SignNumb
+	case "text":
+		return ast.SignText
+	case "boolean":
+		return ast.SignBool
+	case "list":
+		return ast.SignList
+	case "dictionary":
+		return ast.SignDict
+	case "component":
+		return ast.SignComponent
+	case "":
+		return ast.SignVoid
+	}
+	return ast.SignAny
+}
@@ -0,0 +1,38 @@
+package registry
+
+import "testing"
+
+func TestDefaultKnowsTheStandardComponents(t *testing.T) {
+	names := []string{"Form", "Button", "ListView", "Player", "Sound", "Canvas", "ImageSprite", "Ball", "Switch",
+		"Spinner", "WebViewer", "TableArrangement", "LocationSensor", "File", "CloudDB", "BluetoothClient", "Map"}
+	for _, name := range names {
+		if _, found := Default().Component(name); !found {
+			t.Errorf("the registry doesn't know the %s component", name)
+		}
+	}
+	form, _ := Default().Component("Form")
+	for _, property := range []string{"AccentColor", "ActionBar", "PrimaryColorDark", "DefaultFileScope"} {
+		if _, found := form.DesignerProperty(property); !found {
+			t.Errorf("the registry doesn't know the %s property of Form", property)
+		}
+	}
+}
+
+func TestDefaultDescriptors(t *testing.T) {
+	for _, name := range Default().Names() {
+		component, _ := Default().Component(name)
+		if component.Type == "" || component.Version == "" || component.CategoryString == "" {
+			t.Errorf("the descriptor of %s is incomplete", name)
+		}
+		for _, property := range component.Properties {
+			if property.EditorType == "" {
+				t.Errorf("the property %s of %s has no editor", property.Name, name)
+			}
+		}
+		for _, property := range component.BlockProperties {
+			if property.Type == "" || property.RW == "" {
+				t.Errorf("the block property %s of %s has no type or access", property.Name, name)
+			}
+		}
+	}
+}
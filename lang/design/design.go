@@ -0,0 +1,36 @@
+package design
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseDesign reads a screen design, either an .aiml file or the JSON of an .scm file
+func ParseDesign(content string) (*Component, error) {
+	if IsSchema(content) {
+		return NewSchemaParser(content).ParseScreen()
+	}
+	return NewXmlParser(content).ParseScreen()
+}
+
+// IsSchema tells whether the design is the JSON of an .scm file rather than an .aiml file
+func IsSchema(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "#|")
+}
+
+// FindDesign returns the path of the design next to the source file, Screen1.aiml or Screen1.scm
+// for Screen1.mist, or an empty path when there's none
+func FindDesign(sourcePath string) string {
+	if sourcePath == "" || sourcePath == "-" {
+		return ""
+	}
+	base := strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+	for _, extension := range []string{".aiml", ".scm"} {
+		if _, err := os.Stat(base + extension); err == nil {
+			return base + extension
+		}
+	}
+	return ""
+}
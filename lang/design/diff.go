@@ -0,0 +1,318 @@
+package design
+
+import (
+	"strconv"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	Renamed ChangeKind = "rename"
+	Added   ChangeKind = "add"
+	Moved   ChangeKind = "move"
+	Removed ChangeKind = "remove"
+	Set     ChangeKind = "set"
+)
+
+// Change is a change of a component, the components are known by their ids once the renames before
+// the change are made
+type Change struct {
+	Kind  ChangeKind `json:"kind"`
+	Id    string     `json:"id"`
+	NewId string     `json:"newId,omitempty"` // rename
+	Type  string     `json:"type,omitempty"`  // add, remove
+	// Parent is the component the component is added or moved to, it follows the After component
+	// or comes first when there's none
+	Parent  string  `json:"parent,omitempty"`
+	After   string  `json:"after,omitempty"`
+	Entries []Entry `json:"entries,omitempty"` // add
+	// Old and New are the values of the property before and after the change, nil when there's none
+	Property string `json:"property,omitempty"`
+	Old      *Entry `json:"old,omitempty"`
+	New      *Entry `json:"new,omitempty"`
+}
+
+// Patch is the changes from a version of a screen design to another one, in the order they're made
+type Patch struct {
+	Version int       `json:"version"`
+	Changes []*Change `json:"changes"`
+}
+
+const patchVersion = 1
+
+func (c *Change) String() string {
+	switch c.Kind {
+	case Renamed:
+		return "rename " + c.Id + " to " + c.NewId
+	case Added:
+		text := "add " + c.Type + " " + c.Id + c.place()
+		var properties []string
+		for _, entry := range c.Entries {
+			properties = append(properties, entry.Key+"="+entry.text())
+		}
+		if len(properties) > 0 {
+			text += " with " + strings.Join(properties, ", ")
+		}
+		return text
+	case Moved:
+		return "move " + c.Id + c.place()
+	case Removed:
+		return "remove " + c.Type + " " + c.Id
+	}
+	switch {
+	case c.Old == nil:
+		return "set " + c.Id + "." + c.Property + " to " + c.New.text()
+	case c.New == nil:
+		return "unset " + c.Id + "." + c.Property + ", was " + c.Old.text()
+	}
+	return "set " + c.Id + "." + c.Property + " from " + c.Old.text() + " to " + c.New.text()
+}
+
+func (c *Change) place() string {
+	if c.After == "" {
+		return " first in " + c.Parent
+	}
+	return " in " + c.Parent + " after " + c.After
+}
+
+func (e *Entry) text() string {
+	if e.Raw {
+		return e.Value
+	}
+	return strconv.Quote(e.Value)
+}
+
+// node is a component of a design along with its place in it
+type node struct {
+	component *Component
+	parent    *node
+	children  []*node
+}
+
+func nodesOf(component *Component, parent *node, nodes *[]*node) *node {
+	aNode := &node{component: component, parent: parent}
+	*nodes = append(*nodes, aNode)
+	for k := range component.Children {
+		aNode.children = append(aNode.children, nodesOf(&component.Children[k], aNode, nodes))
+	}
+	return aNode
+}
+
+func (n *node) id() string {
+	if n == nil {
+		return ""
+	}
+	return n.component.Id
+}
+
+// Diff compares two versions of a screen design. The components are paired by their Uuid, else by
+// their id, a component of another id but of the same type and properties in the same place is renamed.
+func Diff(old *Component, new *Component) *Patch {
+	var oldNodes, newNodes []*node
+	oldRoot, newRoot := nodesOf(old, nil, &oldNodes), nodesOf(new, nil, &newNodes)
+	pairs := pair(oldRoot, newRoot, oldNodes, newNodes)
+	paired := map[*node]*node{} // new -> old
+	for oldNode, newNode := range pairs {
+		paired[newNode] = oldNode
+	}
+	patch := &Patch{Version: patchVersion, Changes: []*Change{}}
+	add := func(change *Change) {
+		patch.Changes = append(patch.Changes, change)
+	}
+
+	for _, oldNode := range oldNodes {
+		if newNode, found := pairs[oldNode]; found && oldNode.id() != newNode.id() {
+			add(&Change{Kind: Renamed, Id: oldNode.id(), NewId: newNode.id()})
+		}
+	}
+	// the components removed along with all they hold are removed first, so that their ids can
+	// be given again, the other ones once what they hold is moved out
+	var removedLast []*node
+	for _, oldNode := range oldNodes {
+		if _, found := pairs[oldNode]; found || oldNode.parent == nil {
+			continue
+		}
+		if _, parentFound := pairs[oldNode.parent]; !parentFound {
+			// removed along with its parent
+			continue
+		}
+		if holdsPaired(oldNode, pairs) {
+			removedLast = append(removedLast, oldNode)
+		} else {
+			add(&Change{Kind: Removed, Id: oldNode.id(), Type: oldNode.component.Type})
+		}
+	}
+	kept := keptInPlace(pairs)
+	for _, newNode := range newNodes[1:] {
+		after := ""
+		if index := indexOf(newNode.parent.children, newNode); index > 0 {
+			after = newNode.parent.children[index-1].id()
+		}
+		_, found := paired[newNode]
+		switch {
+		case !found:
+			add(&Change{
+				Kind:    Added,
+				Id:      newNode.id(),
+				Type:    newNode.component.Type,
+				Parent:  newNode.parent.id(),
+				After:   after,
+				Entries: newNode.component.orderedEntries(),
+			})
+		case !kept[newNode]:
+			add(&Change{Kind: Moved, Id: newNode.id(), Parent: newNode.parent.id(), After: after})
+		}
+	}
+	for _, oldNode := range removedLast {
+		add(&Change{Kind: Removed, Id: oldNode.id(), Type: oldNode.component.Type})
+	}
+	for _, newNode := range newNodes {
+		if oldNode, found := paired[newNode]; found {
+			for _, change := range propertyChanges(oldNode.component, newNode.component) {
+				add(change)
+			}
+		}
+	}
+	return patch
+}
+
+func indexOf(nodes []*node, aNode *node) int {
+	for i, other := range nodes {
+		if other == aNode {
+			return i
+		}
+	}
+	return -1
+}
+
+func holdsPaired(aNode *node, pairs map[*node]*node) bool {
+	for _, child := range aNode.children {
+		if _, found := pairs[child]; found || holdsPaired(child, pairs) {
+			return true
+		}
+	}
+	return false
+}
+
+// pair finds the components of the old version in the new one
+func pair(oldRoot *node, newRoot *node, oldNodes []*node, newNodes []*node) map[*node]*node {
+	pairs := map[*node]*node{oldRoot: newRoot}
+	taken := map[*node]bool{newRoot: true}
+	match := func(key func(n *node) string) {
+		byKey := map[string]*node{}
+		for _, newNode := range newNodes {
+			if k := key(newNode); k != "" && !taken[newNode] {
+				byKey[k] = newNode
+			}
+		}
+		for _, oldNode := range oldNodes {
+			if _, found := pairs[oldNode]; found {
+				continue
+			}
+			newNode, found := byKey[key(oldNode)]
+			if found && key(oldNode) != "" && !taken[newNode] && newNode.component.Type == oldNode.component.Type {
+				pairs[oldNode], taken[newNode] = newNode, true
+			}
+		}
+	}
+	match(func(n *node) string { return n.component.Properties["Uuid"] })
+	match(func(n *node) string { return n.component.Id })
+	// renamed, the same component in the same place
+	for _, oldNode := range oldNodes {
+		if _, found := pairs[oldNode]; found || oldNode.parent == nil {
+			continue
+		}
+		parent, found := pairs[oldNode.parent]
+		if !found {
+			continue
+		}
+		for _, newNode := range parent.children {
+			if !taken[newNode] && newNode.component.Type == oldNode.component.Type &&
+				len(propertyChanges(oldNode.component, newNode.component)) == 0 {
+				pairs[oldNode], taken[newNode] = newNode, true
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// keptInPlace finds the components that stay where they are, in the same parent and in the same
+// order as the most of their siblings
+func keptInPlace(pairs map[*node]*node) map[*node]bool {
+	kept := map[*node]bool{}
+	for oldNode, newNode := range pairs {
+		if len(oldNode.children) == 0 || len(newNode.children) == 0 {
+			continue
+		}
+		var before []*node // the new nodes of the children of the old node, that stay in the node
+		for _, child := range oldNode.children {
+			if pairedChild, found := pairs[child]; found && pairedChild.parent == newNode {
+				before = append(before, pairedChild)
+			}
+		}
+		for _, child := range longestCommon(before, newNode.children) {
+			kept[child] = true
+		}
+	}
+	return kept
+}
+
+func longestCommon(a []*node, b []*node) []*node {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	var common []*node
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common = append(common, a[i])
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+// propertyChanges lists the entries of the component that differ between the versions, the
+// properties along with the metadata
+func propertyChanges(old *Component, new *Component) []*Change {
+	oldEntries, newEntries := old.orderedEntries(), new.orderedEntries()
+	oldByKey := map[string]Entry{}
+	for _, entry := range oldEntries {
+		oldByKey[entry.Key] = entry
+	}
+	var changes []*Change
+	for _, entry := range newEntries {
+		oldEntry, found := oldByKey[entry.Key]
+		delete(oldByKey, entry.Key)
+		if found && oldEntry == entry {
+			continue
+		}
+		change := &Change{Kind: Set, Id: new.Id, Property: entry.Key, New: &entry}
+		if found {
+			change.Old = &oldEntry
+		}
+		changes = append(changes, change)
+	}
+	for _, entry := range oldEntries {
+		if _, removed := oldByKey[entry.Key]; removed {
+			changes = append(changes, &Change{Kind: Set, Id: new.Id, Property: entry.Key, Old: &entry})
+		}
+	}
+	return changes
+}
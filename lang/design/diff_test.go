@@ -0,0 +1,126 @@
+package design
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const oldDesign = `<Screen id="Screen1" Title="Pets" Uuid="0">
+  <VerticalArrangement id="VerticalArrangement1" Uuid="1">
+    <Button id="Button1" Text="Pet the Kitty" Uuid="2"/>
+    <Label id="Label1" Text="Purr" Uuid="3"/>
+  </VerticalArrangement>
+  <Sound id="Sound1" Source="meow.mp3" Uuid="4"/>
+</Screen>`
+
+func mustParse(t *testing.T, content string) *Component {
+	t.Helper()
+	screen, err := ParseDesign(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return screen
+}
+
+func xmlOf(t *testing.T, screen *Component) string {
+	t.Helper()
+	var content bytes.Buffer
+	if err := screen.WriteXML(&content, 0); err != nil {
+		t.Fatal(err)
+	}
+	return content.String()
+}
+
+func TestDiffAndPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		new     string
+		changes []string
+	}{
+		{"same design", oldDesign, nil},
+		{"property changed", strings.Replace(oldDesign, `Title="Pets"`, `Title="Kitty"`, 1),
+			[]string{`set Screen1.Title from "Pets" to "Kitty"`}},
+		// a property set goes after the others
+		{"property set", strings.Replace(oldDesign, `Uuid="3"/>`, `Uuid="3" FontBold="true"/>`, 1),
+			[]string{`set Label1.FontBold to "True"`}},
+		{"property unset", strings.Replace(oldDesign, ` Source="meow.mp3"`, "", 1),
+			[]string{`unset Sound1.Source, was "meow.mp3"`}},
+		{"renamed", strings.Replace(oldDesign, `id="Label1"`, `id="Caption"`, 1),
+			[]string{"rename Label1 to Caption"}},
+		{"added", strings.Replace(oldDesign, `<Sound id="Sound1"`, `<Player id="Player1" Uuid="5"/>
+  <Sound id="Sound1"`, 1),
+			[]string{`add Player Player1 in Screen1 after VerticalArrangement1 with Uuid="5"`}},
+		{"removed", strings.Replace(oldDesign, `    <Label id="Label1" Text="Purr" Uuid="3"/>
+`, "", 1),
+			[]string{"remove Label Label1"}},
+		{"moved", strings.Replace(strings.Replace(oldDesign, `    <Label id="Label1" Text="Purr" Uuid="3"/>
+`, "", 1), `<Sound id="Sound1"`, `<Label id="Label1" Text="Purr" Uuid="3"/>
+  <Sound id="Sound1"`, 1),
+			[]string{"move Label1 in Screen1 after VerticalArrangement1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch := Diff(mustParse(t, oldDesign), mustParse(t, test.new))
+			var changes []string
+			for _, change := range patch.Changes {
+				changes = append(changes, change.String())
+			}
+			if strings.Join(changes, "\n") != strings.Join(test.changes, "\n") {
+				t.Fatalf("expected the changes:\n%s\nbut got:\n%s", strings.Join(test.changes, "\n"), strings.Join(changes, "\n"))
+			}
+			// the patch goes through its JSON like the one design diff writes
+			content, err := json.Marshal(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			read, err := ReadPatch(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			screen := mustParse(t, oldDesign)
+			if err := read.Apply(screen); err != nil {
+				t.Fatal(err)
+			}
+			if patched, expected := xmlOf(t, screen), xmlOf(t, mustParse(t, test.new)); patched != expected {
+				t.Errorf("expected the patched design:\n%s\nbut got:\n%s", expected, patched)
+			}
+		})
+	}
+}
+
+func TestPatchConflicts(t *testing.T) {
+	patch := Diff(mustParse(t, oldDesign), mustParse(t, strings.Replace(oldDesign, `Text="Purr"`, `Text="Meow"`, 1)))
+	tests := []struct {
+		name     string
+		design   string
+		conflict string
+	}{
+		{"changed the same", strings.Replace(oldDesign, `Text="Purr"`, `Text="Meow"`, 1), ""},
+		{"changed otherwise", strings.Replace(oldDesign, `Text="Purr"`, `Text="Hiss"`, 1), `Text was changed to "Hiss"`},
+		{"removed", strings.Replace(oldDesign, ` Text="Purr"`, "", 1), "Text was removed"},
+		{"component removed", strings.Replace(oldDesign, `    <Label id="Label1" Text="Purr" Uuid="3"/>
+`, "", 1), "Label1 isn't in the design"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := patch.Apply(mustParse(t, test.design))
+			if test.conflict == "" {
+				if err != nil {
+					t.Errorf("unexpected conflict %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.conflict) {
+				t.Errorf("expected the conflict %s but got %v", test.conflict, err)
+			}
+		})
+	}
+}
+
+func TestReadPatchChecksTheVersion(t *testing.T) {
+	if _, err := ReadPatch([]byte(`{"version": 2, "changes": []}`)); err == nil {
+		t.Error("expected an unsupported version to be refused")
+	}
+}
@@ -0,0 +1,135 @@
+package design
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Entry is a key of a component in the .scm file, kept in the order it is written there so
+// that a design converted to .aiml and back is the same
+type Entry struct {
+	Key   string `json:"key"`           // such as Text, Uuid or $Version
+	Value string `json:"value"`         // the JSON of the value when it is Raw
+	Raw   bool   `json:"raw,omitempty"` // the value isn't a string, such as a number or a list
+}
+
+// the keys of a component in the .scm file that the .aiml file holds by its structure
+const (
+	nameKey       = "$Name"
+	typeKey       = "$Type"
+	versionKey    = "$Version"
+	componentsKey = "$Components"
+)
+
+// how the entries the .aiml file can't name as they are are written as attributes:
+// $Version is _Version, the YaVersion of the file is file.YaVersion and a list is authURL.json
+const (
+	metadataPrefix = "_"
+	filePrefix     = "file."
+	rawSuffix      = ".json"
+)
+
+// isMetadata tells whether the attribute of an .aiml element is kept for the .scm file
+// rather than being a designer property
+func isMetadata(attribute string) bool {
+	return strings.HasPrefix(attribute, metadataPrefix) || strings.HasPrefix(attribute, filePrefix) ||
+		strings.HasSuffix(attribute, rawSuffix)
+}
+
+// attributeName is the name of the .aiml attribute of the entry, fileEntry is set for the keys
+// that are outside the Properties of the .scm file
+func (e Entry) attributeName(fileEntry bool) string {
+	name := e.Key
+	if metadata, found := strings.CutPrefix(name, "$"); found {
+		name = metadataPrefix + metadata
+	}
+	if fileEntry {
+		name = filePrefix + name
+	}
+	if e.Raw {
+		name += rawSuffix
+	}
+	return name
+}
+
+// entryOf reads an attribute of an .aiml element back into the entry of the .scm file
+func entryOf(attribute string, value string) (entry Entry, fileEntry bool) {
+	name, raw := strings.CutSuffix(attribute, rawSuffix)
+	name, fileEntry = strings.CutPrefix(name, filePrefix)
+	if metadata, found := strings.CutPrefix(name, metadataPrefix); found {
+		name = "$" + metadata
+	}
+	return Entry{Key: name, Value: value, Raw: raw}, fileEntry
+}
+
+// isProperty tells whether the key is a designer property, one the blocks can see
+func isProperty(key string) bool {
+	return !strings.HasPrefix(key, "$")
+}
+
+// orderedEntries returns the entries of the component in their order, with the designer properties
+// as they are now. The properties added since the design was read follow, sorted by their names.
+func (c *Component) orderedEntries() []Entry {
+	var entries []Entry
+	seen := map[string]bool{}
+	for _, entry := range c.Entries {
+		if isProperty(entry.Key) {
+			value, found := c.Properties[entry.Key]
+			if !found {
+				// removed
+				continue
+			}
+			if value != entry.Value {
+				entry = Entry{Key: entry.Key, Value: value}
+			}
+		}
+		seen[entry.Key] = true
+		entries = append(entries, entry)
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Properties)) {
+		if !seen[key] {
+			entries = append(entries, Entry{Key: key, Value: c.Properties[key]})
+		}
+	}
+	return entries
+}
+
+// addEntry keeps the entry in its order, the designer properties are also known by their names
+func (c *Component) addEntry(entry Entry) {
+	c.Entries = append(c.Entries, entry)
+	if isProperty(entry.Key) {
+		c.Properties[entry.Key] = entry.Value
+	}
+}
+
+// setEntry changes the value of a key or adds the key last
+func (c *Component) setEntry(entry Entry) {
+	if isProperty(entry.Key) {
+		c.Properties[entry.Key] = entry.Value
+	}
+	for i := range c.Entries {
+		if c.Entries[i].Key == entry.Key {
+			c.Entries[i] = entry
+			return
+		}
+	}
+	c.Entries = append(c.Entries, entry)
+}
+
+func (c *Component) removeEntry(key string) {
+	delete(c.Properties, key)
+	c.Entries = slices.DeleteFunc(c.Entries, func(entry Entry) bool {
+		return entry.Key == key
+	})
+}
+
+// entry returns the value of a key of the component, such as $Version
+func (c *Component) entry(key string) (Entry, bool) {
+	for _, entry := range c.Entries {
+		if entry.Key == key {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,141 @@
+package design
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ReadPatch reads the JSON of a patch made by Diff
+func ReadPatch(content []byte) (*Patch, error) {
+	var patch Patch
+	if err := json.Unmarshal(content, &patch); err != nil {
+		return nil, err
+	}
+	if patch.Version != patchVersion {
+		return nil, fmt.Errorf("unsupported patch version %d", patch.Version)
+	}
+	return &patch, nil
+}
+
+// Apply makes the changes to another copy of the design. A change that conflicts with the design,
+// such as a property changed to another value or a component that isn't there, is skipped and
+// reported in the error.
+func (p *Patch) Apply(screen *Component) error {
+	var conflicts []error
+	for _, change := range p.Changes {
+		if err := change.apply(screen); err != nil {
+			conflicts = append(conflicts, fmt.Errorf("%s: %w", change, err))
+		}
+	}
+	return errors.Join(conflicts...)
+}
+
+func (c *Change) apply(screen *Component) error {
+	component := find(screen, c.Id)
+	if component == nil && c.Kind != Added && c.Kind != Removed {
+		return errors.New(c.Id + " isn't in the design")
+	}
+	switch c.Kind {
+	case Renamed:
+		if find(screen, c.NewId) != nil {
+			return errors.New(c.NewId + " is already in the design")
+		}
+		component.Id = c.NewId
+	case Added:
+		if component != nil {
+			return errors.New(c.Id + " is already in the design")
+		}
+		added := Component{Id: c.Id, Type: c.Type, Properties: map[string]string{}}
+		added.XMLName.Local = c.Type
+		for _, entry := range c.Entries {
+			added.addEntry(entry)
+		}
+		return insert(screen, added, c.Parent, c.After)
+	case Moved:
+		if component == screen || find(component, c.Parent) != nil {
+			return errors.New(c.Id + " can't be moved into itself")
+		}
+		if find(screen, c.Parent) == nil {
+			return errors.New(c.Parent + " isn't in the design")
+		}
+		moved := *component
+		parent, index := parentOf(screen, c.Id)
+		parent.Children = slices.Delete(parent.Children, index, index+1)
+		return insert(screen, moved, c.Parent, c.After)
+	case Removed:
+		if component == nil {
+			// already removed
+			return nil
+		}
+		parent, index := parentOf(screen, c.Id)
+		if parent == nil {
+			return errors.New("the screen can't be removed")
+		}
+		parent.Children = slices.Delete(parent.Children, index, index+1)
+	case Set:
+		current, found := component.entry(c.Property)
+		switch {
+		case c.New != nil && found && current == *c.New, c.New == nil && !found:
+			// already changed
+		case c.Old != nil && !found:
+			return errors.New(c.Property + " was removed")
+		case c.Old == nil && found, c.Old != nil && current != *c.Old:
+			return errors.New(c.Property + " was changed to " + current.text())
+		case c.New == nil:
+			component.removeEntry(c.Property)
+		default:
+			component.setEntry(*c.New)
+		}
+	default:
+		return errors.New("unknown change " + string(c.Kind))
+	}
+	return nil
+}
+
+// insert adds the component to the parent after its sibling, or first when there's none. It goes
+// last when the sibling isn't there anymore.
+func insert(screen *Component, component Component, parentId string, after string) error {
+	parent := find(screen, parentId)
+	if parent == nil {
+		return errors.New(parentId + " isn't in the design")
+	}
+	index := 0
+	if after != "" {
+		index = len(parent.Children)
+		for k := range parent.Children {
+			if parent.Children[k].Id == after {
+				index = k + 1
+			}
+		}
+	}
+	parent.Children = slices.Insert(parent.Children, index, component)
+	return nil
+}
+
+// find returns the component of that id among the component and all it holds
+func find(component *Component, id string) *Component {
+	if component.Id == id {
+		return component
+	}
+	for k := range component.Children {
+		if found := find(&component.Children[k], id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// parentOf returns the component holding the component of that id, and where it is among the children
+func parentOf(component *Component, id string) (*Component, int) {
+	for k := range component.Children {
+		if component.Children[k].Id == id {
+			return component, k
+		}
+		if parent, index := parentOf(&component.Children[k], id); parent != nil {
+			return parent, index
+		}
+	}
+	return nil, -1
+}
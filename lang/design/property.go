@@ -0,0 +1,428 @@
+package design
+
+import (
+	"Falcon/components/registry"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Value is a designer property read by the editor of the property. It has the form App Inventor
+// encodes in .scm files, such as -1050 for half of the screen, and the form written in .aiml files.
+type Value interface {
+	Encode() string // the form of the .scm files
+	String() string // the form of the .aiml files
+}
+
+type LengthKind int
+
+const (
+	Automatic LengthKind = iota
+	FillParent
+	Pixels
+	Percent
+)
+
+// Length is the width or the height of a component, Amount is the pixels or the percent of the screen
+type Length struct {
+	Kind   LengthKind
+	Amount int
+}
+
+// how App Inventor encodes lengths, a percent is -1000 less the percent
+const (
+	automaticLength  = -1
+	fillParentLength = -2
+	percentLength    = -1000
+)
+
+func (l Length) Encode() string {
+	switch l.Kind {
+	case Automatic:
+		return strconv.Itoa(automaticLength)
+	case FillParent:
+		return strconv.Itoa(fillParentLength)
+	case Percent:
+		return strconv.Itoa(percentLength - l.Amount)
+	}
+	return strconv.Itoa(l.Amount)
+}
+
+func (l Length) String() string {
+	switch l.Kind {
+	case Automatic:
+		return "automatic"
+	case FillParent:
+		return "fill parent"
+	case Percent:
+		return strconv.Itoa(l.Amount) + "%"
+	}
+	return strconv.Itoa(l.Amount) + "px"
+}
+
+// Color is an ARGB color, the .aiml files write it #RRGGBB when it is opaque and #AARRGGBB otherwise
+type Color struct {
+	Alpha, Red, Green, Blue uint8
+}
+
+func (c Color) Encode() string {
+	return fmt.Sprintf("&H%02X%02X%02X%02X", c.Alpha, c.Red, c.Green, c.Blue)
+}
+
+func (c Color) String() string {
+	if c.Alpha == 0xFF {
+		return fmt.Sprintf("#%02X%02X%02X", c.Red, c.Green, c.Blue)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", c.Alpha, c.Red, c.Green, c.Blue)
+}
+
+type Boolean bool
+
+func (b Boolean) Encode() string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func (b Boolean) String() string {
+	return strconv.FormatBool(bool(b))
+}
+
+// Choice is one of the values of an enumerated property, such as the center of an alignment
+type Choice struct {
+	Code string // the value App Inventor encodes, such as 3
+	Name string // such as center
+}
+
+func (c Choice) Encode() string {
+	return c.Code
+}
+
+func (c Choice) String() string {
+	return c.Name
+}
+
+// Font is one of the typefaces of the device or a font file among the assets
+type Font struct {
+	Typeface Choice
+	File     Asset
+}
+
+func (f Font) Encode() string {
+	if f.File != "" {
+		return string(f.File)
+	}
+	return f.Typeface.Code
+}
+
+func (f Font) String() string {
+	if f.File != "" {
+		return string(f.File)
+	}
+	return f.Typeface.Name
+}
+
+// Asset is the name of a file of the assets of the project, such as kitty.png
+type Asset string
+
+func (a Asset) Encode() string {
+	return string(a)
+}
+
+func (a Asset) String() string {
+	return string(a)
+}
+
+// Number keeps the text of a number as it is written, such as 14.0
+type Number string
+
+func (n Number) Encode() string {
+	return string(n)
+}
+
+func (n Number) String() string {
+	return string(n)
+}
+
+// Text is the value of a property that isn't typed
+type Text string
+
+func (t Text) Encode() string {
+	return string(t)
+}
+
+func (t Text) String() string {
+	return string(t)
+}
+
+// the choices of the editors of enumerated properties, in the order of the designer
+var editorChoices = map[string][]Choice{
+	"horizontal_alignment": {{"1", "left"}, {"3", "center"}, {"2", "right"}},
+	"vertical_alignment":   {{"1", "top"}, {"2", "center"}, {"3", "bottom"}},
+	"textalignment":        {{"0", "left"}, {"1", "center"}, {"2", "right"}},
+	"button_shape":         {{"0", "default"}, {"1", "rounded"}, {"2", "rectangular"}, {"3", "oval"}},
+	"scaling":              {{"0", "scale proportionally"}, {"1", "scale to fit"}},
+	"toast_length":         {{"0", "short"}, {"1", "long"}},
+	"typeface":             {{"0", "default"}, {"1", "sans serif"}, {"2", "serif"}, {"3", "monospace"}},
+	"screen_orientation":   namedByCode("unspecified", "portrait", "landscape", "sensor", "user"),
+	"screen_animation":     namedByCode("default", "fade", "zoom", "slidehorizontal", "slidevertical", "none"),
+	"sizing":               namedByCode("Fixed", "Responsive"),
+	"theme":                namedByCode("Classic", "AppTheme.Light.DarkActionBar", "AppTheme.Light", "AppTheme"),
+}
+
+// namedByCode makes the choices of an editor whose values are already words
+func namedByCode(codes ...string) []Choice {
+	choices := make([]Choice, len(codes))
+	for i, code := range codes {
+		choices[i] = Choice{Code: code, Name: code}
+	}
+	return choices
+}
+
+var (
+	colorPattern    = regexp.MustCompile(`^&H[0-9A-Fa-f]{8}$`)
+	hexColorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$`)
+)
+
+// DecodeValue reads a property the way App Inventor encodes it in .scm files
+func DecodeValue(editorType string, encoded string) (Value, error) {
+	switch editorType {
+	case "boolean", "visibility":
+		if encoded != "True" && encoded != "False" {
+			return nil, errors.New("expected True or False")
+		}
+		return Boolean(encoded == "True"), nil
+	case "color":
+		if !colorPattern.MatchString(encoded) {
+			return nil, errors.New("expected a color such as &HFF000000 (&HAARRGGBB)")
+		}
+		argb, _ := strconv.ParseUint(encoded[2:], 16, 32)
+		return Color{Alpha: uint8(argb >> 24), Red: uint8(argb >> 16), Green: uint8(argb >> 8), Blue: uint8(argb)}, nil
+	case "length":
+		number, err := strconv.Atoi(encoded)
+		switch {
+		case err != nil:
+		case number == automaticLength:
+			return Length{Kind: Automatic}, nil
+		case number == fillParentLength:
+			return Length{Kind: FillParent}, nil
+		case number >= 0:
+			return Length{Kind: Pixels, Amount: number}, nil
+		case number <= percentLength-1 && number >= percentLength-100:
+			return Length{Kind: Percent, Amount: percentLength - number}, nil
+		}
+		return nil, errors.New("expected pixels, -1 (automatic), -2 (fill parent) or -1001 to -1100 (percent)")
+	case "float":
+		if _, err := strconv.ParseFloat(encoded, 64); err != nil {
+			return nil, errors.New("expected a number")
+		}
+		return Number(encoded), nil
+	case "non_negative_float":
+		if number, err := strconv.ParseFloat(encoded, 64); err != nil || number < 0 {
+			return nil, errors.New("expected a number not below 0")
+		}
+		return Number(encoded), nil
+	case "non_negative_integer":
+		if _, err := strconv.ParseUint(encoded, 10, 64); err != nil {
+			return nil, errors.New("expected a whole number not below 0")
+		}
+		return Number(encoded), nil
+	case "asset":
+		return Asset(encoded), nil
+	case "typeface":
+		if choice, found := choiceOf(editorType, encoded, false); found {
+			return Font{Typeface: choice}, nil
+		}
+		if !strings.Contains(encoded, ".") {
+			return nil, errors.New("expected 0, 1, 2, 3 or a font file")
+		}
+		return Font{File: Asset(encoded)}, nil
+	}
+	if choices, enumerated := editorChoices[editorType]; enumerated {
+		if choice, found := choiceOf(editorType, encoded, false); found {
+			return choice, nil
+		}
+		codes := make([]string, len(choices))
+		for i, choice := range choices {
+			codes[i] = choice.Code
+		}
+		return nil, errors.New("expected one of " + strings.Join(codes, ", "))
+	}
+	return Text(encoded), nil
+}
+
+// ParseValue reads a property written in an .aiml file, either in the form App Inventor encodes or
+// in a friendlier one: 50%, fill parent or 120px for lengths, #FF0000 for colors, true for booleans,
+// center for alignments and the names of the other choices
+func ParseValue(editorType string, value string) (Value, error) {
+	decoded, err := DecodeValue(editorType, value)
+	if err == nil {
+		return decoded, nil
+	}
+	switch editorType {
+	case "boolean", "visibility":
+		if parsed, parseErr := strconv.ParseBool(strings.ToLower(value)); parseErr == nil {
+			return Boolean(parsed), nil
+		}
+		return nil, errors.New("expected true or false")
+	case "color":
+		if !hexColorPattern.MatchString(value) {
+			return nil, errors.New("expected a color such as #FF0000 (#RRGGBB or #AARRGGBB)")
+		}
+		hex := value[1:]
+		if len(hex) == 6 {
+			hex = "FF" + hex
+		}
+		return DecodeValue(editorType, "&H"+strings.ToUpper(hex))
+	case "length":
+		return parseLength(value)
+	}
+	if _, enumerated := editorChoices[editorType]; enumerated {
+		if choice, found := choiceOf(editorType, value, true); found {
+			if editorType == "typeface" {
+				return Font{Typeface: choice}, nil
+			}
+			return choice, nil
+		}
+		return nil, errors.New(expectedChoice(editorType))
+	}
+	return nil, err
+}
+
+func parseLength(value string) (Value, error) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	switch normalized {
+	case "automatic":
+		return Length{Kind: Automatic}, nil
+	case "fill parent", "fill-parent", "fillparent":
+		return Length{Kind: FillParent}, nil
+	}
+	if percent, found := strings.CutSuffix(normalized, "%"); found {
+		amount, err := strconv.Atoi(percent)
+		if err != nil || amount < 1 || amount > 100 {
+			return nil, errors.New("expected a percent from 1% to 100%")
+		}
+		return Length{Kind: Percent, Amount: amount}, nil
+	}
+	if pixels, found := strings.CutSuffix(normalized, "px"); found {
+		if amount, err := strconv.Atoi(pixels); err == nil && amount >= 0 {
+			return Length{Kind: Pixels, Amount: amount}, nil
+		}
+	}
+	return nil, errors.New("expected automatic, fill parent, a percent such as 50% or pixels such as 120px")
+}
+
+// expectedChoice tells the choices of an editor by their names, along with their codes when they differ
+func expectedChoice(editorType string) string {
+	choices := editorChoices[editorType]
+	names, codes := make([]string, len(choices)), make([]string, len(choices))
+	for i, choice := range choices {
+		names[i], codes[i] = choice.Name, choice.Code
+	}
+	if editorType == "typeface" {
+		return "expected " + strings.Join(names, ", ") + " (" + strings.Join(codes, ", ") + ") or a font file"
+	}
+	if slices.Equal(names, codes) {
+		return "expected one of " + strings.Join(names, ", ")
+	}
+	return "expected one of " + strings.Join(names, ", ") + " (" + strings.Join(codes, ", ") + ")"
+}
+
+// choiceOf finds the choice of an enumerated editor by its code, or by its name as well
+func choiceOf(editorType string, value string, byName bool) (Choice, bool) {
+	choices := editorChoices[editorType]
+	index := slices.IndexFunc(choices, func(choice Choice) bool {
+		return choice.Code == value || byName && strings.EqualFold(choice.Name, value)
+	})
+	if index < 0 {
+		return Choice{}, false
+	}
+	return choices[index], true
+}
+
+// Value reads a designer property of the component by the editor of the property, it is Text for
+// the properties whose editor isn't known
+func (c *Component) Value(property string, descriptors *registry.Registry) (Value, error) {
+	value, found := c.Properties[property]
+	if !found {
+		return nil, errors.New(c.Id + " has no " + property)
+	}
+	if editorType, known := editorOf(c.ComponentType(), property, descriptors); known {
+		return DecodeValue(editorType, value)
+	}
+	return Text(value), nil
+}
+
+// SetValue changes a designer property, it is kept the way App Inventor encodes it
+func (c *Component) SetValue(property string, value Value) {
+	c.setEntry(Entry{Key: property, Value: value.Encode()})
+}
+
+// editorOf finds the editor of a designer property. The properties the descriptors don't have, such
+// as the ones of the extensions, are read by their kind: the editor of the properties of the same
+// name in the other components, else a color for a name ending with Color.
+func editorOf(componentType string, property string, descriptors *registry.Registry) (string, bool) {
+	if descriptors == nil {
+		descriptors = registry.Default()
+	}
+	if component, known := descriptors.Component(componentType); known {
+		if designerProperty, found := component.DesignerProperty(property); found {
+			return designerProperty.EditorType, true
+		}
+	}
+	if editorType, agreed := descriptors.EditorType(property); agreed {
+		return editorType, true
+	}
+	if strings.HasSuffix(property, "Color") {
+		return "color", true
+	}
+	return "", false
+}
+
+// encodeValue is the form of the .scm files of a property written in an .aiml file. A value already
+// encoded is kept as it is, so is one that isn't valid.
+func encodeValue(componentType string, property string, value string) string {
+	editorType, known := editorOf(componentType, property, nil)
+	if !known {
+		return value
+	}
+	if _, err := DecodeValue(editorType, value); err == nil {
+		return value
+	}
+	if parsed, err := ParseValue(editorType, value); err == nil {
+		return parsed.Encode()
+	}
+	return value
+}
+
+// friendlyValue is the form of the .aiml files of an encoded property. Only the values encoded the
+// way App Inventor does are changed, so that they are encoded back the same.
+func friendlyValue(componentType string, property string, value string) string {
+	editorType, known := editorOf(componentType, property, nil)
+	if !known {
+		return value
+	}
+	decoded, err := DecodeValue(editorType, value)
+	if err != nil || decoded.Encode() != value {
+		return value
+	}
+	return decoded.String()
+}
+
+// encodeProperties encodes the properties of the components written in an .aiml file
+func encodeProperties(component *Component) {
+	for _, entry := range component.Entries {
+		if isProperty(entry.Key) && !entry.Raw {
+			if encoded := encodeValue(component.ComponentType(), entry.Key, entry.Value); encoded != entry.Value {
+				component.setEntry(Entry{Key: entry.Key, Value: encoded})
+			}
+		}
+	}
+	for k := range component.Children {
+		encodeProperties(&component.Children[k])
+	}
+}
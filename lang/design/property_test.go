@@ -0,0 +1,52 @@
+package design
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStockScreenIsFriendly(t *testing.T) {
+	schema, err := os.ReadFile("testdata/Screen1.scm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aiml, err := NewSchemaParser(string(schema)).ConvertSchemaToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(aiml, "&amp;H") {
+		t.Errorf("a color is left encoded:\n%s", aiml)
+	}
+	for _, attribute := range []string{`AccentColor="#FF4081"`, `PrimaryColor="#3F51B5"`, `PrimaryColorDark="#303F9F"`,
+		`ActionBar="true"`, `FontBold="true"`, `Height="fill parent"`, `Width="50%"`, `Height="200px"`,
+		`PaintColor="#FF0000"`, `BackgroundColor="#00FFFFFF"`, `Shape="rounded"`} {
+		if !strings.Contains(aiml, attribute) {
+			t.Errorf("expected %s in:\n%s", attribute, aiml)
+		}
+	}
+}
+
+func TestExtensionPropertiesAreFriendly(t *testing.T) {
+	schema := `#|
+$JSON
+{"authURL":["ai2.appinventor.mit.edu"],"YaVersion":"208","Source":"Form","Properties":{"$Name":"Screen1","$Type":"Form","$Version":"31","Uuid":"0","$Components":[` +
+		`{"$Name":"Picker1","$Type":"ColorPicker","$Version":"1","BackgroundColor":"&HFF000000","Enabled":"False",` +
+		`"Label":"#FF0000","PickedColor":"&H8000FF00","Width":"-1050","Uuid":"1"}]}}
+|#`
+	aiml, err := NewSchemaParser(schema).ConvertSchemaToXml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<ColorPicker id="Picker1" _Version="1" BackgroundColor="#000000" Enabled="false" Label="#FF0000" PickedColor="#8000FF00" Width="50%" Uuid="1"/>`
+	if !strings.Contains(aiml, expected) {
+		t.Errorf("expected %s in:\n%s", expected, aiml)
+	}
+	back, err := NewXmlParser(aiml).ConvertXmlToSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back != schema {
+		t.Errorf("the design changed when converted to .aiml and back:\n%s", back)
+	}
+}
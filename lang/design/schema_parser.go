@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
 )
 
 type SchemaParser struct {
@@ -15,57 +18,138 @@ func NewSchemaParser(schemaJson string) *SchemaParser {
 }
 
 func (p *SchemaParser) ConvertSchemaToXml() (string, error) {
-	var jsonStruct map[string]interface{}
-	err := json.Unmarshal([]byte(p.schemaJson), &jsonStruct)
+	root, err := p.ParseScreen()
 	if err != nil {
 		return "", err
 	}
-	properties := jsonStruct["Properties"].(map[string]interface{})
-	screenId := properties["$Name"].(string)
+	var buf bytes.Buffer
+	err = root.WriteXML(&buf, 0)
+	return buf.String(), err
+}
 
-	schemaComponents := properties["$Components"].([]interface{})
-	var xmlChildren []Component
-	for _, schemaComponent := range schemaComponents {
-		xmlChildren = append(xmlChildren, schemaComponentToXml(schemaComponent.(map[string]interface{})))
+// ParseScreen reads the screen design, the #|$JSON .. |# wrapper of a .scm file is optional.
+// The keys are kept in their order along with the values that aren't strings.
+func (p *SchemaParser) ParseScreen() (*Component, error) {
+	decoder := json.NewDecoder(strings.NewReader(schemaBody(p.schemaJson)))
+	screen := &Component{XMLName: xml.Name{Local: "Screen"}, Type: "Screen", Properties: map[string]string{}}
+	found := false
+	err := readObject(decoder, func(key string) error {
+		if key != "Properties" {
+			entry, err := readEntry(decoder, key)
+			screen.File = append(screen.File, entry)
+			return err
+		}
+		found = true
+		return readComponent(decoder, screen)
+	})
+	if err != nil {
+		return nil, err
 	}
+	if !found {
+		return nil, errors.New("the schema has no Properties")
+	}
+	return screen, nil
+}
 
-	root := Component{
-		XMLName:    xml.Name{Local: "Screen"},
-		Id:         screenId,
-		Type:       "Screen",
-		Properties: filterDesignerProperties(properties),
-		Children:   xmlChildren,
+// WrapSchema puts the JSON of a screen design in the #|$JSON .. |# form of the .scm files
+func WrapSchema(schemaJson string) string {
+	return "#|\n$JSON\n" + strings.TrimSpace(schemaJson) + "\n|#"
+}
+
+func schemaBody(content string) string {
+	content = strings.TrimSpace(content)
+	if body, ok := strings.CutPrefix(content, "#|"); ok {
+		body = strings.TrimSpace(strings.TrimSuffix(body, "|#"))
+		return strings.TrimPrefix(body, "$JSON")
 	}
-	var buf bytes.Buffer
-	err = root.WriteXML(&buf, 0)
-	return buf.String(), err
+	return content
 }
 
-func schemaComponentToXml(schemaJson map[string]interface{}) Component {
-	compType := schemaJson["$Type"].(string)
-	schemaComponents := schemaJson["$Components"]
-	var xmlChildren []Component
-	if schemaComponents != nil {
-		for _, schemaComponent := range schemaComponents.([]interface{}) {
-			xmlChildren = append(xmlChildren, schemaComponentToXml(schemaComponent.(map[string]interface{})))
+// readComponent reads the properties of a component, its children among them
+func readComponent(decoder *json.Decoder, component *Component) error {
+	return readObject(decoder, func(key string) error {
+		switch key {
+		case componentsKey:
+			return readChildren(decoder, component)
+		case nameKey, typeKey:
+			var value string
+			if err := decoder.Decode(&value); err != nil {
+				return fmt.Errorf("the %s of a component must be a string: %w", key, err)
+			}
+			if key == nameKey {
+				component.Id = value
+			} else if component.Type != "Screen" {
+				component.XMLName, component.Type = xml.Name{Local: value}, value
+			}
+			return nil
+		}
+		entry, err := readEntry(decoder, key)
+		if err == nil {
+			component.addEntry(entry)
 		}
+		return err
+	})
+}
+
+func readChildren(decoder *json.Decoder, component *Component) error {
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
 	}
-	return Component{
-		XMLName:    xml.Name{Local: compType},
-		Id:         schemaJson["$Name"].(string),
-		Type:       compType,
-		Properties: filterDesignerProperties(schemaJson),
-		Children:   xmlChildren,
+	for decoder.More() {
+		child := Component{Properties: map[string]string{}}
+		if err := readComponent(decoder, &child); err != nil {
+			return err
+		}
+		if child.Type == "" {
+			return errors.New("the component " + child.Id + " has no $Type")
+		}
+		component.Children = append(component.Children, child)
 	}
+	return expectDelim(decoder, ']')
 }
 
-func filterDesignerProperties(componentProps map[string]interface{}) map[string]string {
-	filteredProperties := make(map[string]string)
-	for key, value := range componentProps {
-		if len(key) > 0 && key[0] == '$' {
-			continue
+// readObject calls readValue for every key of the object, which must read the value of the key
+func readObject(decoder *json.Decoder, readValue func(key string) error) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
 		}
-		filteredProperties[key] = value.(string)
+		if err := readValue(token.(string)); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+// readEntry reads a value, the JSON of the ones that aren't strings (null too) is kept as it is
+func readEntry(decoder *json.Decoder, key string) (Entry, error) {
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return Entry{}, err
+	}
+	var value string
+	// null would be read as an empty string
+	if string(raw) != "null" && json.Unmarshal(raw, &value) == nil {
+		return Entry{Key: key, Value: value}, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return Entry{}, err
+	}
+	return Entry{Key: key, Value: compact.String(), Raw: true}, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v in the schema but got %v", delim, token)
 	}
-	return filteredProperties
+	return nil
 }
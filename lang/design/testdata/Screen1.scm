@@ -0,0 +1,4 @@
+#|
+$JSON
+{"authURL":["ai2.appinventor.mit.edu"],"YaVersion":"208","Source":"Form","Properties":{"$Name":"Screen1","$Type":"Form","$Version":"27","AccentColor":"&HFFFF4081","ActionBar":"True","AlignHorizontal":"3","AppName":"HelloPurr","BackgroundColor":"&HFFFFFFFF","Icon":"kitty.png","PrimaryColor":"&HFF3F51B5","PrimaryColorDark":"&HFF303F9F","ScreenOrientation":"portrait","Sizing":"Responsive","Theme":"AppTheme.Light.DarkActionBar","Title":"HelloPurr","Uuid":"0","$Components":[{"$Name":"VerticalArrangement1","$Type":"VerticalArrangement","$Version":"3","AlignHorizontal":"3","BackgroundColor":"&H00FFFFFF","Height":"-2","Width":"-1050","Uuid":"-1093219467","$Components":[{"$Name":"Button1","$Type":"Button","$Version":"7","FontSize":"20","Image":"kitty.png","Shape":"1","Text":"Pet the Kitty","TextColor":"&HFFFFFFFF","Uuid":"1046337513"},{"$Name":"Label1","$Type":"Label","$Version":"5","BackgroundColor":"&HFF0000FF","FontBold":"True","FontSize":"24","HasMargins":"False","Text":"Pet the Kitty","TextAlignment":"1","Width":"-2","Uuid":"-2045711013"}]},{"$Name":"ListView1","$Type":"ListView","$Version":"6","Height":"200","Width":"-2","Uuid":"1352466285"},{"$Name":"Canvas1","$Type":"Canvas","$Version":"14","Height":"300","PaintColor":"&HFFFF0000","Width":"-2","Uuid":"-1518236404"},{"$Name":"Sound1","$Type":"Sound","$Version":"3","MinimumInterval":"500","Source":"meow.mp3","Uuid":"-1711512380"},{"$Name":"Player1","$Type":"Player","$Version":"6","Source":"purr.mp3","Uuid":"752519618"}]}}
+|#
\ No newline at end of file
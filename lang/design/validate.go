@@ -0,0 +1,305 @@
+package design
+
+import (
+	"Falcon/code/context"
+	"Falcon/components/registry"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// element is a component of an .aiml design along with where it is written
+type element struct {
+	Type       string
+	Id         string
+	span       context.Span // the name of the tag
+	idSpan     context.Span
+	attributes []attribute
+	children   []*element
+}
+
+type attribute struct {
+	Name, Value string
+	nameSpan    context.Span
+	valueSpan   context.Span
+}
+
+// properties every component of an .scm file has, besides the designer ones
+var commonProperties = []string{"Uuid"}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// Validate checks an .aiml design against the component descriptors, the default ones when nil.
+// It reports duplicate ids, invalid property values and components placed where they can't be, each
+// at its line and column in the XML. The unknown component types and properties are warnings, the
+// descriptors may not know every component.
+func Validate(fileName string, content string, components *registry.Registry) []*context.Diagnostic {
+	if components == nil {
+		components = registry.Default()
+	}
+	validator := &validator{
+		codeContext: &context.CodeContext{SourceCode: &content, FileName: fileName},
+		content:     content,
+		components:  components,
+		ids:         map[string]*element{},
+	}
+	screen := validator.read()
+	if screen == nil {
+		return validator.diagnostics
+	}
+	if screen.Type != "Screen" {
+		validator.errorAt(screen.span, "The design must be a Screen element, got %", screen.Type)
+		return validator.diagnostics
+	}
+	validator.checkId(screen)
+	validator.checkComponent(screen, "Form")
+	for _, child := range screen.children {
+		validator.check(child, "Form")
+	}
+	return validator.diagnostics
+}
+
+type validator struct {
+	codeContext *context.CodeContext
+	content     string
+	components  *registry.Registry
+	ids         map[string]*element
+	diagnostics []*context.Diagnostic
+}
+
+func (v *validator) errorAt(span context.Span, message string, args ...string) *context.Diagnostic {
+	diagnostic := v.codeContext.NewDiagnostic(context.SeverityError, context.CodeDesign, span, message, args...)
+	v.diagnostics = append(v.diagnostics, diagnostic)
+	return diagnostic
+}
+
+func (v *validator) warningAt(span context.Span, message string, args ...string) {
+	diagnostic := v.codeContext.NewDiagnostic(context.SeverityWarning, context.CodeDesign, span, message, args...)
+	v.diagnostics = append(v.diagnostics, diagnostic)
+}
+
+// read parses the XML into elements, nil when it is malformed
+func (v *validator) read() *element {
+	decoder := xml.NewDecoder(strings.NewReader(v.content))
+	var root *element
+	var open []*element
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var syntaxError *xml.SyntaxError
+			message := err.Error()
+			if errors.As(err, &syntaxError) {
+				message = syntaxError.Msg
+			}
+			v.errorAt(v.spanAt(int(decoder.InputOffset()), 1), "Invalid XML: %", message)
+			return nil
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			anElement := v.elementOf(token, start, int(decoder.InputOffset()))
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, anElement)
+			} else if root == nil {
+				root = anElement
+			}
+			open = append(open, anElement)
+		case xml.EndElement:
+			open = open[:len(open)-1]
+		}
+	}
+	if root == nil {
+		v.errorAt(context.Span{}, "The design has no Screen element")
+	}
+	return root
+}
+
+func (v *validator) elementOf(token xml.StartElement, start int, end int) *element {
+	tag := v.content[start:end]
+	// the name follows the <
+	anElement := &element{Type: token.Name.Local, span: v.spanAt(start+1, utf8.RuneCountInString(token.Name.Local))}
+	anElement.idSpan = anElement.span
+	for _, attr := range token.Attr {
+		if attr.Name.Space != "" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		anAttribute := attribute{Name: attr.Name.Local, Value: attr.Value, nameSpan: anElement.span, valueSpan: anElement.span}
+		pattern := regexp.MustCompile(`\s(` + regexp.QuoteMeta(attr.Name.Local) + `)\s*=\s*["']([^"']*)`)
+		if match := pattern.FindStringSubmatchIndex(tag); match != nil {
+			anAttribute.nameSpan = v.spanAt(start+match[2], match[3]-match[2])
+			anAttribute.valueSpan = v.spanAt(start+match[4], utf8.RuneCountInString(tag[match[4]:match[5]]))
+		}
+		if attr.Name.Local == "id" {
+			anElement.Id, anElement.idSpan = attr.Value, anAttribute.valueSpan
+			continue
+		}
+		anElement.attributes = append(anElement.attributes, anAttribute)
+	}
+	return anElement
+}
+
+// spanAt is the span of a number of characters at a byte offset of the content, kept on one line
+func (v *validator) spanAt(offset int, length int) context.Span {
+	offset = min(offset, len(v.content))
+	lineStart := strings.LastIndexByte(v.content[:offset], '\n') + 1
+	line := strings.Count(v.content[:lineStart], "\n") + 1
+	column := utf8.RuneCountInString(v.content[lineStart:offset]) + 1
+	lineEnd := strings.IndexByte(v.content[offset:], '\n')
+	if lineEnd >= 0 {
+		length = min(length, utf8.RuneCountInString(v.content[offset:offset+lineEnd]))
+	}
+	return context.Span{Line: line, Column: column, EndColumn: column + max(length, 1)}
+}
+
+func (v *validator) check(anElement *element, parentType string) {
+	v.checkId(anElement)
+	if anElement.Type == "Screen" {
+		v.errorAt(anElement.span, "A Screen can't be inside another component")
+		return
+	}
+	component, known := v.components.Component(anElement.Type)
+	if !known {
+		// the descriptors may lack it, such as an extension not given or a component newer than them
+		if suggestion := closest(anElement.Type, v.components.Names()); suggestion != "" {
+			v.warningAt(anElement.span, "Unknown component type %, did you mean %? The component descriptors "+
+				"don't have it, its properties are not checked", anElement.Type, suggestion)
+		} else {
+			v.warningAt(anElement.span, "Unknown component type %, the component descriptors don't have it, "+
+				"its properties are not checked", anElement.Type)
+		}
+		for _, child := range anElement.children {
+			v.check(child, anElement.Type)
+		}
+		return
+	}
+	if component.IsNonVisible() && parentType != "Form" {
+		v.errorAt(anElement.span, "% is non-visible, it must be a child of the screen", v.nameOf(anElement))
+	}
+	v.checkComponent(anElement, anElement.Type)
+	if len(anElement.children) > 0 && !isContainer(component) {
+		v.errorAt(anElement.span, "% can't hold components", v.nameOf(anElement))
+	}
+	for _, child := range anElement.children {
+		v.check(child, anElement.Type)
+	}
+}
+
+// isContainer tells if the component holds others, the arrangements, a Canvas its sprites and a Map its features
+func isContainer(component *registry.Component) bool {
+	switch component.Name {
+	case "Form", "Canvas", "Map", "FeatureCollection":
+		return true
+	}
+	return component.CategoryString == "LAYOUT"
+}
+
+func (v *validator) nameOf(anElement *element) string {
+	if anElement.Id != "" {
+		return anElement.Id
+	}
+	return anElement.Type
+}
+
+func (v *validator) checkId(anElement *element) {
+	if anElement.Id == "" {
+		// a name is generated, one that isn't taken
+		return
+	}
+	if !identifierPattern.MatchString(anElement.Id) {
+		v.errorAt(anElement.idSpan, "Invalid id %, it must start with a letter followed by letters, digits or _", strconv.Quote(anElement.Id))
+	} else if _, isType := v.components.Component(anElement.Id); isType {
+		v.errorAt(anElement.idSpan, "The id % is the name of a component type", anElement.Id)
+	}
+	if first, found := v.ids[anElement.Id]; found {
+		v.errorAt(anElement.idSpan, "Duplicate id %", anElement.Id).
+			WithRelated(v.withFile(first.idSpan), "first used by this "+first.Type)
+		return
+	}
+	v.ids[anElement.Id] = anElement
+}
+
+func (v *validator) withFile(span context.Span) context.Span {
+	span.File = v.codeContext.FileName
+	return span
+}
+
+func (v *validator) checkComponent(anElement *element, componentType string) {
+	component, _ := v.components.Component(componentType)
+	seen := map[string]bool{}
+	for _, anAttribute := range anElement.attributes {
+		if seen[anAttribute.Name] {
+			v.errorAt(anAttribute.nameSpan, "Duplicate property %", anAttribute.Name)
+			continue
+		}
+		seen[anAttribute.Name] = true
+		if slices.Contains(commonProperties, anAttribute.Name) || isMetadata(anAttribute.Name) {
+			continue
+		}
+		property, found := component.DesignerProperty(anAttribute.Name)
+		if !found {
+			names := make([]string, len(component.Properties))
+			for i, designerProperty := range component.Properties {
+				names[i] = designerProperty.Name
+			}
+			if suggestion := closest(anAttribute.Name, names); suggestion != "" {
+				v.warningAt(anAttribute.nameSpan, "Unknown property % of %, did you mean %? The descriptor of % "+
+					"doesn't have it, its value is not checked", anAttribute.Name, componentType, suggestion, componentType)
+			} else {
+				v.warningAt(anAttribute.nameSpan, "Unknown property % of %, the descriptor of % doesn't have it, "+
+					"its value is not checked", anAttribute.Name, componentType, componentType)
+			}
+			continue
+		}
+		if _, err := ParseValue(property.EditorType, anAttribute.Value); err != nil {
+			v.errorAt(anAttribute.valueSpan, "Invalid value % of %.%, %",
+				strconv.Quote(anAttribute.Value), componentType, anAttribute.Name, err.Error())
+		}
+	}
+}
+
+// closest finds the name a misspelled one was meant to be, the same but for the case or
+// for a couple of characters
+func closest(name string, names []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return candidate
+		}
+		if len(candidate) < 4 {
+			continue
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,80 @@
+package design
+
+import (
+	"Falcon/code/context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		design   string
+		expected []string // the severity and the start of each message
+	}{
+		{"valid", `<Screen id="Screen1" AccentColor="#FF4081" ActionBar="true">
+  <Canvas id="Canvas1" Width="fill parent"><ImageSprite id="Sprite1"/><Ball id="Ball1"/></Canvas>
+  <ListView id="ListView1"/>
+  <Player id="Player1" Source="purr.mp3"/>
+</Screen>`, nil},
+		{"unknown type close to a known one", `<Screen id="Screen1"><Buton id="Button1"/></Screen>`,
+			[]string{"warning Unknown component type Buton, did you mean Button?"}},
+		{"unknown type", `<Screen id="Screen1"><KioskMode id="Kiosk1" Mode="1"/></Screen>`,
+			[]string{"warning Unknown component type KioskMode, the component descriptors don't have it"}},
+		{"unknown property close to a known one", `<Screen id="Screen1"><Button id="Button1" Txt="a"/></Screen>`,
+			[]string{"warning Unknown property Txt of Button, did you mean Text?"}},
+		{"invalid value", `<Screen id="Screen1"><Button id="Button1" Enabled="maybe"/></Screen>`,
+			[]string{`error Invalid value "maybe" of Button.Enabled`}},
+		{"duplicate id", `<Screen id="Screen1"><Label id="A"/><Label id="A"/></Screen>`,
+			[]string{"error Duplicate id A"}},
+		{"non-visible in an arrangement", `<Screen id="Screen1"><VerticalArrangement id="V"><Clock id="Clock1"/></VerticalArrangement></Screen>`,
+			[]string{"error Clock1 is non-visible"}},
+		{"children of a button", `<Screen id="Screen1"><Button id="B"><Label id="L"/></Button></Screen>`,
+			[]string{"error B can't hold components"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := Validate("Screen1.aiml", test.design, nil)
+			if len(diagnostics) != len(test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, diagnostics)
+			}
+			for i, diagnostic := range diagnostics {
+				got := diagnostic.Severity.String() + " " + diagnostic.Message
+				if !strings.HasPrefix(got, test.expected[i]) {
+					t.Errorf("expected %s but got %s", test.expected[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownComponentsAreConverted(t *testing.T) {
+	design := `<Screen id="Screen1"><Buton id="Button1" Text="a"/></Screen>`
+	if context.HasErrors(Validate("Screen1.aiml", design, nil)) {
+		t.Fatal("an unknown component is an error")
+	}
+	if _, err := NewXmlParser(design).ConvertXmlToSchema(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuplicateIdOfTheCalculator(t *testing.T) {
+	content, err := os.ReadFile("../../testing/Screen1.aiml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var duplicates []*context.Diagnostic
+	for _, diagnostic := range Validate("Screen1.aiml", string(content), nil) {
+		if diagnostic.Severity == context.SeverityError {
+			duplicates = append(duplicates, diagnostic)
+		}
+	}
+	if len(duplicates) != 1 || duplicates[0].Message != "Duplicate id firstNumberTextBox" ||
+		duplicates[0].Line != 5 || duplicates[0].Column != 16 {
+		t.Fatalf("expected the second firstNumberTextBox to be reported at 5:16 but got %v", duplicates)
+	}
+	if related := duplicates[0].Related; len(related) != 1 || related[0].Line != 3 || related[0].Column != 16 {
+		t.Errorf("expected the first firstNumberTextBox to be noted at 3:16 but got %+v", related)
+	}
+}
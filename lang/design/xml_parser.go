@@ -1,72 +1,167 @@
 package design
 
 import (
+	"Falcon/code/context"
+	"Falcon/components/registry"
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"strconv"
+	"strings"
 )
 
 type XmlParser struct {
 	xmlContent  string
 	autoIdCount map[string]int
+	usedIds     map[string]bool
 }
 
 func NewXmlParser(xmlContent string) *XmlParser {
-	return &XmlParser{xmlContent: xmlContent, autoIdCount: make(map[string]int)}
+	return &XmlParser{xmlContent: xmlContent, autoIdCount: make(map[string]int), usedIds: make(map[string]bool)}
 }
 
+// ConvertXmlToSchema converts the design to an .scm file, a design that doesn't Validate against
+// the default components is rejected with its first error. The keys keep the order and the values
+// of the .scm file the design was converted from, the ones a written design lacks are App Inventor's.
 func (p *XmlParser) ConvertXmlToSchema() (string, error) {
-	var screen Component
-	if err := xml.Unmarshal([]byte(p.xmlContent), &screen); err != nil {
-		panic(err)
+	for _, diagnostic := range Validate("", p.xmlContent, nil) {
+		if diagnostic.Severity == context.SeverityError {
+			return "", diagnostic
+		}
 	}
-	var components []interface{}
-	for _, child := range screen.Children {
-		components = append(components, p.componentToJson(child))
+	screen, err := p.ParseScreen()
+	if err != nil {
+		return "", err
 	}
+	return SchemaOf(screen), nil
+}
 
-	props := map[string]interface{}{
-		"$Name":       screen.Id,
-		"$Type":       "Form",
-		"$Version":    "31",
-		"$Components": components,
+// SchemaOf writes the screen as an .scm file
+func SchemaOf(screen *Component) string {
+	file := screen.File
+	if len(file) == 0 {
+		file = defaultFile
 	}
-	// add Screen's properties here
-	for k, v := range screen.Properties {
-		props[k] = v
+	writer := &schemaWriter{}
+	writer.WriteByte('{')
+	for _, entry := range file {
+		writer.entry(entry)
 	}
+	writer.key("Properties")
+	writer.component(screen, "Form")
+	writer.WriteByte('}')
+	return WrapSchema(writer.String())
+}
 
-	schema := map[string]interface{}{
-		"authURL":    []interface{}{"ai2.appinventor.mit.edu"},
-		"YaVersion":  "200",
-		"Source":     "Form",
-		"Properties": props,
+// the keys of an .scm file App Inventor writes before the properties of the screen
+var defaultFile = []Entry{
+	{Key: "authURL", Value: `["ai2.appinventor.mit.edu"]`, Raw: true},
+	{Key: "YaVersion", Value: "208"},
+	{Key: "Source", Value: "Form"},
+}
+
+// schemaWriter writes the compact JSON of an .scm file, with the keys in their order
+type schemaWriter struct {
+	strings.Builder
+}
+
+func (w *schemaWriter) key(key string) {
+	// the first key of an object follows its brace
+	if !strings.HasSuffix(w.String(), "{") {
+		w.WriteByte(',')
 	}
-	jsonBytes, _ := json.MarshalIndent(schema, "", "  ")
-	return string(jsonBytes), nil
+	w.string(key)
+	w.WriteByte(':')
+}
+
+func (w *schemaWriter) string(value string) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// & is common in colors such as &HFF000000
+	encoder.SetEscapeHTML(false)
+	// strings can always be encoded
+	_ = encoder.Encode(value)
+	w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
 }
 
-func (p *XmlParser) componentToJson(component Component) interface{} {
-	var children []interface{}
-	for _, child := range component.Children {
-		children = append(children, p.componentToJson(child))
+func (w *schemaWriter) entry(entry Entry) {
+	w.key(entry.Key)
+	if entry.Raw {
+		w.WriteString(entry.Value)
+	} else {
+		w.string(entry.Value)
 	}
-	compId := component.Id
-	if compId == "" {
-		// dynamically generate an Id
-		compId = component.Type + strconv.Itoa(p.autoIdCount[component.Type]+1)
-		p.autoIdCount[component.Type]++
+}
+
+// component writes the $Name, $Type and $Version first and the children last, the way App Inventor does
+func (w *schemaWriter) component(component *Component, componentType string) {
+	w.WriteByte('{')
+	w.key(nameKey)
+	w.string(component.Id)
+	w.key(typeKey)
+	w.string(componentType)
+	if _, found := component.entry(versionKey); !found {
+		if descriptor, known := registry.Default().Component(componentType); known && descriptor.Version != "" {
+			w.entry(Entry{Key: versionKey, Value: descriptor.Version})
+		}
 	}
-	schema := map[string]interface{}{
-		"$Name":    compId,
-		"$Type":    component.Type,
-		"$Version": "32",
+	for _, entry := range component.orderedEntries() {
+		w.entry(entry)
 	}
-	if len(children) > 0 {
-		schema["$Components"] = children
+	if len(component.Children) > 0 {
+		w.key(componentsKey)
+		w.WriteByte('[')
+		for k := range component.Children {
+			if k > 0 {
+				w.WriteByte(',')
+			}
+			child := &component.Children[k]
+			w.component(child, child.ComponentType())
+		}
+		w.WriteByte(']')
 	}
-	for k, v := range component.Properties {
-		schema[k] = v
+	w.WriteByte('}')
+}
+
+// ParseScreen reads the screen design, components without an id are given one and the properties
+// written in a friendly form are encoded the way App Inventor does
+func (p *XmlParser) ParseScreen() (*Component, error) {
+	var screen Component
+	if err := xml.Unmarshal([]byte(p.xmlContent), &screen); err != nil {
+		return nil, err
+	}
+	p.nameComponents(&screen)
+	encodeProperties(&screen)
+	return &screen, nil
+}
+
+// nameComponents generates the missing ids of the children, e.g. Button1, Button2, skipping
+// the ids already given in the design
+func (p *XmlParser) nameComponents(screen *Component) {
+	p.collectIds(screen)
+	p.nameChildren(screen)
+}
+
+func (p *XmlParser) collectIds(component *Component) {
+	if component.Id != "" {
+		p.usedIds[component.Id] = true
+	}
+	for k := range component.Children {
+		p.collectIds(&component.Children[k])
+	}
+}
+
+func (p *XmlParser) nameChildren(component *Component) {
+	for k := range component.Children {
+		child := &component.Children[k]
+		p.nameChildren(child)
+		for child.Id == "" {
+			p.autoIdCount[child.Type]++
+			id := child.Type + strconv.Itoa(p.autoIdCount[child.Type])
+			if !p.usedIds[id] {
+				child.Id = id
+				p.usedIds[id] = true
+			}
+		}
 	}
-	return schema
 }
@@ -19,6 +19,20 @@ type Component struct {
 	Type       string            `xml:"-"`
 	Properties map[string]string `xml:"-"`
 	Children   []Component       `xml:",any"`
+
+	// Entries are the keys of the component in the .scm file in their order, the designer
+	// properties along with the metadata such as $Version
+	Entries []Entry `xml:"-"`
+	// File are the keys of the .scm file around the properties of the screen, such as YaVersion
+	File []Entry `xml:"-"`
+}
+
+// ComponentType is the App Inventor type of the component, the type of a screen is Form
+func (c *Component) ComponentType() string {
+	if c.Type == "Screen" {
+		return "Form"
+	}
+	return c.Type
 }
 
 func (c *Component) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -29,8 +43,16 @@ func (c *Component) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	for _, attr := range start.Attr {
 		if attr.Name.Local == "id" {
 			c.Id = attr.Value
+			continue
+		}
+		if attr.Name.Space != "" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		entry, fileEntry := entryOf(attr.Name.Local, attr.Value)
+		if fileEntry {
+			c.File = append(c.File, entry)
 		} else {
-			c.Properties[attr.Name.Local] = attr.Value
+			c.addEntry(entry)
 		}
 	}
 	for {
@@ -58,31 +80,28 @@ func (c *Component) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 }
 
 // WriteXML manually converts Component structure to XML, a workaround for now, since
-// Go lang does not support self-closing tags
+// Go lang does not support self-closing tags. The attributes are written in the order of the
+// keys of the .scm file.
 func (c *Component) WriteXML(w io.Writer, indent int) error {
 	indentStr := strings.Repeat("  ", indent)
 
 	// Start tag
 	tag := indentStr + "<" + c.Type
 	if c.Id != "" {
-		var buf bytes.Buffer
-		err := xml.EscapeText(&buf, []byte(c.Id))
-		if err != nil {
-			return err
-		}
-		tag += ` id="` + buf.String() + `"`
+		tag += ` id="` + escapeAttribute(c.Id) + `"`
 	}
-
-	for k, v := range c.Properties {
-		if k == "id" || k == "type" {
+	for _, entry := range c.File {
+		tag += ` ` + entry.attributeName(true) + `="` + escapeAttribute(entry.Value) + `"`
+	}
+	for _, entry := range c.orderedEntries() {
+		if entry.Key == "id" || entry.Key == "type" {
 			continue
 		}
-		var buf bytes.Buffer
-		err := xml.EscapeText(&buf, []byte(v))
-		if err != nil {
-			return err
+		value := entry.Value
+		if isProperty(entry.Key) && !entry.Raw {
+			value = friendlyValue(c.ComponentType(), entry.Key, value)
 		}
-		tag += ` ` + k + `="` + buf.String() + `"`
+		tag += ` ` + entry.attributeName(false) + `="` + escapeAttribute(value) + `"`
 	}
 
 	if len(c.Children) == 0 {
@@ -108,3 +127,11 @@ func (c *Component) WriteXML(w io.Writer, indent int) error {
 	_, err := w.Write([]byte(indentStr + "</" + c.Type + ">\n"))
 	return err
 }
+
+// escapeAttribute escapes the quotes and the line breaks as well, so that the value is read back the same
+func escapeAttribute(value string) string {
+	var buf bytes.Buffer
+	// the writes of a buffer don't fail
+	_ = xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
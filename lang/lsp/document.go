@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/check"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/design"
+	"Falcon/project"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Document is an open source file along with the result of its analysis
+type Document struct {
+	URI         string
+	Version     int
+	Text        string
+	Expressions []ast.Expr
+	Diagnostics []*context.Diagnostic
+
+	lines   []string
+	tokens  []*lex.Token
+	parser  *mistparser.LangParser
+	library *Document // the lib.mist of the project, whose declarations are visible
+}
+
+// Analyze lexes and parses the source text of a document
+func Analyze(uri string, version int, text string) (document *Document) {
+	document = &Document{URI: uri, Version: version, Text: text, lines: strings.Split(text, "\n")}
+	codeContext := &context.CodeContext{SourceCode: &text, FileName: fileName(uri)}
+	defer func() {
+		// an internal compiler error must not take down the server
+		if r := recover(); r != nil {
+			document.Diagnostics = append(document.Diagnostics,
+				codeContext.NewDiagnostic(context.SeverityError, context.CodeInternal, context.Span{}, fmt.Sprint(r)))
+		}
+	}()
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	document.tokens = tokens
+	document.Diagnostics = diagnostics
+	document.parser = mistparser.NewLangParser(true, tokens)
+	if document.library = libraryOf(uri); document.library != nil && document.library.parser != nil {
+		document.parser.Include(document.library.parser)
+	}
+	if screen := designOf(uri); screen != nil {
+		document.parser.SetDesign(screen)
+	}
+	expressions, parseDiagnostics := document.parser.ParseAll()
+	document.Expressions = expressions
+	document.Diagnostics = append(document.Diagnostics, parseDiagnostics...)
+	if len(document.Diagnostics) == 0 {
+		// types are only worth checking once the code parses
+		program, located := expressions, document.parser.Located
+		if document.library != nil && document.library.parser != nil {
+			program = slices.Concat(document.library.Expressions, program)
+			located = slices.Concat(document.library.parser.Located, located)
+		}
+		for _, diagnostic := range check.NewChecker(false).Locate(located).Check(program) {
+			// the ones of the library are reported on its own document
+			if document.library == nil || diagnostic.File != project.LibraryFile {
+				document.Diagnostics = append(document.Diagnostics, diagnostic)
+			}
+		}
+	}
+	return document
+}
+
+// libraryOf analyzes the library of the project the file of the document is in, if there's one
+func libraryOf(uri string) *Document {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return nil
+	}
+	libraryFile := project.FindLibrary(filepath.FromSlash(parsed.Path))
+	if libraryFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(libraryFile)
+	if err != nil {
+		return nil
+	}
+	libraryURI := *parsed
+	libraryURI.Path = filepath.ToSlash(libraryFile)
+	return Analyze(libraryURI.String(), 0, string(content))
+}
+
+func fileName(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Path == "" {
+		return uri
+	}
+	return path.Base(parsed.Path)
+}
+
+// designOf reads the screen design next to the file of the document, if there's one
+func designOf(uri string) *design.Component {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return nil
+	}
+	designFile := design.FindDesign(filepath.FromSlash(parsed.Path))
+	if designFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(designFile)
+	if err != nil {
+		return nil
+	}
+	screen, err := design.ParseDesign(string(content))
+	if err != nil {
+		return nil
+	}
+	return screen
+}
+
+func (d *Document) LspDiagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0, len(d.Diagnostics))
+	for _, diagnostic := range d.Diagnostics {
+		lspDiagnostic := Diagnostic{
+			Range:    d.toRange(diagnostic.Span),
+			Severity: toLspSeverity(diagnostic.Severity),
+			Code:     diagnostic.Code,
+			Source:   "falcon",
+			Message:  diagnostic.Message,
+		}
+		for _, related := range diagnostic.Related {
+			lspDiagnostic.RelatedInformation = append(lspDiagnostic.RelatedInformation, relatedInformation{
+				Location: Location{URI: d.URI, Range: d.toRange(related.Span)},
+				Message:  related.Message,
+			})
+		}
+		diagnostics = append(diagnostics, lspDiagnostic)
+	}
+	return diagnostics
+}
+
+func toLspSeverity(severity context.Severity) int {
+	switch severity {
+	case context.SeverityError:
+		return severityError
+	case context.SeverityWarning:
+		return severityWarning
+	default:
+		return severityInformation
+	}
+}
+
+// toRange converts a span (1-based, byte columns) to an LSP range (0-based, UTF-16 columns)
+func (d *Document) toRange(span context.Span) Range {
+	if span.Line < 1 {
+		return Range{}
+	}
+	return Range{
+		Start: d.toPosition(span.Line, span.Column),
+		End:   d.toPosition(span.Line, span.EndColumn),
+	}
+}
+
+func (d *Document) toPosition(line int, column int) Position {
+	if line > len(d.lines) {
+		return Position{Line: line - 1}
+	}
+	text := d.lines[line-1]
+	offset := min(max(column-1, 0), len(text))
+	return Position{Line: line - 1, Character: len(utf16.Encode([]rune(text[:offset])))}
+}
+
+// fromPosition converts an LSP position to a 1-based line and byte column
+func (d *Document) fromPosition(position Position) (int, int) {
+	if position.Line >= len(d.lines) {
+		return position.Line + 1, position.Character + 1
+	}
+	text := d.lines[position.Line]
+	offset, units := 0, 0
+	for offset < len(text) && units < position.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return position.Line + 1, offset + 1
+}
+
+// tokenAt returns the index of the token under the position, a token that ends right
+// before the position is also accepted, that's where the cursor is after typing a word
+func (d *Document) tokenAt(position Position) int {
+	line, column := d.fromPosition(position)
+	found := -1
+	for i, token := range d.tokens {
+		span := token.Span()
+		if span.Line != line {
+			continue
+		}
+		if span.Column <= column && column < span.EndColumn {
+			return i
+		}
+		if column == span.EndColumn {
+			found = i
+		}
+	}
+	return found
+}
+
+// before compares the start of two spans
+func before(first context.Span, second context.Span) bool {
+	if first.Line != second.Line {
+		return first.Line < second.Line
+	}
+	return first.Column <= second.Column
+}
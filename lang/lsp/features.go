@@ -0,0 +1,255 @@
+package lsp
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Definition finds where the procedure or the global variable under the position is declared
+func (d *Document) Definition(position Position) *Location {
+	index := d.tokenAt(position)
+	if index < 0 || d.parser == nil || d.tokens[index].Type != lex.Name {
+		return nil
+	}
+	name := *d.tokens[index].Content
+	var where *lex.Token
+	if procedure := d.procedureAt(index); procedure != nil {
+		where = procedure.Where
+	} else if !d.isMember(index) || d.isThisMember(index) {
+		where = d.parser.Resolver.Globals[name]
+	}
+	if where == nil {
+		return nil
+	}
+	if d.library != nil && where.Span().File == fileName(d.library.URI) {
+		return &Location{URI: d.library.URI, Range: d.library.toRange(where.Span())}
+	}
+	return &Location{URI: d.URI, Range: d.toRange(where.Span())}
+}
+
+// procedureAt returns the user defined procedure named by the token, if it's a call or a declaration
+func (d *Document) procedureAt(index int) *mistparser.Procedure {
+	procedure, ok := d.parser.Resolver.Procedures[*d.tokens[index].Content]
+	if !ok || d.isMember(index) {
+		return nil
+	}
+	if procedure.Where == d.tokens[index] || d.typeAt(index+1) == lex.OpenCurve {
+		return procedure
+	}
+	return nil
+}
+
+// isMember checks if the token follows a dot, e.g. a method name or a property
+func (d *Document) isMember(index int) bool {
+	return d.typeAt(index-1) == lex.Dot
+}
+
+func (d *Document) isThisMember(index int) bool {
+	return d.isMember(index) && d.typeAt(index-2) == lex.This
+}
+
+func (d *Document) typeAt(index int) lex.Type {
+	if index < 0 || index >= len(d.tokens) {
+		return lex.Undefined
+	}
+	return d.tokens[index].Type
+}
+
+// Hover describes the symbol or the innermost expression under the position
+func (d *Document) Hover(position Position) *Hover {
+	index := d.tokenAt(position)
+	if index < 0 || d.parser == nil {
+		return nil
+	}
+	token := d.tokens[index]
+	tokenRange := d.toRange(token.Span())
+	if token.Type == lex.Name {
+		name := *token.Content
+		if procedure := d.procedureAt(index); procedure != nil {
+			return markdownHover(procedureDetail(procedure), &tokenRange)
+		}
+		if componentType, ok := d.parser.Resolver.ComponentTypesMap[name]; ok && !d.isMember(index) {
+			return markdownHover(name+": "+componentType, &tokenRange)
+		}
+	}
+	located := d.expressionAt(index)
+	if located == nil {
+		return nil
+	}
+	expressionRange := Range{
+		Start: d.toRange(located.Start.Span()).Start,
+		End:   d.toRange(located.End.Span()).End,
+	}
+	code := located.Expr.String()
+	if lines := strings.Split(code, "\n"); len(lines) > 1 {
+		code = lines[0] + " ..."
+	}
+	return markdownHover(code+"\n// "+signatureNames(located.Expr.Signature()), &expressionRange)
+}
+
+// expressionAt finds the smallest expression containing the token
+func (d *Document) expressionAt(index int) *mistparser.LocatedExpr {
+	span := d.tokens[index].Span()
+	var best *mistparser.LocatedExpr
+	bestSize := 0
+	for i := range d.parser.Located {
+		located := &d.parser.Located[i]
+		if located.Statement {
+			continue
+		}
+		start, end := located.Start.Span(), located.End.Span()
+		if !before(start, span) || !before(span, end) {
+			continue
+		}
+		size := (end.Line-start.Line)*10000 + end.EndColumn - start.Column
+		if best == nil || size < bestSize {
+			best, bestSize = located, size
+		}
+	}
+	return best
+}
+
+func markdownHover(code string, hoverRange *Range) *Hover {
+	return &Hover{
+		Contents: markupContent{Kind: "markdown", Value: "```falcon\n" + code + "\n```"},
+		Range:    hoverRange,
+	}
+}
+
+func procedureDetail(procedure *mistparser.Procedure) string {
+	detail := "func " + procedure.Name + "(" + ast.JoinParameters(procedure.Parameters, procedure.ParameterTypes) + ")"
+	if procedure.Returning {
+		if procedure.ReturnType != "" {
+			return detail + ": " + procedure.ReturnType
+		}
+		return detail + " = any"
+	}
+	return detail
+}
+
+var signatureNameMap = map[ast.Signature]string{
+	ast.SignBool:      "boolean",
+	ast.SignNumb:      "number",
+	ast.SignText:      "text",
+	ast.SignList:      "list",
+	ast.SignDict:      "dictionary",
+	ast.SignComponent: "component",
+	ast.SignHelper:    "helper",
+	ast.SignAny:       "any",
+	ast.SignOfEvent:   "event parameter",
+	ast.SignVoid:      "void",
+}
+
+func signatureNames(signatures []ast.Signature) string {
+	if len(signatures) == 0 {
+		return "any"
+	}
+	names := make([]string, len(signatures))
+	for i, signature := range signatures {
+		names[i] = signatureNameMap[signature]
+	}
+	return strings.Join(names, " | ")
+}
+
+// completion ranks, the candidates of a lower rank are listed first
+const (
+	rankComponent = iota // the components and their members
+	rankSymbol           // the procedures and the globals of the program
+	rankBuiltin
+	rankKeyword
+)
+
+// Completion suggests the symbols known at the position, the components and their members first
+func (d *Document) Completion(position Position) []CompletionItem {
+	items := []CompletionItem{}
+	if d.parser == nil {
+		return items
+	}
+	resolver := d.parser.Resolver
+	index := d.tokenAt(position)
+	if index >= 0 && d.tokens[index].Type == lex.Name {
+		// the cursor is at the end of the word being typed
+		index--
+	}
+	switch {
+	case d.typeAt(index) == lex.Dot && d.typeAt(index-1) == lex.This:
+		for name := range resolver.Globals {
+			items = append(items, CompletionItem{Label: name, Kind: kindVariable, Detail: "global", rank: rankSymbol})
+		}
+	case d.typeAt(index) == lex.Dot && d.typeAt(index-1) == lex.Name:
+		if componentType, ok := resolver.ComponentTypesMap[*d.tokens[index-1].Content]; ok {
+			items = d.memberCompletion(componentType)
+		}
+	case d.typeAt(index) == lex.Dot:
+		// the members of the other values are not known yet
+	case d.typeAt(index) == lex.At:
+		for componentType := range resolver.ComponentNameMap {
+			items = append(items, CompletionItem{Label: componentType, Kind: kindClass, rank: rankComponent})
+		}
+	default:
+		for componentType, names := range resolver.ComponentNameMap {
+			for _, name := range names {
+				items = append(items, CompletionItem{Label: name, Kind: kindVariable, Detail: componentType, rank: rankComponent})
+			}
+		}
+		for _, procedure := range resolver.Procedures {
+			items = append(items, CompletionItem{Label: procedure.Name, Kind: kindFunction,
+				Detail: procedureDetail(procedure), rank: rankSymbol})
+		}
+		for name := range resolver.Globals {
+			items = append(items, CompletionItem{Label: name, Kind: kindVariable, Detail: "global", rank: rankSymbol})
+		}
+		for _, builtin := range common.Builtins() {
+			items = append(items, CompletionItem{Label: builtin.Name, Kind: kindFunction, Detail: "in-built", rank: rankBuiltin})
+		}
+		for keyword := range lex.Keywords {
+			items = append(items, CompletionItem{Label: keyword, Kind: kindKeyword, rank: rankKeyword})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].rank != items[j].rank {
+			return items[i].rank < items[j].rank
+		}
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind > items[j].Kind
+		}
+		return items[i].Label < items[j].Label
+	})
+	for i := range items {
+		// the editors keep the order of the server when they sort by the position
+		items[i].SortText = fmt.Sprintf("%04d", i)
+	}
+	return items
+}
+
+// memberCompletion lists the properties and the methods of the component type the descriptors know
+func (d *Document) memberCompletion(componentType string) []CompletionItem {
+	items := []CompletionItem{}
+	component, ok := d.parser.Resolver.Components.Component(componentType)
+	if !ok {
+		return items
+	}
+	for _, property := range component.BlockProperties {
+		if property.Deprecated == "true" {
+			continue
+		}
+		items = append(items, CompletionItem{Label: property.Name, Kind: kindProperty, Detail: property.Type, rank: rankComponent})
+	}
+	for _, method := range component.Methods {
+		if method.Deprecated == "true" {
+			continue
+		}
+		parameters := make([]string, len(method.Params))
+		for i, parameter := range method.Params {
+			parameters[i] = parameter.Name
+		}
+		items = append(items, CompletionItem{Label: method.Name, Kind: kindMethod,
+			Detail: method.Name + "(" + strings.Join(parameters, ", ") + ")", rank: rankComponent})
+	}
+	return items
+}
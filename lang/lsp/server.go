@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Server is a Language Server Protocol server for Falcon source files, it
+// communicates over a pair of streams, usually the stdin and the stdout.
+type Server struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*Document
+	shutdown  bool
+}
+
+type handler func(server *Server, params json.RawMessage) (any, error)
+
+var requestHandlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"shutdown":                (*Server).shutdownRequest,
+	"textDocument/definition": (*Server).definition,
+	"textDocument/hover":      (*Server).hover,
+	"textDocument/completion": (*Server).completion,
+}
+
+var notificationHandlers = map[string]handler{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// errExit is returned by the exit notification when it wasn't preceded by a shutdown request
+var errExit = errors.New("exit requested before shutdown")
+
+func NewServer(reader io.Reader, writer io.Writer) *Server {
+	return &Server{
+		reader:    bufio.NewReader(reader),
+		writer:    writer,
+		documents: map[string]*Document{},
+	}
+}
+
+// Serve processes the messages until the client exits or the input is closed
+func (s *Server) Serve() error {
+	for {
+		content, err := readMessage(s.reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var request requestMessage
+		if err := json.Unmarshal(content, &request); err != nil {
+			if err := s.replyError(nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if request.Method == "exit" {
+			if !s.shutdown {
+				return errExit
+			}
+			return nil
+		}
+		if err := s.dispatch(&request); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) dispatch(request *requestMessage) error {
+	if request.Id == nil {
+		// notifications do not get a response, unknown ones are ignored
+		if handle, ok := notificationHandlers[request.Method]; ok {
+			if _, err := handle(s, request.Params); err != nil {
+				// there's no response to report the error with, it's logged and the server carries on
+				return s.notify("window/logMessage",
+					&logMessageParams{Type: messageError, Message: request.Method + ": " + err.Error()})
+			}
+		}
+		return nil
+	}
+	if s.shutdown {
+		return s.replyError(request.Id, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"})
+	}
+	handle, ok := requestHandlers[request.Method]
+	if !ok {
+		return s.replyError(request.Id, &responseError{Code: codeMethodNotFound, Message: "unknown method " + request.Method})
+	}
+	result, err := handle(s, request.Params)
+	if err != nil {
+		var rErr *responseError
+		if !errors.As(err, &rErr) {
+			rErr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.replyError(request.Id, rErr)
+	}
+	return writeMessage(s.writer, &responseMessage{JsonRpc: "2.0", Id: request.Id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, err *responseError) error {
+	return writeMessage(s.writer, &errorMessage{JsonRpc: "2.0", Id: id, Error: err})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.writer, &notificationMessage{JsonRpc: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:   syncFull,
+			DefinitionProvider: true,
+			HoverProvider:      true,
+			CompletionProvider: completionProvider{TriggerCharacters: []string{".", "@"}},
+		},
+		ServerInfo: serverInfo{Name: "falcon"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var openParams didOpenParams
+	if err := json.Unmarshal(params, &openParams); err != nil {
+		return nil, err
+	}
+	item := openParams.TextDocument
+	return nil, s.update(Analyze(item.URI, item.Version, item.Text))
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var changeParams didChangeParams
+	if err := json.Unmarshal(params, &changeParams); err != nil {
+		return nil, err
+	}
+	changes := changeParams.ContentChanges
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	// full sync, the last change holds the whole document
+	identifier := changeParams.TextDocument
+	return nil, s.update(Analyze(identifier.URI, identifier.Version, changes[len(changes)-1].Text))
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var closeParams didCloseParams
+	if err := json.Unmarshal(params, &closeParams); err != nil {
+		return nil, err
+	}
+	uri := closeParams.TextDocument.URI
+	delete(s.documents, uri)
+	// clear the diagnostics of the closed document
+	return nil, s.notify("textDocument/publishDiagnostics",
+		&publishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}})
+}
+
+func (s *Server) update(document *Document) error {
+	s.documents[document.URI] = document
+	return s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+		URI:         document.URI,
+		Version:     document.Version,
+		Diagnostics: document.LspDiagnostics(),
+	})
+}
+
+// positionRequest decodes the params of a request made at a position in an open document
+func (s *Server) positionRequest(params json.RawMessage) (*Document, Position, error) {
+	var positionParams positionParams
+	if err := json.Unmarshal(params, &positionParams); err != nil {
+		return nil, Position{}, err
+	}
+	document, ok := s.documents[positionParams.TextDocument.URI]
+	if !ok {
+		return nil, Position{}, &responseError{
+			Code:    codeInvalidParams,
+			Message: "document is not open: " + positionParams.TextDocument.URI,
+		}
+	}
+	return document, positionParams.Position, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	document, position, err := s.positionRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	if location := document.Definition(position); location != nil {
+		return location, nil
+	}
+	return nil, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	document, position, err := s.positionRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	if hover := document.Hover(position); hover != nil {
+		return hover, nil
+	}
+	return nil, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	document, position, err := s.positionRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	return document.Completion(position), nil
+}
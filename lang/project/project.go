@@ -0,0 +1,261 @@
+package project
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/common"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/ast/procedures"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/components/registry"
+	"Falcon/design"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LibraryFile holds the procedures shared by every screen of a project
+const LibraryFile = "lib.mist"
+
+// Project is a directory of screens. Each ScreenN.aiml (or ScreenN.scm) design is a screen, along
+// with its ScreenN.mist source when there's one.
+type Project struct {
+	Dir     string
+	Screens []*Screen
+	// Library is the content of lib.mist, it is inlined into the blocks of every screen
+	Library []ast.Expr
+
+	// Components describes the component types, the default ones when nil
+	Components *registry.Registry
+
+	library *mistparser.LangParser
+}
+
+type Screen struct {
+	Name        string
+	DesignFile  string
+	Design      *design.Component
+	Expressions []ast.Expr
+	Parser      *mistparser.LangParser
+}
+
+// Load reads every screen of the directory, the diagnostics of all the files are returned together
+func Load(dir string, components *registry.Registry) (*Project, []*context.Diagnostic, error) {
+	project := &Project{Dir: dir, Components: components}
+	var diagnostics []*context.Diagnostic
+
+	librarySource, err := os.ReadFile(filepath.Join(dir, LibraryFile))
+	if err == nil {
+		expressions, parser, libraryDiagnostics := project.parse(LibraryFile, string(librarySource), nil)
+		project.Library, project.library = expressions, parser
+		diagnostics = append(diagnostics, libraryDiagnostics...)
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	designFiles, err := FindDesigns(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, designFile := range designFiles {
+		screen, screenDiagnostics, err := project.loadScreen(designFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		project.Screens = append(project.Screens, screen)
+		diagnostics = append(diagnostics, screenDiagnostics...)
+	}
+	if project.library != nil {
+		// the library is inlined into every screen, but its diagnostics are only reported once
+		diagnostics = append(diagnostics, project.checkOpenedScreens(project.library.Located)...)
+	}
+	for _, screen := range project.Screens {
+		diagnostics = append(diagnostics, project.checkScreen(screen)...)
+	}
+	return project, diagnostics, nil
+}
+
+// FindDesigns lists the designs of the screens, an .aiml design is preferred to the .scm one
+func FindDesigns(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	designs := map[string]string{}
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".aiml" && extension != ".scm") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), extension)
+		if _, found := designs[name]; !found || extension == ".aiml" {
+			designs[name] = filepath.Join(dir, entry.Name())
+		}
+	}
+	files := make([]string, 0, len(designs))
+	for _, file := range designs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// FindLibrary returns the library of the project the source file is in, an empty string when there's
+// none or when the source file is the library itself
+func FindLibrary(sourcePath string) string {
+	if sourcePath == "" || sourcePath == "-" || filepath.Base(sourcePath) == LibraryFile {
+		return ""
+	}
+	library := filepath.Join(filepath.Dir(sourcePath), LibraryFile)
+	if _, err := os.Stat(library); err != nil {
+		return ""
+	}
+	return library
+}
+
+func (p *Project) loadScreen(designFile string) (*Screen, []*context.Diagnostic, error) {
+	content, err := os.ReadFile(designFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	screenDesign, err := design.ParseDesign(string(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	var diagnostics []*context.Diagnostic
+	if filepath.Ext(designFile) == ".aiml" {
+		diagnostics = design.Validate(filepath.Base(designFile), string(content), p.Components)
+	}
+	name := strings.TrimSuffix(filepath.Base(designFile), filepath.Ext(designFile))
+	screen := &Screen{Name: name, DesignFile: designFile, Design: screenDesign}
+	sourceFile := name + ".mist"
+	sourceCode, err := os.ReadFile(filepath.Join(p.Dir, sourceFile))
+	if os.IsNotExist(err) {
+		// a screen without blocks
+		return screen, diagnostics, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	expressions, parser, sourceDiagnostics := p.parse(sourceFile, string(sourceCode), screenDesign)
+	screen.Expressions, screen.Parser = expressions, parser
+	return screen, append(diagnostics, sourceDiagnostics...), nil
+}
+
+func (p *Project) parse(
+	fileName string,
+	sourceCode string,
+	screenDesign *design.Component,
+) ([]ast.Expr, *mistparser.LangParser, []*context.Diagnostic) {
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: fileName}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	parser := mistparser.NewLangParser(true, tokens)
+	if p.library != nil {
+		parser.Include(p.library)
+	}
+	if p.Components != nil {
+		parser.Resolver.Components = p.Components
+	}
+	if screenDesign != nil {
+		parser.SetDesign(screenDesign)
+	}
+	expressions, parseDiagnostics := parser.ParseAll()
+	return expressions, parser, append(diagnostics, parseDiagnostics...)
+}
+
+// Screen returns the screen of that name
+func (p *Project) Screen(name string) (*Screen, bool) {
+	for _, screen := range p.Screens {
+		if screen.Name == name {
+			return screen, true
+		}
+	}
+	return nil, false
+}
+
+// Blocks returns the top level expressions the blocks of the screen are made of, the library ones first
+func (p *Project) Blocks(screen *Screen) []ast.Expr {
+	blocks := make([]ast.Expr, 0, len(p.Library)+len(screen.Expressions))
+	blocks = append(blocks, p.Library...)
+	return append(blocks, screen.Expressions...)
+}
+
+// Located returns where the expressions of the screen and of the library are in their sources
+func (p *Project) Located(screen *Screen) []mistparser.LocatedExpr {
+	var located []mistparser.LocatedExpr
+	if p.library != nil {
+		located = append(located, p.library.Located...)
+	}
+	if screen.Parser != nil {
+		located = append(located, screen.Parser.Located...)
+	}
+	return located
+}
+
+// checkScreen reports the screens opened that are not in the project, and the procedures
+// of the screen that replace the ones of the library
+func (p *Project) checkScreen(screen *Screen) []*context.Diagnostic {
+	if screen.Parser == nil {
+		return nil
+	}
+	diagnostics := p.checkOpenedScreens(screen.Parser.Located)
+	for _, expr := range screen.Expressions {
+		if name := procedureName(expr); name != "" && p.libraryDefines(name) {
+			where := screen.Parser.Resolver.Procedures[name].Where
+			diagnostics = append(diagnostics, where.Diagnostic(context.CodeSyntax,
+				"Procedure %() is already defined in %", name, LibraryFile))
+		}
+	}
+	return diagnostics
+}
+
+// checkOpenedScreens reports the screens opened by the expressions that are not in the project
+func (p *Project) checkOpenedScreens(located []mistparser.LocatedExpr) []*context.Diagnostic {
+	var diagnostics []*context.Diagnostic
+	seen := map[*common.FuncCall]bool{}
+	for _, aLocated := range located {
+		call, ok := aLocated.Expr.(*common.FuncCall)
+		if !ok || seen[call] || (call.Name != "openScreen" && call.Name != "openScreenWithValue") || len(call.Args) == 0 {
+			continue
+		}
+		seen[call] = true
+		target, ok := call.Args[0].(*fundamentals.Text)
+		if !ok {
+			// only known when the app runs
+			continue
+		}
+		if _, found := p.Screen(target.Content); !found {
+			diagnostics = append(diagnostics, call.Where.Diagnostic(context.CodeUnresolved,
+				"Cannot find screen '%' in the project, the screens are %", target.Content, p.screenNames()))
+		}
+	}
+	return diagnostics
+}
+
+func (p *Project) screenNames() string {
+	names := make([]string, len(p.Screens))
+	for i, screen := range p.Screens {
+		names[i] = screen.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (p *Project) libraryDefines(name string) bool {
+	for _, expr := range p.Library {
+		if procedureName(expr) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func procedureName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *procedures.VoidProcedure:
+		return e.Name
+	case *procedures.RetProcedure:
+		return e.Name
+	}
+	return ""
+}
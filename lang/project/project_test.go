@@ -0,0 +1,110 @@
+package project
+
+import (
+	"Falcon/code/ast/procedures"
+	"Falcon/code/context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// emptyScreen is the design of a screen without components
+func emptyScreen(name string) string {
+	return "#|\n$JSON\n{\"authURL\":[],\"YaVersion\":\"208\",\"Source\":\"Form\",\"Properties\":{\"$Name\":\"" + name +
+		"\",\"$Type\":\"Form\",\"$Version\":\"27\",\"Uuid\":\"0\"}}\n|#\n"
+}
+
+// load writes the files to a project directory along with the designs of Screen1 and Screen2
+func load(t *testing.T, files map[string]string) (*Project, []*context.Diagnostic) {
+	t.Helper()
+	dir := t.TempDir()
+	files["Screen1.scm"], files["Screen2.scm"] = emptyScreen("Screen1"), emptyScreen("Screen2")
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project, diagnostics, err := Load(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return project, diagnostics
+}
+
+func rendered(diagnostics []*context.Diagnostic) []string {
+	messages := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		messages[i] = diagnostic.Position() + diagnostic.Message
+	}
+	return messages
+}
+
+func TestLibraryIsInlined(t *testing.T) {
+	project, diagnostics := load(t, map[string]string{
+		LibraryFile:    "global count = 0\n\nfunc go() {\n  this.count = this.count + 1\n}\n",
+		"Screen1.mist": "when Screen1.Initialize() {\n  go()\n  println(this.count)\n}\n",
+	})
+	if len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics but got %v", rendered(diagnostics))
+	}
+	screen, found := project.Screen("Screen1")
+	if !found {
+		t.Fatal("expected Screen1 in the project")
+	}
+	blocks := project.Blocks(screen)
+	if len(blocks) != 3 || blocks[2] != screen.Expressions[0] {
+		t.Fatalf("expected the global and go() followed by the event but got %d blocks", len(blocks))
+	}
+	if procedure, ok := blocks[1].(*procedures.VoidProcedure); !ok || procedure.Name != "go" {
+		t.Errorf("expected go() to be inlined but got %T", blocks[1])
+	}
+	files := map[string]bool{}
+	for _, located := range project.Located(screen) {
+		files[located.Start.Span().File] = true
+	}
+	if !files[LibraryFile] || !files["Screen1.mist"] {
+		t.Errorf("expected the expressions of both files to be located but got %v", files)
+	}
+	if screen, _ := project.Screen("Screen2"); len(project.Blocks(screen)) != 2 {
+		t.Errorf("expected the library in the blocks of a screen without source")
+	}
+}
+
+func TestUnknownScreens(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{"opened by a screen", map[string]string{"Screen1.mist": "func f() {\n  openScreen(\"Nowhere\")\n}\n"},
+			[]string{"Screen1.mist:2:3: Cannot find screen 'Nowhere' in the project, the screens are Screen1, Screen2"}},
+		{"opened by the library", map[string]string{
+			LibraryFile:    "func away() {\n  openScreenWithValue(\"Nowhere\", 1)\n}\n",
+			"Screen1.mist": "func f() {\n  away()\n}\n",
+			"Screen2.mist": "func g() {\n  away()\n}\n",
+		}, []string{"lib.mist:2:3: Cannot find screen 'Nowhere' in the project, the screens are Screen1, Screen2"}},
+		{"in the project", map[string]string{"Screen1.mist": "func f() {\n  openScreen(\"Screen2\")\n}\n"}, nil},
+		{"only known when the app runs", map[string]string{"Screen1.mist": "func f() {\n  openScreen(\"Screen\" _ 2)\n}\n"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, diagnostics := load(t, test.files)
+			if messages := rendered(diagnostics); strings.Join(messages, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("expected %q but got %q", test.expected, messages)
+			}
+		})
+	}
+}
+
+func TestProcedureReplacingTheLibrary(t *testing.T) {
+	_, diagnostics := load(t, map[string]string{
+		LibraryFile:    "func go() {\n  println(1)\n}\n",
+		"Screen1.mist": "func go() {\n  println(2)\n}\n",
+		"Screen2.mist": "func stay() {\n  go()\n}\n",
+	})
+	expected := "Screen1.mist:1:6: Procedure go() is already defined in lib.mist"
+	if messages := rendered(diagnostics); len(messages) != 1 || messages[0] != expected {
+		t.Errorf("expected %q but got %q", expected, messages)
+	}
+}
@@ -0,0 +1,215 @@
+package scaffold
+
+import (
+	"Falcon/code/ast"
+	"Falcon/code/ast/components"
+	"Falcon/code/ast/fundamentals"
+	"Falcon/code/ast/variables"
+	"Falcon/code/context"
+	"Falcon/code/lex"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/code/sugar"
+	"Falcon/components/registry"
+	"Falcon/design"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// the property holding what the user entered in an input component, a global keeps it
+var inputValues = map[string]string{
+	"TextBox":    "Text",
+	"CheckBox":   "Checked",
+	"Slider":     "ThumbPosition",
+	"ListPicker": "Selection",
+}
+
+// Merge writes the skeleton of the source of a screen into the source, which is empty for a new one:
+// the @ headers of the components of the design, an empty when for the primary events of each and a
+// global for the value of each input component. The headers, the handlers and the globals already
+// in the source are kept as they are, the missing ones are added. A component missing from the
+// source goes in the @ header of its type when there's one.
+func Merge(
+	fileName string,
+	sourceCode string,
+	screen *design.Component,
+	descriptors *registry.Registry,
+) (string, []*context.Diagnostic, error) {
+	if descriptors == nil {
+		descriptors = registry.Default()
+	}
+	codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: fileName}
+	tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+	parser := mistparser.NewLangParser(false, tokens)
+	parser.Resolver.Components = descriptors
+	parser.SetDesign(screen)
+	expressions, parseDiagnostics := parser.ParseAll()
+	diagnostics = append(diagnostics, parseDiagnostics...)
+	if context.HasErrors(diagnostics) {
+		return "", diagnostics, errors.New("the source to scaffold must have no errors")
+	}
+
+	handled := map[string]bool{}
+	globals := map[string]bool{}
+	for _, expression := range expressions {
+		switch e := expression.(type) {
+		case *components.Event:
+			handled[e.ComponentName+"."+e.Event] = true
+		case *variables.Global:
+			globals[e.Name] = true
+		}
+	}
+
+	undeclared := map[string][]string{}
+	var stubs, handlers []ast.Expr
+	var visit func(component *design.Component)
+	visit = func(component *design.Component) {
+		componentType := component.ComponentType()
+		if !parser.Declares(component.Id) {
+			undeclared[componentType] = append(undeclared[componentType], component.Id)
+		}
+		if descriptor, known := descriptors.Component(componentType); known {
+			for _, event := range descriptor.PrimaryEvents() {
+				if !handled[component.Id+"."+event.Name] {
+					handlers = append(handlers, &components.Event{
+						ComponentName: component.Id,
+						ComponentType: componentType,
+						Event:         event.Name,
+						Parameters:    event.ParameterNames(),
+					})
+				}
+			}
+			if global := inputGlobal(component, descriptor); global != nil && !globals[global.Name] {
+				stubs = append(stubs, global)
+			}
+		}
+		for k := range component.Children {
+			visit(&component.Children[k])
+		}
+	}
+	visit(screen)
+
+	lines := strings.SplitAfter(sourceCode, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	first := firstStatement(parser)
+	extendHeaders(lines, parser.Tokens[:first], undeclared)
+	cut := headerEnd(parser, first)
+	headers := headersOf(undeclared)
+	if headers != "" && cut == 0 && len(lines) > 0 {
+		// the first headers of the source
+		headers += "\n"
+	}
+	merged := strings.Join(lines[:cut], "") + headers + strings.Join(lines[cut:], "")
+	for _, declaration := range slices.Concat(stubs, handlers) {
+		if merged != "" {
+			if !strings.HasSuffix(merged, "\n") {
+				merged += "\n"
+			}
+			merged += "\n"
+		}
+		merged += ast.FormatStatement(declaration) + "\n"
+	}
+	return merged, diagnostics, nil
+}
+
+// firstStatement is the index of the first token of the statements, the @ headers are before it
+func firstStatement(parser *mistparser.LangParser) int {
+	first := len(parser.Tokens)
+	for _, located := range parser.Located {
+		// the statements of a body are located before the one holding them
+		if index := slices.Index(parser.Tokens, located.Start); located.Statement && index >= 0 {
+			first = min(first, index)
+		}
+	}
+	return first
+}
+
+// headerEnd is the number of lines taken by the @ headers of the source, up to the line of the
+// last token before the first statement
+func headerEnd(parser *mistparser.LangParser, first int) int {
+	if first < 1 {
+		return 0
+	}
+	return parser.Tokens[first-1].Column
+}
+
+// extendHeaders adds the undeclared components to the @ header of their type when the source has
+// one, after its last name. These components are no longer undeclared.
+func extendHeaders(lines []string, headerTokens []*lex.Token, undeclared map[string][]string) {
+	// from the last header so that the columns of the ones before stay right
+	for i := len(headerTokens) - 3; i >= 0; i-- {
+		if headerTokens[i].Type != lex.At || headerTokens[i+1].Type != lex.Name {
+			continue
+		}
+		componentType := *headerTokens[i+1].Content
+		names, found := undeclared[componentType]
+		if !found {
+			continue
+		}
+		end := i + 2
+		for end < len(headerTokens) && headerTokens[end].Type != lex.CloseCurly {
+			end++
+		}
+		if end == len(headerTokens) {
+			continue
+		}
+		last, closing := headerTokens[end-1], headerTokens[end]
+		line := lines[last.Column-1]
+		if last.Type == lex.OpenCurly && closing.Column == last.Column {
+			// an empty header, such as @Button {}
+			line = line[:last.Row] + " " + strings.Join(names, ", ") + " " + line[closing.Row-1:]
+		} else {
+			separator := ", "
+			if last.Type == lex.OpenCurly {
+				separator = " "
+			}
+			line = line[:last.Row] + separator + strings.Join(names, ", ") + line[last.Row:]
+		}
+		lines[last.Column-1] = line
+		delete(undeclared, componentType)
+	}
+}
+
+// headersOf declares the components by their sorted types, the names in the order of the design
+func headersOf(undeclared map[string][]string) string {
+	componentTypes := make([]string, 0, len(undeclared))
+	for componentType := range undeclared {
+		componentTypes = append(componentTypes, componentType)
+	}
+	sort.Strings(componentTypes)
+	var headers strings.Builder
+	for _, componentType := range componentTypes {
+		headers.WriteString(sugar.Format("@% { % }\n", componentType, strings.Join(undeclared[componentType], ", ")))
+	}
+	return headers.String()
+}
+
+// inputGlobal is the global keeping the value of an input component, such as the text of a TextBox,
+// set to the value in the design
+func inputGlobal(component *design.Component, descriptor *registry.Component) *variables.Global {
+	property, isInput := inputValues[descriptor.Name]
+	if !isInput {
+		return nil
+	}
+	designerProperty, found := descriptor.DesignerProperty(property)
+	if !found {
+		return nil
+	}
+	value, set := component.Properties[property]
+	if !set {
+		value = designerProperty.DefaultValue
+	}
+	var initial ast.Expr
+	switch designerProperty.EditorType {
+	case "boolean":
+		initial = &fundamentals.Boolean{Value: value == "True"}
+	case "float", "non_negative_float", "non_negative_integer":
+		initial = &fundamentals.Number{Content: value}
+	default:
+		initial = &fundamentals.Text{Content: value}
+	}
+	return &variables.Global{Name: component.Id + property, Value: initial}
+}
@@ -0,0 +1,177 @@
+package scaffold
+
+import (
+	"Falcon/design"
+	"strings"
+	"testing"
+)
+
+const screenDesign = `<Screen id="Screen1" Uuid="0">
+  <Button id="Button1" Text="Pet" Uuid="1"/>
+  <TextBox id="Name" Text="Kitty" Uuid="2"/>
+</Screen>`
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"new source", "", `@Button { Button1 }
+@Form { Screen1 }
+@TextBox { Name }
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+
+when Button1.Click() {
+}
+`},
+		{"scaffolded already", `@Button { Button1 }
+@Form { Screen1 }
+@TextBox { Name }
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+
+when Button1.Click() {
+}
+`, `@Button { Button1 }
+@Form { Screen1 }
+@TextBox { Name }
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+
+when Button1.Click() {
+}
+`},
+		{"headers above the handlers", `@Button { Button1 }
+
+when Button1.Click {
+  println("hi")
+}
+`, `@Button { Button1 }
+@Form { Screen1 }
+@TextBox { Name }
+
+when Button1.Click {
+  println("hi")
+}
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+`},
+		{"headers extended", `@Button {}
+@TextBox { Other }
+
+when Other.GotFocus {
+}
+`, `@Button { Button1 }
+@TextBox { Other, Name }
+@Form { Screen1 }
+
+when Other.GotFocus {
+}
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+
+when Button1.Click() {
+}
+`},
+		{"comments kept", `// the pet screen
+when Screen1.Initialize {
+  // nothing yet
+}
+`, `@Button { Button1 }
+@Form { Screen1 }
+@TextBox { Name }
+
+// the pet screen
+when Screen1.Initialize {
+  // nothing yet
+}
+
+global NameText = "Kitty"
+
+when Button1.Click() {
+}
+`},
+	}
+	screen, err := design.ParseDesign(screenDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, diagnostics, err := Merge("Screen1.mist", test.source, screen, nil)
+			if err != nil {
+				t.Fatalf("%v: %v", err, diagnostics)
+			}
+			if merged != test.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", test.expected, merged)
+			}
+		})
+	}
+}
+
+func TestMergeNeedsAValidSource(t *testing.T) {
+	screen, err := design.ParseDesign(screenDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, diagnostics, err := Merge("Screen1.mist", "when Button1.Click {\n", screen, nil); err == nil || len(diagnostics) == 0 {
+		t.Errorf("expected the errors of the source but got %v", diagnostics)
+	}
+}
+
+func TestMergeTwice(t *testing.T) {
+	screen, err := design.ParseDesign(screenDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, diagnostics, err := Merge("Screen1.mist", "", screen, nil)
+	if err != nil {
+		t.Fatalf("%v: %v", err, diagnostics)
+	}
+	// a button added to the design since
+	screen, err = design.ParseDesign(strings.Replace(screenDesign, "</Screen>",
+		`  <Button id="Button2" Text="Feed" Uuid="3"/>
+</Screen>`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, diagnostics, err = Merge("Screen1.mist", merged, screen, nil)
+	if err != nil {
+		t.Fatalf("%v: %v", err, diagnostics)
+	}
+	expected := `@Button { Button1, Button2 }
+@Form { Screen1 }
+@TextBox { Name }
+
+global NameText = "Kitty"
+
+when Screen1.Initialize() {
+}
+
+when Button1.Click() {
+}
+
+when Button2.Click() {
+}
+`
+	if merged != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, merged)
+	}
+}
@@ -8,9 +8,14 @@ package main
 import (
 	"Falcon/code/ast"
 	"Falcon/code/context"
+	"Falcon/code/diff"
+	"Falcon/code/layout"
 	"Falcon/code/lex"
-	"Falcon/code/parser"
+	"Falcon/code/parsers/blocklytomist"
+	"Falcon/code/parsers/mistparser"
+	"Falcon/code/sourcemap"
 	"Falcon/design"
+	"encoding/json"
 	"encoding/xml"
 	"strings"
 	"syscall/js"
@@ -23,6 +28,8 @@ func safeExec(fn func() js.Value) (ret js.Value) {
 		if r := recover(); r != nil {
 			if msg, ok := r.(string); ok {
 				js.Global().Call("mistError", msg)
+			} else if diagnostic, ok := r.(*context.Diagnostic); ok {
+				reportDiagnostics([]*context.Diagnostic{diagnostic})
 			} else if err, ok := r.(error); ok {
 				js.Global().Call("mistError", err.Error())
 			} else {
@@ -36,45 +43,89 @@ func safeExec(fn func() js.Value) (ret js.Value) {
 	return
 }
 
+// reportDiagnostics forwards the rendered errors along with their JSON form to mistError
+func reportDiagnostics(diagnostics []*context.Diagnostic) {
+	rendered := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		rendered[i] = diagnostic.Render(true)
+	}
+	diagnosticsJson, _ := json.Marshal(diagnostics)
+	js.Global().Call("mistError", strings.Join(rendered, "\n"), string(diagnosticsJson))
+}
+
 // Code -> Blocks
 func mistToXml(this js.Value, p []js.Value) any {
 	return safeExec(func() js.Value {
 		if len(p) < 2 {
-			return js.ValueOf("mistToXML(sourceCode string, componentDefinitions map[string][]string) not provided!")
+			return js.ValueOf("mistToXML(sourceCode string, componentDefinitions map[string][]string | design string, previousXml string?, withSourceMap bool?) not provided!")
 		}
 		sourceCode := p[0].String()
 
-		// Parse the Component Definition Context
+		var screen *design.Component
 		componentContextMap := make(map[string][]string) // Button -> [Button1, Button2]
 		reverseComponentMap := make(map[string]string)   // Button1 -> Button, Button2 -> Button
-		obj := p[1]
-		keys := js.Global().Get("Object").Call("keys", obj)
-		length := keys.Length()
-		for i := 0; i < length; i++ {
-			compType := keys.Index(i).String()
-			jsArr := obj.Get(compType)
-			var compNames []string
-			for j := 0; j < jsArr.Length(); j++ {
-				instanceName := jsArr.Index(j).String()
-				compNames = append(compNames, instanceName)
-				reverseComponentMap[instanceName] = compType
+		if p[1].Type() == js.TypeString {
+			// the screen design (.aiml or .scm) declares the components
+			parsed, err := design.ParseDesign(p[1].String())
+			if err != nil {
+				panic(err)
+			}
+			screen = parsed
+		} else {
+			// Parse the Component Definition Context
+			obj := p[1]
+			keys := js.Global().Get("Object").Call("keys", obj)
+			length := keys.Length()
+			for i := 0; i < length; i++ {
+				compType := keys.Index(i).String()
+				jsArr := obj.Get(compType)
+				var compNames []string
+				for j := 0; j < jsArr.Length(); j++ {
+					instanceName := jsArr.Index(j).String()
+					compNames = append(compNames, instanceName)
+					reverseComponentMap[instanceName] = compType
+				}
+				componentContextMap[compType] = compNames
 			}
-			componentContextMap[compType] = compNames
 		}
 
 		// Parse Mist To XML Blockly
 		codeContext := &context.CodeContext{SourceCode: &sourceCode, FileName: "appinventor.live"}
 
-		tokens := lex.NewLexer(codeContext).Lex()
-		langParser := parser.NewLangParser(true, tokens)
+		tokens, diagnostics := lex.NewLexer(codeContext).Lex()
+		langParser := mistparser.NewLangParser(true, tokens)
 		langParser.SetComponentDefinitions(componentContextMap, reverseComponentMap)
-		expressions := langParser.ParseAll()
+		if screen != nil {
+			langParser.SetDesign(screen)
+		}
+		expressions, parseDiagnostics := langParser.ParseAll()
+		diagnostics = append(diagnostics, parseDiagnostics...)
+		if context.HasErrors(diagnostics) {
+			reportDiagnostics(diagnostics)
+			return js.Undefined()
+		}
+
+		// the blocks of the workspace before the import keep their positions when unchanged
+		var previous *layout.Positions
+		if len(p) > 2 && p[2].Type() == js.TypeString {
+			positions, err := layout.ReadPositions(p[2].String())
+			if err != nil {
+				panic(err)
+			}
+			previous = positions
+		}
+		blocks := make([]ast.Block, len(expressions))
+		for i, expression := range expressions {
+			blocks[i] = ast.RootBlock(expression)
+		}
+		sourcemap.AssignIds(blocks)
+		layout.Arrange(blocks, previous)
 
 		var xmlCode strings.Builder
 
-		for _, expression := range expressions {
+		for _, block := range blocks {
 			xmlBlock := ast.XmlRoot{
-				Blocks: []ast.Block{expression.Blockly(true)},
+				Blocks: []ast.Block{block},
 				XMLNS:  "https://developers.google.com/blockly/xml",
 			}
 			bytes, _ := xml.MarshalIndent(xmlBlock, "", "  ")
@@ -83,6 +134,12 @@ func mistToXml(this js.Value, p []js.Value) any {
 			xmlCode.WriteByte(0)
 		}
 
+		if withSourceMap(p, 3) {
+			return js.ValueOf(map[string]any{
+				"xml":       xmlCode.String(),
+				"sourceMap": sourceMapJson(sourcemap.FromSource(blocks, langParser.Located)),
+			})
+		}
 		return js.ValueOf(xmlCode.String())
 	})
 }
@@ -94,11 +151,14 @@ func xmlToMist(this js.Value, p []js.Value) any {
 			return js.ValueOf("No XML content provided")
 		}
 		xmlContent := p[0].String()
-		exprs := parser.NewXMLParser(xmlContent).ParseBlockly()
+		parser := blocklytomist.NewParser(xmlContent)
+		// the block ids are only kept when asked for, every block has one
+		parser.KeepIds = len(p) > 1 && p[1].Type() == js.TypeBoolean && p[1].Bool()
+		exprs := parser.GenerateAST()
 		var builder strings.Builder
 
 		for _, expr := range exprs {
-			builder.WriteString(expr.String())
+			builder.WriteString(ast.FormatStatement(expr))
 			builder.WriteString("\n")
 
 			block := expr.Blockly(true)
@@ -106,10 +166,58 @@ func xmlToMist(this js.Value, p []js.Value) any {
 				builder.WriteString("\n")
 			}
 		}
+		if withSourceMap(p, 2) {
+			sourceMap := sourcemap.FromWorkspace(parser.Blocks(), "appinventor.live", builder.String())
+			return js.ValueOf(map[string]any{
+				"mist":      builder.String(),
+				"sourceMap": sourceMapJson(sourceMap),
+			})
+		}
 		return js.ValueOf(builder.String())
 	})
 }
 
+// Text and blocks edited apart -> Code
+func mergeMist(this js.Value, p []js.Value) any {
+	return safeExec(func() js.Value {
+		if len(p) < 3 {
+			return js.ValueOf("mergeMist(baseCode string, textCode string, blocksCode string, design string?) not provided!")
+		}
+		var options diff.Options
+		if len(p) > 3 && p[3].Type() == js.TypeString {
+			screen, err := design.ParseDesign(p[3].String())
+			if err != nil {
+				panic(err)
+			}
+			options.Screen = screen
+		}
+		result, diagnostics, err := diff.Merge(p[0].String(), p[1].String(), p[2].String(), options)
+		if err != nil {
+			reportDiagnostics(diagnostics)
+			return js.Undefined()
+		}
+		conflicts := make([]any, len(result.Conflicts))
+		for i, conflict := range result.Conflicts {
+			conflicts[i] = conflict.Key
+		}
+		return js.ValueOf(map[string]any{"mist": result.Source, "conflicts": conflicts})
+	})
+}
+
+// withSourceMap tells if the source map is asked for by the argument at that index, the result
+// is then an object holding the code and the source map JSON
+func withSourceMap(p []js.Value, index int) bool {
+	return len(p) > index && p[index].Type() == js.TypeBoolean && p[index].Bool()
+}
+
+func sourceMapJson(sourceMap *sourcemap.SourceMap) string {
+	content, err := json.Marshal(sourceMap)
+	if err != nil {
+		panic(err)
+	}
+	return string(content)
+}
+
 func convertSchemaToXml(this js.Value, p []js.Value) any {
 	return safeExec(func() js.Value {
 		if len(p) < 1 {
@@ -128,6 +236,10 @@ func convertXmlToSchema(this js.Value, p []js.Value) any {
 		if len(p) < 1 {
 			return js.ValueOf("No schema provided")
 		}
+		if diagnostics := design.Validate("Screen.aiml", p[0].String(), nil); context.HasErrors(diagnostics) {
+			reportDiagnostics(diagnostics)
+			return js.Undefined()
+		}
 		schemaString, err := design.NewXmlParser(p[0].String()).ConvertXmlToSchema()
 		if err != nil {
 			panic(err)
@@ -142,6 +254,7 @@ func main() {
 	c := make(chan struct{}, 0)
 	js.Global().Set("mistToXml", js.FuncOf(mistToXml))
 	js.Global().Set("xmlToMist", js.FuncOf(xmlToMist))
+	js.Global().Set("mergeMist", js.FuncOf(mergeMist))
 	js.Global().Set("schemaToXml", js.FuncOf(convertSchemaToXml))
 	js.Global().Set("xmlToSchema", js.FuncOf(convertXmlToSchema))
 	<-c